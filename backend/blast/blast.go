@@ -0,0 +1,154 @@
+package blast
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"conf/mailer"
+)
+
+// Blast is the content of a blast-email, the same templates are rendered for every recipient.
+type Blast struct {
+	Subject   string
+	PlainText *Template
+	Html      *Template
+	// Variables are available to every recipient, a recipient's own fields take precedence.
+	Variables map[string]any
+}
+
+// Recipient is a single destination of the blast.
+type Recipient struct {
+	Name  string
+	Email string
+	// Fields are the template variables of this recipient.
+	Fields map[string]any
+}
+
+// Deduplicate keeps the first recipient of every email, emails are compared case-insensitively.
+func Deduplicate(recipients []Recipient) []Recipient {
+	seen := make(map[string]struct{}, len(recipients))
+	deduplicated := make([]Recipient, 0, len(recipients))
+	for _, recipient := range recipients {
+		key := recipientKey(recipient.Email)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		deduplicated = append(deduplicated, recipient)
+	}
+
+	return deduplicated
+}
+
+// MissingFields lists the template variables a recipient does not have.
+type MissingFields struct {
+	RecipientEmail string
+	Fields         []string
+}
+
+// Render renders the mail of every recipient. A recipient that's missing a variable is still rendered, the
+// variable renders as an empty string, and it's listed on the returned MissingFields so the blast can be
+// stopped before anything is sent.
+func (b Blast) Render(recipients []Recipient) ([]*mailer.Mail, []MissingFields, error) {
+	mails := make([]*mailer.Mail, 0, len(recipients))
+	var missingFields []MissingFields
+	for _, recipient := range recipients {
+		data := b.data(recipient)
+
+		missing := mergeMissing(b.PlainText.Missing(data), b.Html.Missing(data))
+		if len(missing) > 0 {
+			missingFields = append(missingFields, MissingFields{RecipientEmail: recipient.Email, Fields: missing})
+		}
+
+		plainTextBody, err := b.PlainText.Render(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rendering plaintext body for %s: %w", recipient.Email, err)
+		}
+
+		htmlBody, err := b.Html.Render(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rendering html body for %s: %w", recipient.Email, err)
+		}
+
+		mails = append(mails, &mailer.Mail{
+			RecipientName:  recipient.Name,
+			RecipientEmail: recipient.Email,
+			Subject:        b.Subject,
+			PlainTextBody:  plainTextBody,
+			HtmlBody:       htmlBody,
+		})
+	}
+
+	return mails, missingFields, nil
+}
+
+func (b Blast) data(recipient Recipient) map[string]any {
+	data := make(map[string]any, len(b.Variables)+len(recipient.Fields)+2)
+	maps.Copy(data, b.Variables)
+
+	// An empty field, like a blank cell on the CSV, is left out so the template is flagged for it. The blast
+	// variables are kept even if they are empty, they are the same for everyone.
+	for key, value := range recipient.Fields {
+		if value != nil && value != "" {
+			data[key] = value
+		}
+	}
+
+	// Not every recipient has a name, e.g. a ticket without a registration. Templates are expected to go
+	// without one, with `{{#if name}}` or a greeting that reads fine either way.
+	data["name"] = recipient.Name
+	data["email"] = recipient.Email
+
+	return data
+}
+
+func mergeMissing(first []string, second []string) []string {
+	merged := append([]string(nil), first...)
+	for _, field := range second {
+		if !slices.Contains(merged, field) {
+			merged = append(merged, field)
+		}
+	}
+
+	return merged
+}
+
+// WriteMessages renders every mail as it would be sent and writes it to the directory as an .eml file, named
+// after its position and recipient. It returns the paths of the written files.
+func WriteMessages(ctx context.Context, mailSender *mailer.Mailer, directory string, mails []*mailer.Mail) ([]string, error) {
+	err := os.MkdirAll(directory, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+
+	paths := make([]string, 0, len(mails))
+	for i, mail := range mails {
+		path := filepath.Join(directory, fmt.Sprintf("%05d-%s.eml", i+1, sanitizeFilename(mail.RecipientEmail)))
+		err := os.WriteFile(path, mailSender.Render(ctx, mail), 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("writing %s: %w", path, err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// sanitizeFilename keeps the characters that are safe on every file system.
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_', r == '+':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,111 @@
+package blast_test
+
+import (
+	"context"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"conf/blast"
+	"conf/mailer"
+)
+
+func mustParseTemplate(t *testing.T, source string) *blast.Template {
+	t.Helper()
+
+	template, err := blast.ParseTemplate(source)
+	if err != nil {
+		t.Fatalf("parsing template: %s", err.Error())
+	}
+
+	return template
+}
+
+func TestBlast_Render(t *testing.T) {
+	mailBlast := blast.Blast{
+		Subject:   "TeknumConf 2023",
+		PlainText: mustParseTemplate(t, "Hai {{name}}, bayar {{ticketPrice}}"),
+		Html:      mustParseTemplate(t, "<p>Hai {{name}}, hubungi {{conferenceEmail}} atau {{phone}}{{bankAccounts}}</p>"),
+		Variables: map[string]any{"ticketPrice": "Rp 150.000", "conferenceEmail": "conference@example.com", "bankAccounts": ""},
+	}
+
+	mails, missingFields, err := mailBlast.Render([]blast.Recipient{
+		{Name: "Aji", Email: "aji@example.com", Fields: map[string]any{"phone": "0812"}},
+		{Name: "", Email: "wah@example.com", Fields: map[string]any{"phone": ""}},
+		{Name: "", Email: "no-name@example.com", Fields: map[string]any{"phone": "0813"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(mails) != 3 {
+		t.Fatalf("expecting 3 mails, got %d", len(mails))
+	}
+
+	if mails[0].PlainTextBody != "Hai Aji, bayar Rp 150.000" {
+		t.Errorf("unexpected plaintext body %q", mails[0].PlainTextBody)
+	}
+
+	if mails[0].HtmlBody != "<p>Hai Aji, hubungi conference@example.com atau 0812</p>" {
+		t.Errorf("unexpected html body %q", mails[0].HtmlBody)
+	}
+
+	if mails[1].RecipientEmail != "wah@example.com" || mails[1].Subject != "TeknumConf 2023" {
+		t.Errorf("unexpected second mail %+v", mails[1])
+	}
+
+	if len(missingFields) != 1 {
+		t.Fatalf("expecting 1 recipient with missing fields, got %+v", missingFields)
+	}
+
+	if missingFields[0].RecipientEmail != "wah@example.com" || !slices.Equal(missingFields[0].Fields, []string{"phone"}) {
+		t.Errorf("expecting wah@example.com to miss phone, got %+v", missingFields[0])
+	}
+
+	if mails[2].PlainTextBody != "Hai , bayar Rp 150.000" {
+		t.Errorf("expecting a recipient without a name to be rendered, got %q", mails[2].PlainTextBody)
+	}
+}
+
+func TestWriteMessages(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "preview")
+	mailSender := mailer.NewMailSenderWithTransport(mailer.NewMemoryTransport(), mailer.DefaultFrom)
+
+	paths, err := blast.WriteMessages(context.Background(), mailSender, directory, []*mailer.Mail{
+		{RecipientName: "Aji", RecipientEmail: "aji@example.com", Subject: "Hai", PlainTextBody: "Hai Aji", HtmlBody: "<p>Hai Aji</p>"},
+		{RecipientEmail: "../wah/@example.com", Subject: "Hai", PlainTextBody: "Hai", HtmlBody: "<p>Hai</p>"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := []string{
+		filepath.Join(directory, "00001-aji@example.com.eml"),
+		filepath.Join(directory, "00002-.._wah_@example.com.eml"),
+	}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expecting paths %v, got %v", expected, paths)
+	}
+
+	file, err := os.Open(paths[0])
+	if err != nil {
+		t.Fatalf("opening message: %s", err.Error())
+	}
+	defer file.Close()
+
+	message, err := mail.ReadMessage(file)
+	if err != nil {
+		t.Fatalf("parsing message: %s", err.Error())
+	}
+
+	to, err := mail.ParseAddress(message.Header.Get("To"))
+	if err != nil || to.Name != "Aji" || to.Address != "aji@example.com" {
+		t.Errorf("unexpected To header %q", message.Header.Get("To"))
+	}
+
+	if message.Header.Get("Subject") != "Hai" {
+		t.Errorf("unexpected Subject header %q", message.Header.Get("Subject"))
+	}
+}
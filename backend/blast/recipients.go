@@ -0,0 +1,189 @@
+package blast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CsvOptions configures ReadRecipients.
+type CsvOptions struct {
+	// Delimiter separates the fields. If it's zero, the delimiter is detected from the header among comma,
+	// semicolon, tab, and pipe.
+	Delimiter rune
+	// RequireName fails the rows with an empty name column.
+	RequireName bool
+}
+
+var detectedDelimiters = []rune{',', ';', '\t', '|'}
+
+// maxRowErrors caps the errors returned by ReadRecipients, the rest is summarized.
+const maxRowErrors = 20
+
+// ReadRecipients reads the recipients from a CSV file with a header row. Every column is exposed as a template
+// variable named after its header, the `email` column is required and the `name` column is optional, both are
+// matched case-insensitively. A UTF-8 byte order mark at the start of the file is ignored.
+//
+// Malformed rows do not stop the parsing, every problem is returned at once with its line number.
+func ReadRecipients(r io.Reader, options CsvOptions) ([]Recipient, error) {
+	reader := bufio.NewReader(r)
+	if bom, err := reader.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = reader.Discard(3)
+	}
+
+	delimiter := options.Delimiter
+	if delimiter == 0 {
+		// Peeking for the byte order mark filled the buffer, the header is usually well within it.
+		buffered, _ := reader.Peek(reader.Buffered())
+		delimiter = detectDelimiter(buffered)
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = delimiter
+	// Rows with the wrong number of fields are reported with their line number below.
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("csv is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	emailColumn, nameColumn := -1, -1
+	seen := make(map[string]struct{}, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		header[i] = column
+
+		if column == "" {
+			return nil, fmt.Errorf("line 1: column %d has an empty header", i+1)
+		}
+
+		// Headers differing only in case would be ambiguous for the email and name columns.
+		if _, ok := seen[strings.ToLower(column)]; ok {
+			return nil, fmt.Errorf("line 1: column %q is duplicated", column)
+		}
+		seen[strings.ToLower(column)] = struct{}{}
+
+		switch strings.ToLower(column) {
+		case "email":
+			emailColumn = i
+		case "name":
+			nameColumn = i
+		}
+	}
+
+	if emailColumn == -1 {
+		return nil, fmt.Errorf("line 1: email column is missing")
+	}
+
+	if options.RequireName && nameColumn == -1 {
+		return nil, fmt.Errorf("line 1: name column is missing")
+	}
+
+	var recipients []Recipient
+	var rowErrors []error
+	var skippedErrors int
+	addError := func(err error) {
+		if len(rowErrors) < maxRowErrors {
+			rowErrors = append(rowErrors, err)
+			return
+		}
+		skippedErrors++
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			addError(fmt.Errorf("line %d: %w", parseError.StartLine, parseError.Err))
+			if parseError.Err == csv.ErrQuote || parseError.Err == csv.ErrBareQuote {
+				// The reader can't tell where the broken quoted field ends, stop here instead of reporting
+				// every line after it.
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading csv: %w", err)
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		if len(record) != len(header) {
+			addError(fmt.Errorf("line %d: expecting %d fields, got %d", line, len(header), len(record)))
+			continue
+		}
+
+		recipient := Recipient{
+			Email:  strings.TrimSpace(record[emailColumn]),
+			Fields: make(map[string]any, len(header)),
+		}
+		if nameColumn != -1 {
+			recipient.Name = strings.TrimSpace(record[nameColumn])
+		}
+
+		for i, column := range header {
+			recipient.Fields[column] = strings.TrimSpace(record[i])
+		}
+
+		if recipient.Email == "" {
+			addError(fmt.Errorf("line %d: email is empty", line))
+			continue
+		}
+
+		if options.RequireName && recipient.Name == "" {
+			addError(fmt.Errorf("line %d: name is empty", line))
+			continue
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	if skippedErrors > 0 {
+		rowErrors = append(rowErrors, fmt.Errorf("and %d more errors", skippedErrors))
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, errors.Join(rowErrors...)
+	}
+
+	return recipients, nil
+}
+
+// detectDelimiter picks the candidate that appears the most on the header line outside quoted fields, falling
+// back to a comma.
+func detectDelimiter(content []byte) rune {
+	headerLine, _, _ := bytes.Cut(content, []byte("\n"))
+
+	counts := make(map[rune]int, len(detectedDelimiters))
+	quoted := false
+	for _, r := range string(headerLine) {
+		if r == '"' {
+			quoted = !quoted
+			continue
+		}
+
+		if !quoted {
+			counts[r]++
+		}
+	}
+
+	delimiter := ','
+	for _, candidate := range detectedDelimiters {
+		if counts[candidate] > counts[delimiter] {
+			delimiter = candidate
+		}
+	}
+
+	return delimiter
+}
@@ -0,0 +1,149 @@
+package blast_test
+
+import (
+	"strings"
+	"testing"
+
+	"conf/blast"
+)
+
+func TestReadRecipients(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		options  blast.CsvOptions
+		expected []blast.Recipient
+	}{
+		{
+			name:    "every column is a field",
+			content: "name,email,deadline,ticketType\nAji,aji@example.com,2023-08-01,student\n",
+			expected: []blast.Recipient{
+				{Name: "Aji", Email: "aji@example.com", Fields: map[string]any{"name": "Aji", "email": "aji@example.com", "deadline": "2023-08-01", "ticketType": "student"}},
+			},
+		},
+		{
+			name:    "byte order mark and quoted fields",
+			content: "\xef\xbb\xbfname,email,talk\n\"Wah, Jr\",wah@example.com,\"Go \"\"generics\"\",\nin practice\"\n",
+			expected: []blast.Recipient{
+				{Name: "Wah, Jr", Email: "wah@example.com", Fields: map[string]any{"name": "Wah, Jr", "email": "wah@example.com", "talk": "Go \"generics\",\nin practice"}},
+			},
+		},
+		{
+			name:    "detected semicolon",
+			content: "Name;Email;amount\nAji;aji@example.com;150.000,00\n",
+			expected: []blast.Recipient{
+				{Name: "Aji", Email: "aji@example.com", Fields: map[string]any{"Name": "Aji", "Email": "aji@example.com", "amount": "150.000,00"}},
+			},
+		},
+		{
+			name:    "explicit tab",
+			content: "email\tname\n aji@example.com \tAji\n",
+			options: blast.CsvOptions{Delimiter: '\t'},
+			expected: []blast.Recipient{
+				{Name: "Aji", Email: "aji@example.com", Fields: map[string]any{"name": "Aji", "email": "aji@example.com"}},
+			},
+		},
+		{
+			name:    "name is optional",
+			content: "email\naji@example.com\n",
+			expected: []blast.Recipient{
+				{Email: "aji@example.com", Fields: map[string]any{"email": "aji@example.com"}},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recipients, err := blast.ReadRecipients(strings.NewReader(testCase.content), testCase.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(recipients) != len(testCase.expected) {
+				t.Fatalf("expecting %d recipients, got %+v", len(testCase.expected), recipients)
+			}
+
+			for i, expected := range testCase.expected {
+				recipient := recipients[i]
+				if recipient.Name != expected.Name || recipient.Email != expected.Email {
+					t.Errorf("expecting recipient %q <%s>, got %q <%s>", expected.Name, expected.Email, recipient.Name, recipient.Email)
+				}
+
+				if len(recipient.Fields) != len(expected.Fields) {
+					t.Errorf("expecting fields %v, got %v", expected.Fields, recipient.Fields)
+				}
+
+				for key, value := range expected.Fields {
+					if recipient.Fields[key] != value {
+						t.Errorf("expecting field %s to be %q, got %q", key, value, recipient.Fields[key])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadRecipients_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		options  blast.CsvOptions
+		expected []string
+	}{
+		{
+			name:     "empty file",
+			content:  "",
+			expected: []string{"csv is empty"},
+		},
+		{
+			name:     "missing email column",
+			content:  "name,phone\nAji,0812\n",
+			expected: []string{"line 1: email column is missing"},
+		},
+		{
+			name:     "missing name column",
+			content:  "email\naji@example.com\n",
+			options:  blast.CsvOptions{RequireName: true},
+			expected: []string{"line 1: name column is missing"},
+		},
+		{
+			name:     "duplicated header",
+			content:  "name,email,Email\n",
+			expected: []string{`line 1: column "Email" is duplicated`},
+		},
+		{
+			name:     "empty header",
+			content:  "name,,email\n",
+			expected: []string{"line 1: column 2 has an empty header"},
+		},
+		{
+			name:    "every malformed row is reported",
+			content: "name,email,deadline\nAji,aji@example.com,2023-08-01\nWah\n\"Multi\nline\",,2023-08-01\nBudi,budi@example.com\n,ani@example.com,2023-08-01\n",
+			options: blast.CsvOptions{RequireName: true},
+			expected: []string{
+				"line 3: expecting 3 fields, got 1",
+				"line 4: email is empty",
+				"line 6: expecting 3 fields, got 2",
+				"line 7: name is empty",
+			},
+		},
+		{
+			name:     "unterminated quote",
+			content:  "name,email\nAji,aji@example.com\n\"Wah,wah@example.com\n",
+			expected: []string{"line 3: extraneous or missing \" in quoted-field"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recipients, err := blast.ReadRecipients(strings.NewReader(testCase.content), testCase.options)
+			if err == nil {
+				t.Fatalf("expecting an error, got %+v", recipients)
+			}
+
+			if message := err.Error(); message != strings.Join(testCase.expected, "\n") {
+				t.Errorf("expecting error %q, got %q", strings.Join(testCase.expected, "\n"), message)
+			}
+		})
+	}
+}
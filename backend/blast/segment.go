@@ -0,0 +1,212 @@
+package blast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"conf/ticketing"
+	"conf/user"
+	"github.com/getsentry/sentry-go"
+)
+
+// Segment is a named group of recipients that's queried from the database.
+type Segment string
+
+const (
+	// SegmentNoReceipt are the confirmed participants that have not uploaded a payment receipt.
+	SegmentNoReceipt Segment = "no-receipt"
+	// SegmentUnpaid are the participants whose latest payment receipt has not been approved, including the
+	// rejected ones.
+	SegmentUnpaid Segment = "unpaid"
+	// SegmentPaid are the holders of an approved ticket that's not revoked.
+	SegmentPaid Segment = "paid"
+	// SegmentCheckedIn are the holders of a ticket that's been redeemed on any checkpoint.
+	SegmentCheckedIn Segment = "checked-in"
+	// SegmentSpeakers are the registered speakers.
+	SegmentSpeakers Segment = "speakers"
+	// SegmentStudents are the holders of a ticket that's been verified as a student.
+	SegmentStudents Segment = "students"
+)
+
+// Segments lists every known segment.
+var Segments = []Segment{SegmentNoReceipt, SegmentUnpaid, SegmentPaid, SegmentCheckedIn, SegmentSpeakers, SegmentStudents}
+
+// ErrUnknownSegment is returned for a segment name that's not in Segments.
+var ErrUnknownSegment = errors.New("unknown segment")
+
+// ParseSegment validates a segment name.
+func ParseSegment(name string) (Segment, error) {
+	for _, segment := range Segments {
+		if string(segment) == strings.TrimSpace(strings.ToLower(name)) {
+			return segment, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w %q, expecting one of %s", ErrUnknownSegment, name, joinSegments(Segments))
+}
+
+func joinSegments(segments []Segment) string {
+	names := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		names = append(names, string(segment))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// SegmentResolver queries the recipients of a segment from the users and the ticketing entries.
+type SegmentResolver struct {
+	userDomain          *user.UserDomain
+	ticketingRepository ticketing.Repository
+}
+
+func NewSegmentResolver(userDomain *user.UserDomain, ticketingRepository ticketing.Repository) (*SegmentResolver, error) {
+	if userDomain == nil {
+		return nil, fmt.Errorf("userDomain is nil")
+	}
+
+	if ticketingRepository == nil {
+		return nil, fmt.Errorf("ticketingRepository is nil")
+	}
+
+	return &SegmentResolver{userDomain: userDomain, ticketingRepository: ticketingRepository}, nil
+}
+
+// Resolve returns the union of the recipients of every segment, in the order of the segments. An email that
+// appears more than once, on the same or on another segment, is only returned the first time. Emails are
+// compared case-insensitively.
+func (s *SegmentResolver) Resolve(ctx context.Context, segments []Segment) ([]Recipient, error) {
+	span := sentry.StartSpan(ctx, "blast.resolve_segments", sentry.WithTransactionName("ResolveSegments"))
+	defer span.Finish()
+
+	users, err := s.userDomain.GetUsers(ctx, user.UserFilterRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("getting users: %w", err)
+	}
+
+	// Tickets only have the email, the name comes from the registration.
+	usersByEmail := make(map[string]user.User, len(users))
+	for _, userItem := range users {
+		key := recipientKey(userItem.Email)
+		if existing, ok := usersByEmail[key]; ok && existing.Name != "" {
+			continue
+		}
+
+		usersByEmail[key] = userItem
+	}
+
+	var tickets map[string]ticketing.Ticketing
+	var ticketOnlyEmails []string
+	var recipients []Recipient
+	seen := make(map[string]struct{})
+	add := func(email string) {
+		key := recipientKey(email)
+		if _, ok := seen[key]; ok || key == "" {
+			return
+		}
+		seen[key] = struct{}{}
+
+		recipients = append(recipients, Recipient{Name: usersByEmail[key].Name, Email: strings.TrimSpace(email)})
+	}
+
+	for _, segment := range segments {
+		if segment != SegmentSpeakers && tickets == nil {
+			tickets, err = s.latestTickets(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			// Tickets can outlive their registration, they are listed after the registered users.
+			for key, ticket := range tickets {
+				if _, ok := usersByEmail[key]; !ok {
+					ticketOnlyEmails = append(ticketOnlyEmails, ticket.Email)
+				}
+			}
+			sort.Strings(ticketOnlyEmails)
+		}
+
+		switch segment {
+		case SegmentNoReceipt:
+			for _, userItem := range users {
+				if _, ok := tickets[recipientKey(userItem.Email)]; !ok && userItem.Type == user.TypeParticipant && userItem.EmailConfirmed {
+					add(userItem.Email)
+				}
+			}
+		case SegmentSpeakers:
+			for _, userItem := range users {
+				if userItem.Type == user.TypeSpeaker {
+					add(userItem.Email)
+				}
+			}
+		case SegmentUnpaid, SegmentPaid, SegmentCheckedIn, SegmentStudents:
+			for _, email := range usersEmails(users, ticketOnlyEmails) {
+				if ticket, ok := tickets[recipientKey(email)]; ok && ticketInSegment(ticket, segment) {
+					add(email)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%w %q", ErrUnknownSegment, segment)
+		}
+	}
+
+	return recipients, nil
+}
+
+func usersEmails(users []user.User, extraEmails []string) []string {
+	emails := make([]string, 0, len(users)+len(extraEmails))
+	for _, userItem := range users {
+		emails = append(emails, userItem.Email)
+	}
+
+	return append(emails, extraEmails...)
+}
+
+func ticketInSegment(ticket ticketing.Ticketing, segment Segment) bool {
+	switch segment {
+	case SegmentUnpaid:
+		return !ticket.Paid && ticket.ReceiptPhotoPath != ""
+	case SegmentPaid:
+		return ticket.Paid && !ticket.Revoked
+	case SegmentCheckedIn:
+		return ticket.Used || ticket.RedeemedCheckpoints != ""
+	case SegmentStudents:
+		return ticket.Student && !ticket.Revoked
+	default:
+		return false
+	}
+}
+
+// latestTickets returns the latest ticketing entry of every email, older ones are superseded by a newer upload.
+func (s *SegmentResolver) latestTickets(ctx context.Context) (map[string]ticketing.Ticketing, error) {
+	tickets := make(map[string]ticketing.Ticketing)
+	var offset int64
+	for {
+		currentTickets, isLastPage, err := s.ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("listing tickets: %w", err)
+		}
+
+		offset += int64(len(currentTickets))
+
+		// Tickets are listed newest first.
+		for _, ticket := range currentTickets {
+			key := recipientKey(ticket.Email)
+			if _, ok := tickets[key]; !ok {
+				tickets[key] = ticket
+			}
+		}
+
+		if isLastPage || len(currentTickets) == 0 {
+			break
+		}
+	}
+
+	return tickets, nil
+}
+
+func recipientKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
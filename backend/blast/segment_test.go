@@ -0,0 +1,192 @@
+package blast_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"conf/blast"
+	"conf/nocodb"
+	"conf/nocodb/nocodbmock"
+	"conf/ticketing"
+	"conf/user"
+	"github.com/rs/zerolog/log"
+)
+
+func newSegmentResolver(t *testing.T) (*blast.SegmentResolver, user.Repository, ticketing.Repository) {
+	t.Helper()
+
+	nocodbMockServer, err := nocodbmock.NewNocoDBMockServer()
+	if err != nil {
+		t.Fatalf("creating nocodb mock server: %s", err.Error())
+	}
+	t.Cleanup(nocodbMockServer.Close)
+
+	database, err := nocodb.NewClient(nocodb.ClientOptions{
+		ApiToken:   "testing",
+		BaseUrl:    nocodbMockServer.URL,
+		HttpClient: nocodbMockServer.Client(),
+		Logger:     log.Logger,
+	})
+	if err != nil {
+		t.Fatalf("creating nocodb client: %s", err.Error())
+	}
+
+	userRepository, err := user.NewNocoDBRepository(database, "users")
+	if err != nil {
+		t.Fatalf("creating user repository: %s", err.Error())
+	}
+
+	ticketingRepository, err := ticketing.NewNocoDBRepository(database, "ticketing")
+	if err != nil {
+		t.Fatalf("creating ticketing repository: %s", err.Error())
+	}
+
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating user domain: %s", err.Error())
+	}
+
+	segmentResolver, err := blast.NewSegmentResolver(userDomain, ticketingRepository)
+	if err != nil {
+		t.Fatalf("creating segment resolver: %s", err.Error())
+	}
+
+	return segmentResolver, userRepository, ticketingRepository
+}
+
+func TestSegmentResolver_Resolve(t *testing.T) {
+	ctx := context.Background()
+	segmentResolver, userRepository, ticketingRepository := newSegmentResolver(t)
+
+	now := time.Now()
+	users := []user.User{
+		{Name: "Aji", Email: "aji@example.com", Type: user.TypeParticipant, EmailConfirmed: true, CreatedAt: now},
+		{Name: "Budi", Email: "budi@example.com", Type: user.TypeParticipant, IsProcessed: true, EmailConfirmed: true, CreatedAt: now},
+		{Name: "Citra", Email: "citra@example.com", Type: user.TypeParticipant, EmailConfirmed: true, CreatedAt: now},
+		{Name: "Dewi", Email: "dewi@example.com", Type: user.TypeParticipant, EmailConfirmed: true, CreatedAt: now},
+		{Name: "Eko", Email: "eko@example.com", Type: user.TypeSpeaker, EmailConfirmed: true, CreatedAt: now},
+		// A second registration of the same person with different casing.
+		{Name: "Aji Kisworo", Email: "AJI@example.com ", Type: user.TypeParticipant, EmailConfirmed: true, CreatedAt: now},
+		// Gita has not confirmed the email address yet, so the payment instructions are not sent.
+		{Name: "Gita", Email: "gita@example.com", Type: user.TypeParticipant, CreatedAt: now},
+	}
+	for _, userItem := range users {
+		if err := userRepository.InsertUser(ctx, userItem); err != nil {
+			t.Fatalf("inserting user: %s", err.Error())
+		}
+	}
+
+	tickets := []ticketing.Ticketing{
+		// Budi was rejected first, then got approved on the second upload.
+		{Email: "budi@example.com", ReceiptPhotoPath: "budi-1.jpg", Rejected: true, CreatedAt: now.Add(-2 * time.Hour)},
+		{Email: "budi@example.com", ReceiptPhotoPath: "budi-2.jpg", Paid: true, Student: true, Used: true, CreatedAt: now.Add(-time.Hour)},
+		{Email: "citra@example.com", ReceiptPhotoPath: "citra.jpg", CreatedAt: now.Add(-time.Hour)},
+		{Email: "dewi@example.com", ReceiptPhotoPath: "dewi.jpg", Paid: true, Student: true, Revoked: true, CreatedAt: now.Add(-time.Hour)},
+		{Email: "eko@example.com", ReceiptPhotoPath: "eko.jpg", Paid: true, RedeemedCheckpoints: "workshop", CreatedAt: now.Add(-time.Hour)},
+		// Fajar uploaded a receipt without registering.
+		{Email: "fajar@example.com", ReceiptPhotoPath: "fajar.jpg", Paid: true, CreatedAt: now.Add(-time.Hour)},
+	}
+	for _, ticket := range tickets {
+		if err := ticketingRepository.InsertTicket(ctx, ticket); err != nil {
+			t.Fatalf("inserting ticket: %s", err.Error())
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		segments []blast.Segment
+		expected []blast.Recipient
+	}{
+		{
+			name:     "no receipt",
+			segments: []blast.Segment{blast.SegmentNoReceipt},
+			expected: []blast.Recipient{{Name: "Aji", Email: "aji@example.com"}},
+		},
+		{
+			name:     "unpaid",
+			segments: []blast.Segment{blast.SegmentUnpaid},
+			expected: []blast.Recipient{{Name: "Citra", Email: "citra@example.com"}},
+		},
+		{
+			name:     "paid",
+			segments: []blast.Segment{blast.SegmentPaid},
+			expected: []blast.Recipient{
+				{Name: "Budi", Email: "budi@example.com"},
+				{Name: "Eko", Email: "eko@example.com"},
+				{Name: "", Email: "fajar@example.com"},
+			},
+		},
+		{
+			name:     "checked in",
+			segments: []blast.Segment{blast.SegmentCheckedIn},
+			expected: []blast.Recipient{
+				{Name: "Budi", Email: "budi@example.com"},
+				{Name: "Eko", Email: "eko@example.com"},
+			},
+		},
+		{
+			name:     "speakers",
+			segments: []blast.Segment{blast.SegmentSpeakers},
+			expected: []blast.Recipient{{Name: "Eko", Email: "eko@example.com"}},
+		},
+		{
+			name:     "students",
+			segments: []blast.Segment{blast.SegmentStudents},
+			expected: []blast.Recipient{{Name: "Budi", Email: "budi@example.com"}},
+		},
+		{
+			name:     "union is deduplicated",
+			segments: []blast.Segment{blast.SegmentSpeakers, blast.SegmentPaid, blast.SegmentStudents},
+			expected: []blast.Recipient{
+				{Name: "Eko", Email: "eko@example.com"},
+				{Name: "Budi", Email: "budi@example.com"},
+				{Name: "", Email: "fajar@example.com"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recipients, err := segmentResolver.Resolve(ctx, testCase.segments)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(recipients) != len(testCase.expected) {
+				t.Fatalf("expecting recipients %+v, got %+v", testCase.expected, recipients)
+			}
+
+			for i, expected := range testCase.expected {
+				if recipients[i].Name != expected.Name || recipients[i].Email != expected.Email {
+					t.Errorf("expecting recipient %d to be %+v, got %+v", i, expected, recipients[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseSegment(t *testing.T) {
+	segment, err := blast.ParseSegment(" Checked-In ")
+	if err != nil || segment != blast.SegmentCheckedIn {
+		t.Errorf("expecting %q, got %q and error %v", blast.SegmentCheckedIn, segment, err)
+	}
+
+	_, err = blast.ParseSegment("everyone")
+	if !errors.Is(err, blast.ErrUnknownSegment) {
+		t.Errorf("expecting ErrUnknownSegment, got %v", err)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	recipients := blast.Deduplicate([]blast.Recipient{
+		{Name: "Aji", Email: "aji@example.com"},
+		{Name: "Budi", Email: "budi@example.com"},
+		{Name: "Aji Kisworo", Email: " AJI@example.com"},
+	})
+
+	if len(recipients) != 2 || recipients[0].Name != "Aji" || recipients[1].Name != "Budi" {
+		t.Errorf("expecting Aji and Budi, got %+v", recipients)
+	}
+}
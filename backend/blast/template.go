@@ -0,0 +1,180 @@
+package blast
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/flowchartsman/handlebars/v3"
+	"github.com/flowchartsman/handlebars/v3/ast"
+	"github.com/flowchartsman/handlebars/v3/parser"
+)
+
+// Template is a handlebars template of a blast along with the top level variables it refers to.
+type Template struct {
+	template  *handlebars.Template
+	variables []string
+	// required are the variables used outside of `#if` and `#unless` blocks.
+	required []string
+}
+
+// ParseTemplate parses the handlebars source and collects its variables. Variables inside `#each` and `#with`
+// blocks belong to the inner context and are skipped, unless they reach the root with `../` or `@root`.
+// Variables that are only used by `#if` and `#unless` blocks are optional, the template is written to go
+// without them.
+func ParseTemplate(source string) (*Template, error) {
+	template, err := handlebars.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	program, err := parser.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	collector := variableCollector{variables: make(map[string]bool)}
+	collector.program(program, 0, false)
+
+	variables := make([]string, 0, len(collector.variables))
+	var required []string
+	for variable, optional := range collector.variables {
+		variables = append(variables, variable)
+		if !optional {
+			required = append(required, variable)
+		}
+	}
+	sort.Strings(variables)
+	sort.Strings(required)
+
+	return &Template{template: template, variables: variables, required: required}, nil
+}
+
+// Variables lists the top level variables the template refers to, sorted by name.
+func (t *Template) Variables() []string {
+	return slices.Clone(t.variables)
+}
+
+// Missing lists the required variables of the template that are absent on data. An empty value is present, it's
+// up to the caller to leave out the values it considers missing.
+func (t *Template) Missing(data map[string]any) []string {
+	var missing []string
+	for _, variable := range t.required {
+		if value, ok := data[variable]; !ok || value == nil {
+			missing = append(missing, variable)
+		}
+	}
+
+	return missing
+}
+
+// Render executes the template. Unlike MustExec, it returns an error instead of panicking.
+func (t *Template) Render(data map[string]any) (string, error) {
+	return t.template.Exec(data)
+}
+
+// builtinHelpers are registered by the handlebars package, their names are not variables.
+var builtinHelpers = []string{"if", "unless", "with", "each", "log", "lookup", "equal"}
+
+// variableCollector walks the template AST. The depth is the number of blocks that changed the context, a path
+// refers to the root context if it climbs at least that many levels with `../`. A variable is optional while
+// every use of it is inside a conditional block.
+type variableCollector struct {
+	variables map[string]bool
+}
+
+func (c variableCollector) program(program *ast.Program, depth int, optional bool) {
+	if program == nil {
+		return
+	}
+
+	for _, statement := range program.Body {
+		switch statement := statement.(type) {
+		case *ast.MustacheStatement:
+			c.expression(statement.Expression, depth, optional)
+		case *ast.BlockStatement:
+			innerDepth := depth
+			innerOptional := optional
+			switch statement.Expression.HelperName() {
+			case "each", "with":
+				innerDepth++
+			case "if", "unless":
+				innerOptional = true
+			}
+
+			c.expression(statement.Expression, depth, innerOptional)
+			c.program(statement.Program, innerDepth, innerOptional)
+			// The else block of each and with runs on the outer context.
+			c.program(statement.Inverse, depth, innerOptional)
+		case *ast.PartialStatement:
+			for _, param := range statement.Params {
+				c.node(param, depth, optional)
+			}
+			c.hash(statement.Hash, depth, optional)
+		}
+	}
+}
+
+func (c variableCollector) expression(expression *ast.Expression, depth int, optional bool) {
+	if expression == nil {
+		return
+	}
+
+	// A path with params or a hash is a helper call, its params are the variables.
+	if len(expression.Params) > 0 || expression.Hash != nil {
+		for _, param := range expression.Params {
+			c.node(param, depth, optional)
+		}
+		c.hash(expression.Hash, depth, optional)
+		return
+	}
+
+	if slices.Contains(builtinHelpers, expression.HelperName()) {
+		return
+	}
+
+	c.node(expression.Path, depth, optional)
+}
+
+func (c variableCollector) hash(hash *ast.Hash, depth int, optional bool) {
+	if hash == nil {
+		return
+	}
+
+	for _, pair := range hash.Pairs {
+		c.node(pair.Val, depth, optional)
+	}
+}
+
+func (c variableCollector) node(node ast.Node, depth int, optional bool) {
+	switch node := node.(type) {
+	case *ast.Expression:
+		c.expression(node, depth, optional)
+	case *ast.SubExpression:
+		c.expression(node.Expression, depth, optional)
+	case *ast.PathExpression:
+		if node == nil || len(node.Parts) == 0 {
+			return
+		}
+
+		if node.Data {
+			// @root.name refers to the root context, other data variables like @index are not ours.
+			if node.Parts[0] == "root" && len(node.Parts) > 1 {
+				c.add(node.Parts[1], optional)
+			}
+			return
+		}
+
+		if node.Depth >= depth {
+			c.add(node.Parts[0], optional)
+		}
+	}
+}
+
+func (c variableCollector) add(variable string, optional bool) {
+	if wasOptional, ok := c.variables[variable]; ok {
+		optional = optional && wasOptional
+	}
+
+	c.variables[variable] = optional
+}
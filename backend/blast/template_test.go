@@ -0,0 +1,106 @@
+package blast_test
+
+import (
+	"slices"
+	"testing"
+
+	"conf/blast"
+)
+
+func TestParseTemplate_Variables(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		expected []string
+	}{
+		{
+			name:     "plain variables",
+			source:   "Hai {{ name }}, transfer ke {{{bankAccounts}}} sebelum {{deadline}}.",
+			expected: []string{"bankAccounts", "deadline", "name"},
+		},
+		{
+			name:     "nested path",
+			source:   "{{ user.name }} {{this.email}}",
+			expected: []string{"email", "user"},
+		},
+		{
+			name:     "conditional blocks keep the context",
+			source:   "{{#if discount}}{{ discountPrice }}{{else}}{{ price }}{{/if}}{{#unless paid}}{{dueDate}}{{/unless}}",
+			expected: []string{"discount", "discountPrice", "dueDate", "paid", "price"},
+		},
+		{
+			name:     "each block changes the context",
+			source:   "{{#each sessions}}{{title}} {{@index}} {{../name}} {{@root.conferenceEmail}}{{else}}{{ emptyMessage }}{{/each}}",
+			expected: []string{"conferenceEmail", "emptyMessage", "name", "sessions"},
+		},
+		{
+			name:     "nested blocks",
+			source:   "{{#with speaker}}{{#each talks}}{{title}} {{../bio}} {{../../eventName}}{{/each}}{{/with}}",
+			expected: []string{"eventName", "speaker"},
+		},
+		{
+			name:     "helper params and subexpressions",
+			source:   `{{lookup prices tier}} {{#if (equal tier "student")}}{{studentNote}}{{/if}} {{log message level="info"}}`,
+			expected: []string{"message", "prices", "studentNote", "tier"},
+		},
+		{
+			name:     "comments and literals",
+			source:   `{{! {{ignored}} }}{{#if true}}static{{/if}}`,
+			expected: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			template, err := blast.ParseTemplate(testCase.source)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if variables := template.Variables(); !slices.Equal(variables, testCase.expected) {
+				t.Errorf("expecting variables %v, got %v", testCase.expected, variables)
+			}
+		})
+	}
+}
+
+func TestParseTemplate_SyntaxError(t *testing.T) {
+	_, err := blast.ParseTemplate("Hai {{#if name}}")
+	if err == nil {
+		t.Error("expecting an error for an unclosed block")
+	}
+}
+
+func TestTemplate_Missing(t *testing.T) {
+	template, err := blast.ParseTemplate("Hai {{name}}, {{ticketPrice}} {{nickname}}{{#if bankAccounts}} {{{bankAccounts}}}{{/if}}{{#unless paid}} {{dueDate}}{{/unless}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	missing := template.Missing(map[string]any{
+		"name":        "",
+		"ticketPrice": 150000,
+	})
+
+	if !slices.Equal(missing, []string{"nickname"}) {
+		t.Errorf("expecting only nickname to be missing, got %v", missing)
+	}
+
+	conditional, err := blast.ParseTemplate("{{#if discount}}{{price}}{{/if}} {{price}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if missing := conditional.Missing(map[string]any{}); !slices.Equal(missing, []string{"price"}) {
+		t.Errorf("expecting price used outside the conditional to be missing, got %v", missing)
+	}
+
+	rendered, err := template.Render(map[string]any{"name": "Aji", "ticketPrice": 150000, "nickname": "aji", "paid": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if rendered != "Hai Aji, 150000 aji" {
+		t.Errorf("unexpected rendered template %q", rendered)
+	}
+}
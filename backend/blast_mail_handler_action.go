@@ -1,12 +1,18 @@
 package main
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"conf/blast"
 	"conf/mailer"
 	"conf/user"
-	"github.com/flowchartsman/handlebars/v3"
 	"github.com/getsentry/sentry-go"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
@@ -43,6 +49,14 @@ func BlastMailHandlerAction(cCtx *cli.Context) error {
 	htmlBody := cCtx.String("html-body")
 	mailCsv := cCtx.String("recipients")
 	singleRecipient := cCtx.String("single-recipient")
+	campaign := cCtx.String("campaign")
+	resume := cCtx.Bool("resume")
+	dryRun := cCtx.Bool("dry-run")
+	dryRunOutput := cCtx.String("dry-run-output")
+	delimiter, err := csvDelimiter(cCtx.String("delimiter"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid delimiter")
+	}
 
 	if subject == "" {
 		log.Fatal().Msg("Subject is required")
@@ -53,16 +67,30 @@ func BlastMailHandlerAction(cCtx *cli.Context) error {
 	if htmlBody == "" {
 		log.Fatal().Msg("Html template is required")
 	}
-	if mailCsv == "" && singleRecipient == "" {
+	if mailCsv == "" && singleRecipient == "" && len(cCtx.StringSlice("segment")) == 0 {
 		log.Fatal().Msg("Recipient is required")
 	}
 
+	var segments []blast.Segment
+	for _, name := range cCtx.StringSlice("segment") {
+		segment, err := blast.ParseSegment(name)
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid segment")
+		}
+
+		segments = append(segments, segment)
+	}
+	if campaign == "" {
+		campaign = campaignSlug(subject)
+		log.Info().Msgf("Using %q as the campaign name, pass it to --campaign to resume the blast", campaign)
+	}
+
 	plaintextContent, err := os.ReadFile(plaintext)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to read plaintext template")
 	}
 
-	plaintextTemplate, err := handlebars.Parse(string(plaintextContent))
+	plaintextTemplate, err := blast.ParseTemplate(string(plaintextContent))
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to parse plaintext template")
 	}
@@ -72,73 +100,264 @@ func BlastMailHandlerAction(cCtx *cli.Context) error {
 		log.Fatal().Err(err).Msg("failed to read html template")
 	}
 
-	htmlTemplate, err := handlebars.Parse(string(htmlContent))
+	htmlTemplate, err := blast.ParseTemplate(string(htmlContent))
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to parse html template")
 	}
 
-	var userList []user.User
+	var recipients []blast.Recipient
 
 	if mailCsv != "" {
-		emailList, err := os.ReadFile(mailCsv)
+		emailList, err := os.Open(mailCsv)
 		if err != nil {
 			log.Fatal().Err(err).Msg("failed to read email list")
 		}
 
-		userList, err = csvReader(string(emailList), true)
+		recipients, err = blast.ReadRecipients(emailList, blast.CsvOptions{Delimiter: delimiter, RequireName: true})
+		_ = emailList.Close()
 		if err != nil {
 			log.Fatal().Err(err).Msg("failed to parse email list")
 		}
-	} else {
-		userList = append(userList, user.User{
+	}
+
+	if singleRecipient != "" {
+		recipients = append(recipients, blast.Recipient{
 			Email: singleRecipient,
 		})
 	}
 
-	mailSender := mailer.NewMailSender(&mailer.MailConfiguration{
-		SmtpHostname: config.Mailer.Hostname,
-		SmtpPort:     config.Mailer.Port,
-		SmtpFrom:     config.Mailer.From,
-		SmtpPassword: config.Mailer.Password,
-	})
+	// The send ledger lives on the database, a dry run only needs it to query the segments.
+	var repositories Repositories
+	if len(segments) > 0 || !dryRun {
+		repositories, err = NewRepositories(cCtx.Context, config)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create repositories")
+		}
+		defer func() {
+			if err := repositories.Close(); err != nil {
+				log.Warn().Err(err).Msg("Closing database")
+			}
+		}()
+	}
 
-	for _, userItem := range userList {
-		mail := &mailer.Mail{
-			RecipientName:  userItem.Name,
-			RecipientEmail: userItem.Email,
-			Subject:        subject,
-			PlainTextBody:  string(plaintextContent),
-			HtmlBody:       string(htmlContent),
+	if len(segments) > 0 {
+		userDomain, err := user.NewUserDomain(repositories.User, user.UserDomainOptions{})
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create user domain")
 		}
 
-		// Parse email template information
-		emailTemplate := map[string]any{
-			"ticketPrice":                         config.EmailTemplate.TicketPrice,
-			"ticketStudentCollegePrice":           config.EmailTemplate.TicketStudentCollegePrice,
-			"ticketStudentHighSchoolPrice":        config.EmailTemplate.TicketStudentHighSchoolPrice,
-			"ticketStudentCollegeDiscount":        config.EmailTemplate.TicketStudentCollegeDiscount,
-			"ticketStudentHighSchoolDiscount":     config.EmailTemplate.TicketStudentHighSchoolDiscount,
-			"percentageStudentCollegeDiscount":    config.EmailTemplate.PercentageStudentCollegeDiscount,
-			"percentageStudentHighSchoolDiscount": config.EmailTemplate.PercentageStudentHighSchoolDiscount,
-			"conferenceEmail":                     config.EmailTemplate.ConferenceEmail,
-			"bankAccounts":                        config.EmailTemplate.BankAccounts,
+		segmentResolver, err := blast.NewSegmentResolver(userDomain, repositories.Ticketing)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create segment resolver")
 		}
-		// Execute handlebars template only if userItem.Name is not empty
-		if userItem.Name != "" {
-			emailTemplate["name"] = userItem.Name
+
+		segmentRecipients, err := segmentResolver.Resolve(cCtx.Context, segments)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to query segment recipients")
 		}
 
-		mail.PlainTextBody = plaintextTemplate.MustExec(emailTemplate)
-		mail.HtmlBody = htmlTemplate.MustExec(emailTemplate)
+		log.Info().Msgf("Found %d recipients on segments %v", len(segmentRecipients), segments)
+		recipients = append(recipients, segmentRecipients...)
+	}
+
+	recipients = blast.Deduplicate(recipients)
+	if len(recipients) == 0 {
+		log.Fatal().Msg("There are no recipients")
+	}
+
+	mailBlast := blast.Blast{
+		Subject:   subject,
+		PlainText: plaintextTemplate,
+		Html:      htmlTemplate,
+		Variables: config.EmailTemplateVariables(),
+	}
+
+	mails, missingFields, err := mailBlast.Render(recipients)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to render templates")
+	}
 
-		err := mailSender.Send(cCtx.Context, mail)
+	printMissingFields(cCtx.App.Writer, missingFields)
+
+	if dryRun {
+		paths, err := blast.WriteMessages(cCtx.Context, mailer.NewMailSenderWithTransport(nil, config.MailFrom()), dryRunOutput, mails)
 		if err != nil {
-			log.Error().Err(err).Msgf("failed to send email to %s", userItem.Email)
+			return fmt.Errorf("writing messages: %w", err)
+		}
+
+		log.Info().Msgf("Dry run: rendered %d messages to %s, nothing was sent", len(paths), dryRunOutput)
+		if len(missingFields) > 0 {
+			return fmt.Errorf("%d recipients are missing template variables", len(missingFields))
+		}
+
+		return nil
+	}
+
+	if len(missingFields) > 0 {
+		log.Fatal().Msgf("%d recipients are missing template variables, nothing was sent", len(missingFields))
+	}
+
+	// The blast sends synchronously instead of going through the outbox, so failures show up on the log
+	// right away.
+	bulkMailSender, err := config.BulkMailSender()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create bulk mail sender")
+	}
+
+	report, err := bulkMailSender.SendCampaign(cCtx.Context, repositories.BlastLedger, campaign, resume, mails)
+	if errors.Is(err, mailer.ErrCampaignExists) {
+		log.Fatal().Err(err).Msg("pass --resume to skip the recipients that were already sent, or choose another --campaign")
+	}
+	if err != nil && len(report.Results) == 0 {
+		log.Fatal().Err(err).Msg("failed to start the campaign, nothing was sent")
+	}
+	if err != nil {
+		// The mails went out already, only their outcome is missing from the ledger.
+		log.Error().Err(err).Msg("failed to record the campaign ledger")
+	}
+
+	for _, result := range report.Results {
+		if result.Err != nil {
+			log.Error().Err(result.Err).Msgf("failed to send email to %s", result.RecipientEmail)
+			continue
+		}
+
+		log.Info().Msgf("Sent email to %s", result.RecipientEmail)
+	}
+
+	log.Info().
+		Str("campaign", campaign).
+		Int("sent", report.Sent()).
+		Int("failed", len(report.Failed())).
+		Int("skipped", report.Skipped).
+		Int("duplicates", report.Duplicates).
+		Msg("Blasting email done")
+	return nil
+}
+
+// printMissingFields prints the recipients that are missing template variables, one per line.
+func printMissingFields(w io.Writer, missingFields []blast.MissingFields) {
+	if len(missingFields) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "%d recipients are missing template variables:\n", len(missingFields))
+	for _, missing := range missingFields {
+		_, _ = fmt.Fprintf(w, "  %s: %s\n", missing.RecipientEmail, strings.Join(missing.Fields, ", "))
+	}
+}
+
+// campaignSlug turns the subject into a campaign name, keeping only lowercase letters and digits separated by
+// dashes.
+func campaignSlug(subject string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(subject) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		dash = true
+	}
+
+	if slug.Len() == 0 {
+		return "blast"
+	}
+
+	return slug.String()
+}
+
+// csvDelimiter parses the --delimiter flag. An empty value detects the delimiter from the header, `tab` stands
+// for a tab character since it's awkward to type on the shell.
+func csvDelimiter(value string) (rune, error) {
+	switch value {
+	case "":
+		return 0, nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+
+	runes := []rune(value)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+		return 0, fmt.Errorf("delimiter must be a single character other than a quote or a newline, got %q", value)
+	}
+
+	return runes[0], nil
+}
+
+// BlastMailStatusHandlerAction prints the number of sent, failed, and pending recipients of a campaign, and
+// exports the failed ones as CSV if --failures-csv is given.
+func BlastMailStatusHandlerAction(cCtx *cli.Context) error {
+	config, err := GetConfig(cCtx.String("config-file-path"))
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	campaign := cCtx.String("campaign")
+
+	repositories, err := NewRepositories(cCtx.Context, config)
+	if err != nil {
+		return fmt.Errorf("creating repositories: %w", err)
+	}
+	defer func() {
+		if err := repositories.Close(); err != nil {
+			log.Warn().Err(err).Msg("Closing database")
+		}
+	}()
+
+	entries, err := repositories.BlastLedger.ListEntries(cCtx.Context, campaign)
+	if err != nil {
+		return fmt.Errorf("listing ledger entries: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("campaign %q does not exist", campaign)
+	}
+
+	summary := mailer.SummarizeLedger(entries)
+	_, _ = fmt.Fprintf(cCtx.App.Writer, "Campaign: %s\nSent:     %d\nFailed:   %d\nPending:  %d\n", campaign, summary.Sent, summary.Failed, summary.Pending)
+
+	failuresCsv := cCtx.String("failures-csv")
+	if failuresCsv == "" {
+		return nil
+	}
+
+	file, err := os.Create(failuresCsv)
+	if err != nil {
+		return fmt.Errorf("creating failures csv: %w", err)
+	}
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"email", "name", "attempts", "error", "updated_at"})
+	for _, entry := range entries {
+		if entry.Status != mailer.LedgerStatusFailed {
 			continue
 		}
 
-		log.Info().Msgf("Sending email to %s", userItem.Email)
+		_ = writer.Write([]string{
+			entry.RecipientEmail,
+			entry.RecipientName,
+			strconv.Itoa(entry.Attempts),
+			entry.LastError,
+			entry.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("writing failures csv: %w", err)
 	}
-	log.Info().Msg("Blasting email done")
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing failures csv: %w", err)
+	}
+
+	log.Info().Msgf("Exported %d failed recipients to %s", summary.Failed, failuresCsv)
 	return nil
 }
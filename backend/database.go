@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"conf/mailer"
+	"conf/migrations"
+	"conf/nocodb"
+	"conf/ticketing"
+	"conf/user"
+	"github.com/rs/zerolog/log"
+)
+
+// Repositories holds the storage implementation for each domain, as selected by Config.Database.Driver.
+type Repositories struct {
+	User      user.Repository
+	Ticketing ticketing.Repository
+	// MailOutbox stores the queued mails of mailer.Outbox.
+	MailOutbox mailer.OutboxStore
+	// BlastLedger records the recipients of the blast-email campaigns.
+	BlastLedger mailer.CampaignLedger
+	// Close releases the underlying database connection, if there is any.
+	Close func() error
+}
+
+func NewRepositories(ctx context.Context, config Config) (Repositories, error) {
+	switch config.Database.Driver {
+	case "", "nocodb":
+		database, err := nocodb.NewClient(nocodb.ClientOptions{
+			ApiToken:   config.Database.NocoDbApiKey,
+			BaseUrl:    config.Database.NocoDbBaseUrl,
+			HttpClient: &http.Client{Transport: NewSentryRoundTripper(http.DefaultTransport, nil)},
+			Logger:     log.Logger,
+		})
+		if err != nil {
+			return Repositories{}, fmt.Errorf("creating database client instance: %w", err)
+		}
+
+		userRepository, err := user.NewNocoDBRepository(database, config.Database.UserTableId)
+		if err != nil {
+			return Repositories{}, fmt.Errorf("creating user repository: %w", err)
+		}
+
+		ticketingRepository, err := ticketing.NewNocoDBRepository(database, config.Database.TicketingTableId)
+		if err != nil {
+			return Repositories{}, fmt.Errorf("creating ticketing repository: %w", err)
+		}
+
+		outboxStore, err := mailer.NewFileOutboxStore(config.Mailer.Outbox.Path)
+		if err != nil {
+			return Repositories{}, fmt.Errorf("creating mail outbox store: %w", err)
+		}
+
+		blastLedger, err := mailer.NewFileCampaignLedger(config.Mailer.Bulk.LedgerPath)
+		if err != nil {
+			return Repositories{}, fmt.Errorf("creating blast ledger: %w", err)
+		}
+
+		return Repositories{
+			User:        userRepository,
+			Ticketing:   ticketingRepository,
+			MailOutbox:  outboxStore,
+			BlastLedger: blastLedger,
+			Close:       func() error { return nil },
+		}, nil
+	case "postgres":
+		db, err := sql.Open("pgx", config.Database.PostgresUrl)
+		if err != nil {
+			return Repositories{}, fmt.Errorf("opening postgres connection: %w", err)
+		}
+
+		err = db.PingContext(ctx)
+		if err != nil {
+			_ = db.Close()
+			return Repositories{}, fmt.Errorf("pinging postgres: %w", err)
+		}
+
+		err = migrations.Up(ctx, db)
+		if err != nil {
+			_ = db.Close()
+			return Repositories{}, fmt.Errorf("migrating database: %w", err)
+		}
+
+		userRepository, err := user.NewPostgresRepository(db)
+		if err != nil {
+			_ = db.Close()
+			return Repositories{}, fmt.Errorf("creating user repository: %w", err)
+		}
+
+		ticketingRepository, err := ticketing.NewPostgresRepository(db)
+		if err != nil {
+			_ = db.Close()
+			return Repositories{}, fmt.Errorf("creating ticketing repository: %w", err)
+		}
+
+		outboxStore, err := mailer.NewPostgresOutboxStore(db)
+		if err != nil {
+			_ = db.Close()
+			return Repositories{}, fmt.Errorf("creating mail outbox store: %w", err)
+		}
+
+		blastLedger, err := mailer.NewPostgresCampaignLedger(db)
+		if err != nil {
+			_ = db.Close()
+			return Repositories{}, fmt.Errorf("creating blast ledger: %w", err)
+		}
+
+		return Repositories{
+			User:        userRepository,
+			Ticketing:   ticketingRepository,
+			MailOutbox:  outboxStore,
+			BlastLedger: blastLedger,
+			Close:       db.Close,
+		}, nil
+	default:
+		return Repositories{}, fmt.Errorf("unknown database driver %q", config.Database.Driver)
+	}
+}
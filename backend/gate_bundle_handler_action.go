@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"conf/mailer"
+	"conf/ticketing"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+	"gocloud.dev/blob"
+)
+
+// openTicketDomain creates a ticket domain for the CLI commands that does not run the HTTP server.
+// Call the returned function to release the resources once the domain is no longer used.
+func openTicketDomain(ctx context.Context, config Config) (*ticketing.TicketDomain, func(), error) {
+	repositories, err := NewRepositories(ctx, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating repositories: %w", err)
+	}
+
+	bucket, err := blob.OpenBucket(ctx, config.BlobUrl)
+	if err != nil {
+		_ = repositories.Close()
+		return nil, nil, fmt.Errorf("opening bucket: %w", err)
+	}
+
+	closer := func() {
+		if err := bucket.Close(); err != nil {
+			log.Warn().Err(err).Msg("Closing bucket")
+		}
+
+		if err := repositories.Close(); err != nil {
+			log.Warn().Err(err).Msg("Closing database")
+		}
+	}
+
+	signatureKeyring, err := config.SignatureKeyring()
+	if err != nil {
+		closer()
+		return nil, nil, fmt.Errorf("creating signature keyring: %w", err)
+	}
+
+	walletIssuer, err := config.WalletIssuer()
+	if err != nil {
+		closer()
+		return nil, nil, fmt.Errorf("creating wallet issuer: %w", err)
+	}
+
+	mailTransport, err := config.MailTransport()
+	if err != nil {
+		closer()
+		return nil, nil, fmt.Errorf("creating mail transport: %w", err)
+	}
+
+	mailSender := mailer.NewMailSenderWithTransport(mailTransport, config.MailFrom())
+
+	mailTemplates, err := config.MailTemplates()
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+
+	ticketDomainOptions, err := config.TicketDomainOptions(walletIssuer, nil)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(repositories.Ticketing, bucket, signatureKeyring, mailSender, mailTemplates, ticketDomainOptions)
+	if err != nil {
+		closer()
+		return nil, nil, fmt.Errorf("creating ticket domain: %w", err)
+	}
+
+	return ticketDomain, closer, nil
+}
+
+func GateBundleExportHandlerAction(cCtx *cli.Context) error {
+	config, err := GetConfig(cCtx.String("config-file-path"))
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	ticketDomain, closer, err := openTicketDomain(cCtx.Context, config)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	bundle, err := ticketDomain.ExportGateBundle(cCtx.Context)
+	if err != nil {
+		return fmt.Errorf("exporting gate bundle: %w", err)
+	}
+
+	output := cCtx.String("output")
+	if output == "" || output == "-" {
+		_, err = os.Stdout.Write(bundle)
+		return err
+	}
+
+	err = os.WriteFile(output, bundle, 0600)
+	if err != nil {
+		return fmt.Errorf("writing gate bundle: %w", err)
+	}
+
+	log.Info().Str("output", output).Msg("Gate bundle exported")
+	return nil
+}
+
+func GateBundleReconcileHandlerAction(cCtx *cli.Context) error {
+	config, err := GetConfig(cCtx.String("config-file-path"))
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	input, err := os.ReadFile(cCtx.String("input"))
+	if err != nil {
+		return fmt.Errorf("reading redemptions: %w", err)
+	}
+
+	var redemptions []ticketing.OfflineRedemption
+	if err := json.Unmarshal(input, &redemptions); err != nil {
+		return fmt.Errorf("decoding redemptions: %w", err)
+	}
+
+	ticketDomain, closer, err := openTicketDomain(cCtx.Context, config)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	report, err := ticketDomain.ReconcileRedemptions(cCtx.Context, redemptions)
+	if err != nil {
+		return fmt.Errorf("reconciling redemptions: %w", err)
+	}
+
+	for _, conflict := range report.Conflicts {
+		log.Warn().
+			Int64("ticket_id", conflict.TicketId).
+			Str("checkpoint", conflict.Checkpoint).
+			Time("redeemed_at", conflict.RedeemedAt).
+			Msg(conflict.Reason)
+	}
+
+	log.Info().Int("accepted", report.Accepted).Int("conflicts", len(report.Conflicts)).Msg("Redemptions reconciled")
+	return nil
+}
@@ -1,6 +1,6 @@
 module conf
 
-go 1.21
+go 1.23
 
 require (
 	dario.cat/mergo v1.0.0
@@ -18,6 +18,8 @@ require (
 	github.com/urfave/cli/v2 v2.27.1
 	gocloud.dev v0.36.0
 	golang.org/x/crypto v0.21.0
+	golang.org/x/net v0.23.0
+	golang.org/x/text v0.14.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -60,10 +62,8 @@ require (
 	github.com/stretchr/testify v1.8.4 // indirect
 	github.com/xrash/smetrics v0.0.0-20231213231151-1d8dd44e695e // indirect
 	go.opencensus.io v0.24.0 // indirect
-	golang.org/x/net v0.23.0 // indirect
 	golang.org/x/sync v0.6.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/xerrors v0.0.0-20231012003039-104605ab7028 // indirect
 	google.golang.org/api v0.151.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231120223509-83a465c0220f // indirect
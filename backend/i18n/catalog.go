@@ -0,0 +1,108 @@
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalogs
+var builtin embed.FS
+
+// Builtin holds the message catalogs that are compiled into the binary.
+var Builtin, _ = fs.Sub(builtin, "catalogs")
+
+// Catalog holds the translated messages of every supported locale. The messages live on `<locale>.yaml`
+// files, a flat mapping of snake_case keys to the message. A message may refer to its arguments as `{name}`.
+// A Catalog is safe for concurrent use.
+type Catalog struct {
+	messages map[Locale]map[string]string
+}
+
+// LoadCatalog reads the catalog of every supported locale on the file system. Every locale must translate
+// the same set of keys as the Default one, so a missing translation is caught on startup instead of showing
+// up in front of an attendee.
+func LoadCatalog(fileSystem fs.FS) (*Catalog, error) {
+	catalog := &Catalog{messages: make(map[Locale]map[string]string, len(Locales))}
+	for _, locale := range Locales {
+		name := string(locale) + ".yaml"
+		content, err := fs.ReadFile(fileSystem, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+
+		var messages map[string]string
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		err = decoder.Decode(&messages)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+
+		catalog.messages[locale] = messages
+	}
+
+	var errs []error
+	for _, locale := range Locales[1:] {
+		for _, key := range catalog.Keys() {
+			if _, ok := catalog.messages[locale][key]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing %s", locale, key))
+			}
+		}
+
+		for key := range catalog.messages[locale] {
+			if _, ok := catalog.messages[Default][key]; !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown %s", locale, key))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return catalog, nil
+}
+
+// Keys lists the message keys, sorted.
+func (c *Catalog) Keys() []string {
+	keys := make([]string, 0, len(c.messages[Default]))
+	for key := range c.messages[Default] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Message returns the message of the key translated to the locale, an unsupported locale gets the Default
+// one. The arguments are key-value pairs that replace the `{key}` placeholders, for example:
+//
+//	catalog.Message(i18n.English, "ticket_not_found", "id", 42)
+//
+// The key itself is returned if it's not on the catalog.
+func (c *Catalog) Message(locale Locale, key string, args ...any) string {
+	message, ok := c.messages[locale][key]
+	if !ok {
+		message, ok = c.messages[Default][key]
+		if !ok {
+			return key
+		}
+	}
+
+	if len(args) < 2 {
+		return message
+	}
+
+	replacements := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		replacements = append(replacements, "{"+fmt.Sprint(args[i])+"}", fmt.Sprint(args[i+1]))
+	}
+
+	return strings.NewReplacer(replacements...).Replace(message)
+}
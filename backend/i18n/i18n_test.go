@@ -0,0 +1,128 @@
+package i18n_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"conf/i18n"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected i18n.Locale
+		ok       bool
+	}{
+		{value: "id", expected: i18n.Indonesian, ok: true},
+		{value: "id-ID", expected: i18n.Indonesian, ok: true},
+		{value: " EN ", expected: i18n.English, ok: true},
+		{value: "en-GB", expected: i18n.English, ok: true},
+		{value: "fr", ok: false},
+		{value: "", ok: false},
+		{value: "not a locale", ok: false},
+	}
+
+	for _, testCase := range testCases {
+		locale, ok := i18n.Parse(testCase.value)
+		if locale != testCase.expected || ok != testCase.ok {
+			t.Errorf("Parse(%q): expecting %q %t, got %q %t", testCase.value, testCase.expected, testCase.ok, locale, ok)
+		}
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	testCases := map[string]i18n.Locale{
+		"":                           i18n.Default,
+		"en-US,en;q=0.9":             i18n.English,
+		"id-ID,id;q=0.9,en-US;q=0.8": i18n.Indonesian,
+		"fr-FR,fr;q=0.9,en;q=0.5":    i18n.English,
+		"de-DE":                      i18n.Default,
+		"en;q=0.2,id;q=0.8":          i18n.Indonesian,
+		"*":                          i18n.Default,
+		";;;garbage":                 i18n.Default,
+	}
+
+	for header, expected := range testCases {
+		if locale := i18n.FromAcceptLanguage(header); locale != expected {
+			t.Errorf("FromAcceptLanguage(%q): expecting %q, got %q", header, expected, locale)
+		}
+	}
+}
+
+func TestCatalog_Message(t *testing.T) {
+	catalog, err := i18n.LoadCatalog(i18n.Builtin)
+	if err != nil {
+		t.Fatalf("loading builtin catalog: %s", err.Error())
+	}
+
+	if message := catalog.Message(i18n.English, "internal_server_error"); message != "Internal server error" {
+		t.Errorf("unexpected message %q", message)
+	}
+
+	if message := catalog.Message(i18n.Indonesian, "mail_greeting_name", "name", "Aji"); message != "Hai, Aji! 👋" {
+		t.Errorf("unexpected message %q", message)
+	}
+
+	if message := catalog.Message("fr", "done"); message != "Selesai" {
+		t.Errorf("expecting the default locale for an unsupported locale, got %q", message)
+	}
+
+	if message := catalog.Message(i18n.English, "does_not_exist"); message != "does_not_exist" {
+		t.Errorf("expecting the key for a missing message, got %q", message)
+	}
+}
+
+func TestLoadCatalog(t *testing.T) {
+	testCases := []struct {
+		name  string
+		files fstest.MapFS
+		valid bool
+	}{
+		{
+			name: "complete",
+			files: fstest.MapFS{
+				"id.yaml": {Data: []byte("done: Selesai\n")},
+				"en.yaml": {Data: []byte("done: Done\n")},
+			},
+			valid: true,
+		},
+		{
+			name: "missing translation",
+			files: fstest.MapFS{
+				"id.yaml": {Data: []byte("done: Selesai\nhello: Halo\n")},
+				"en.yaml": {Data: []byte("done: Done\n")},
+			},
+		},
+		{
+			name: "unknown key",
+			files: fstest.MapFS{
+				"id.yaml": {Data: []byte("done: Selesai\n")},
+				"en.yaml": {Data: []byte("done: Done\nhello: Hello\n")},
+			},
+		},
+		{
+			name:  "missing locale",
+			files: fstest.MapFS{"id.yaml": {Data: []byte("done: Selesai\n")}},
+		},
+		{
+			name: "malformed",
+			files: fstest.MapFS{
+				"id.yaml": {Data: []byte("done: [Selesai\n")},
+				"en.yaml": {Data: []byte("done: Done\n")},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := i18n.LoadCatalog(testCase.files)
+			if testCase.valid && err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+
+			if !testCase.valid && err == nil {
+				t.Error("expecting an error, got nil")
+			}
+		})
+	}
+}
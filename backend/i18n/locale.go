@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Locale is a language that the attendee facing messages are translated to, as a BCP 47 base language.
+type Locale string
+
+const (
+	Indonesian Locale = "id"
+	English    Locale = "en"
+)
+
+// Default is used when the attendee did not pick a locale, or picked one that is not supported.
+const Default = Indonesian
+
+// Locales lists the supported locales, the Default comes first.
+var Locales = []Locale{Indonesian, English}
+
+var matcher = language.NewMatcher([]language.Tag{language.Indonesian, language.English})
+
+// Parse returns the supported locale of a BCP 47 tag, such as "en", "en-GB", or "id-ID". It returns false if
+// the tag is malformed or the language is not supported.
+func Parse(value string) (Locale, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+
+	tag, err := language.Parse(value)
+	if err != nil {
+		return "", false
+	}
+
+	base, _ := tag.Base()
+	for _, locale := range Locales {
+		if base.String() == string(locale) {
+			return locale, true
+		}
+	}
+
+	return "", false
+}
+
+// FromAcceptLanguage picks the best supported locale of an Accept-Language header, weighing the quality
+// values. It returns Default if none of the languages is supported or the header is malformed.
+func FromAcceptLanguage(header string) Locale {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+
+	return Locales[index]
+}
+
+// String implements fmt.Stringer.
+func (l Locale) String() string {
+	return string(l)
+}
@@ -0,0 +1,282 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+type BulkOptions struct {
+	// Concurrency is the number of connections delivering at the same time. Defaults to 4.
+	Concurrency int
+	// RatePerMinute caps the messages sent per minute across every connection, zero means unlimited.
+	RatePerMinute int
+	// Burst is the number of messages that can be sent right away before RatePerMinute kicks in. Defaults
+	// to 1.
+	Burst int
+	// MessagesPerConnection is the number of messages sent before the connection is reopened, most providers
+	// limit it. Defaults to 100.
+	MessagesPerConnection int
+}
+
+// BulkResult is the delivery result of a single recipient. Err is nil if the message was accepted.
+type BulkResult struct {
+	RecipientName  string
+	RecipientEmail string
+	Err            error
+	SentAt         time.Time
+}
+
+// BulkReport lists the results in the same order as the mails given to SendAll.
+type BulkReport struct {
+	Results []BulkResult
+}
+
+// Sent counts the messages that were accepted.
+func (r BulkReport) Sent() int {
+	var sent int
+	for _, result := range r.Results {
+		if result.Err == nil {
+			sent++
+		}
+	}
+
+	return sent
+}
+
+// Failed lists the results of the messages that were not accepted.
+func (r BulkReport) Failed() []BulkResult {
+	var failed []BulkResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// BulkSender delivers many mails at once for the blasts. It reuses the connections of a SessionTransport to
+// send many messages on each, and shares a token bucket across every SendAll call so the provider's rate limit
+// is respected even if blasts overlap.
+type BulkSender struct {
+	mailer  *Mailer
+	options BulkOptions
+	limiter *tokenBucket
+}
+
+func NewBulkSender(mailer *Mailer, options BulkOptions) (*BulkSender, error) {
+	if mailer == nil {
+		return nil, fmt.Errorf("mailer is nil")
+	}
+
+	if options.RatePerMinute < 0 {
+		return nil, fmt.Errorf("rate per minute is negative")
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+
+	if options.Burst <= 0 {
+		options.Burst = 1
+	}
+
+	if options.MessagesPerConnection <= 0 {
+		options.MessagesPerConnection = 100
+	}
+
+	return &BulkSender{
+		mailer:  mailer,
+		options: options,
+		limiter: newTokenBucket(options.RatePerMinute, options.Burst),
+	}, nil
+}
+
+// SendAll delivers every mail and waits until all of them are done. A failed mail does not stop the others.
+// If the context is cancelled, the mails that have not been sent are reported with the context error.
+//
+// onResult, if it's not nil, is called with the index of the mail as soon as each delivery finishes, so the
+// progress can be persisted before the whole blast is done. The calls never overlap.
+func (b *BulkSender) SendAll(ctx context.Context, mails []*Mail, onResult func(index int, result BulkResult)) BulkReport {
+	span := sentry.StartSpan(ctx, "mailer.send_all")
+	defer span.Finish()
+
+	report := BulkReport{Results: make([]BulkResult, len(mails))}
+	attempted := make([]bool, len(mails))
+	for i, mail := range mails {
+		report.Results[i] = BulkResult{RecipientName: mail.RecipientName, RecipientEmail: mail.RecipientEmail}
+	}
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range mails {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var resultMutex sync.Mutex
+	done := func(i int) {
+		if onResult == nil {
+			return
+		}
+
+		resultMutex.Lock()
+		defer resultMutex.Unlock()
+		onResult(i, report.Results[i])
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(b.options.Concurrency, len(mails)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.work(ctx, jobs, mails, report.Results, attempted, done)
+		}()
+	}
+	wg.Wait()
+
+	for i := range report.Results {
+		if !attempted[i] {
+			report.Results[i].Err = ctx.Err()
+			done(i)
+		}
+	}
+
+	return report
+}
+
+// work delivers the mails from jobs on a single session, reopening it after MessagesPerConnection messages or
+// after a failure, since the connection might be broken.
+func (b *BulkSender) work(ctx context.Context, jobs <-chan int, mails []*Mail, results []BulkResult, attempted []bool, done func(i int)) {
+	var session Session
+	var delivered int
+	closeSession := func() {
+		if session != nil {
+			_ = session.Close()
+			session = nil
+			delivered = 0
+		}
+	}
+	defer closeSession()
+
+	for i := range jobs {
+		attempted[i] = true
+
+		if err := b.limiter.Wait(ctx); err != nil {
+			results[i].Err = err
+			done(i)
+			continue
+		}
+
+		if session == nil {
+			var err error
+			session, err = b.openSession(ctx)
+			if err != nil {
+				results[i].Err = fmt.Errorf("opening session: %w", err)
+				done(i)
+				continue
+			}
+		}
+
+		err := session.Deliver(ctx, b.mailer.from.Address, []string{mails[i].RecipientEmail}, b.mailer.Render(ctx, mails[i]))
+		if err != nil {
+			results[i].Err = err
+			closeSession()
+			done(i)
+			continue
+		}
+
+		results[i].SentAt = time.Now()
+		done(i)
+		delivered++
+		if delivered >= b.options.MessagesPerConnection {
+			closeSession()
+		}
+	}
+}
+
+func (b *BulkSender) openSession(ctx context.Context) (Session, error) {
+	if transport, ok := b.mailer.transport.(SessionTransport); ok {
+		return transport.OpenSession(ctx)
+	}
+
+	return transportSession{transport: b.mailer.transport}, nil
+}
+
+// transportSession delivers every message on its own for transports without sessions.
+type transportSession struct {
+	transport Transport
+}
+
+func (t transportSession) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	return t.transport.Deliver(ctx, from, recipients, message)
+}
+
+func (t transportSession) Close() error {
+	return nil
+}
+
+// tokenBucket allows burst messages at once, then refills one token every interval. A nil tokenBucket does not
+// limit anything.
+type tokenBucket struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int, burst int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	return &tokenBucket{
+		interval: time.Minute / time.Duration(perMinute),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait takes a token, blocking until one is available or the context is done.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	if b == nil {
+		return ctx.Err()
+	}
+
+	b.mutex.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+float64(now.Sub(b.last))/float64(b.interval))
+	b.last = now
+	// Reserve the token right away, a negative balance makes the following callers queue behind this one.
+	b.tokens--
+	wait := time.Duration(-b.tokens * float64(b.interval))
+	b.mutex.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mutex.Lock()
+		b.tokens++
+		b.mutex.Unlock()
+		return ctx.Err()
+	}
+}
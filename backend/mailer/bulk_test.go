@@ -0,0 +1,210 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"conf/mailer"
+)
+
+func bulkMails(count int) []*mailer.Mail {
+	mails := make([]*mailer.Mail, count)
+	for i := range mails {
+		mails[i] = &mailer.Mail{
+			RecipientName:  fmt.Sprintf("Recipient %d", i),
+			RecipientEmail: fmt.Sprintf("recipient%d@example.com", i),
+			Subject:        "Bulk",
+			PlainTextBody:  "Hello",
+		}
+	}
+
+	return mails
+}
+
+func TestBulkSender_ReusesConnections(t *testing.T) {
+	server := startSmtpServer(t, smtpServerOptions{rejectRecipients: []string{"recipient4@example.com"}})
+	hostname, port, _ := net.SplitHostPort(server.address())
+	transport := mailer.NewSmtpTransport(&mailer.MailConfiguration{
+		SmtpHostname: hostname,
+		SmtpPort:     port,
+		TLSMode:      mailer.TLSModeNone,
+	})
+
+	bulkSender, err := mailer.NewBulkSender(mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.BulkOptions{
+		Concurrency:           1,
+		MessagesPerConnection: 3,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	report := bulkSender.SendAll(context.Background(), bulkMails(10), nil)
+
+	if len(report.Results) != 10 {
+		t.Fatalf("expecting 10 results, got %d", len(report.Results))
+	}
+
+	for i, result := range report.Results {
+		if result.RecipientEmail != fmt.Sprintf("recipient%d@example.com", i) {
+			t.Errorf("expecting result %d to be for recipient%d@example.com, got %s", i, i, result.RecipientEmail)
+		}
+	}
+
+	if report.Sent() != 9 {
+		t.Errorf("expecting 9 sent messages, got %d", report.Sent())
+	}
+
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].RecipientEmail != "recipient4@example.com" || !strings.Contains(failed[0].Err.Error(), "550") {
+		t.Errorf("expecting recipient4@example.com to fail with 550, got %+v", failed)
+	}
+
+	if len(server.messages()) != 9 {
+		t.Errorf("expecting the server to receive 9 messages, got %d", len(server.messages()))
+	}
+
+	// recipient0-2 on the first connection, 3 and the rejected 4 on the second, 5-7 on the third, 8-9 on the
+	// fourth.
+	if server.connectionCount() != 4 {
+		t.Errorf("expecting 4 connections, got %d", server.connectionCount())
+	}
+}
+
+func TestBulkSender_Concurrency(t *testing.T) {
+	transport := &countingTransport{delay: 20 * time.Millisecond}
+	bulkSender, err := mailer.NewBulkSender(mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.BulkOptions{
+		Concurrency: 3,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	report := bulkSender.SendAll(context.Background(), bulkMails(12), nil)
+	if report.Sent() != 12 {
+		t.Errorf("expecting 12 sent messages, got %d: %+v", report.Sent(), report.Failed())
+	}
+
+	if transport.maxConcurrent != 3 {
+		t.Errorf("expecting 3 concurrent deliveries, got %d", transport.maxConcurrent)
+	}
+
+	if transport.sessions != 3 {
+		t.Errorf("expecting 3 sessions, got %d", transport.sessions)
+	}
+}
+
+func TestBulkSender_RateLimit(t *testing.T) {
+	transport := mailer.NewMemoryTransport()
+	bulkSender, err := mailer.NewBulkSender(mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.BulkOptions{
+		Concurrency:   4,
+		RatePerMinute: 600,
+		Burst:         2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	start := time.Now()
+	report := bulkSender.SendAll(context.Background(), bulkMails(6), nil)
+	elapsed := time.Since(start)
+
+	if report.Sent() != 6 {
+		t.Errorf("expecting 6 sent messages, got %d", report.Sent())
+	}
+
+	// Two messages go out right away, the other four wait 100ms each.
+	if elapsed < 350*time.Millisecond {
+		t.Errorf("expecting the rate limit to take at least 400ms, took %s", elapsed)
+	}
+}
+
+func TestBulkSender_ContextCancelled(t *testing.T) {
+	transport := mailer.NewMemoryTransport()
+	bulkSender, err := mailer.NewBulkSender(mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.BulkOptions{
+		RatePerMinute: 60,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	report := bulkSender.SendAll(ctx, bulkMails(5), nil)
+
+	// The burst lets the first message through, the rest waits a second each.
+	if report.Sent() != 1 {
+		t.Errorf("expecting 1 sent message, got %d", report.Sent())
+	}
+
+	for _, result := range report.Failed() {
+		if !errors.Is(result.Err, context.DeadlineExceeded) {
+			t.Errorf("expecting %s to fail with deadline exceeded, got %v", result.RecipientEmail, result.Err)
+		}
+	}
+
+	if len(transport.Messages()) != 1 {
+		t.Errorf("expecting 1 delivered message, got %d", len(transport.Messages()))
+	}
+}
+
+func TestNewBulkSender(t *testing.T) {
+	if _, err := mailer.NewBulkSender(nil, mailer.BulkOptions{}); err == nil {
+		t.Error("expecting an error for nil mailer")
+	}
+
+	mailSender := mailer.NewMailSenderWithTransport(mailer.NewMemoryTransport(), mailer.DefaultFrom)
+	if _, err := mailer.NewBulkSender(mailSender, mailer.BulkOptions{RatePerMinute: -1}); err == nil {
+		t.Error("expecting an error for negative rate")
+	}
+}
+
+// countingTransport is a SessionTransport that records how many sessions and deliveries run at once.
+type countingTransport struct {
+	delay time.Duration
+
+	mutex         sync.Mutex
+	sessions      int
+	concurrent    int
+	maxConcurrent int
+}
+
+func (c *countingTransport) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	c.mutex.Lock()
+	c.concurrent++
+	c.maxConcurrent = max(c.maxConcurrent, c.concurrent)
+	c.mutex.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mutex.Lock()
+	c.concurrent--
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *countingTransport) OpenSession(ctx context.Context) (mailer.Session, error) {
+	c.mutex.Lock()
+	c.sessions++
+	c.mutex.Unlock()
+
+	return countingSession{transport: c}, nil
+}
+
+type countingSession struct {
+	transport *countingTransport
+}
+
+func (c countingSession) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	return c.transport.Deliver(ctx, from, recipients, message)
+}
+
+func (c countingSession) Close() error {
+	return nil
+}
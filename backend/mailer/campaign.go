@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrCampaignExists is returned by SendCampaign when the campaign already has recipients on the ledger and it's
+// not being resumed.
+var ErrCampaignExists = errors.New("campaign already exists on the ledger")
+
+// CampaignReport is the BulkReport of the recipients that were sent on this run.
+type CampaignReport struct {
+	BulkReport
+	// Skipped counts the recipients that were sent on a previous run.
+	Skipped int
+	// Duplicates counts the mails dropped because their recipient is listed more than once.
+	Duplicates int
+}
+
+// SendCampaign sends the mails as a named campaign, recording the status of every recipient on the ledger as
+// it goes. Resuming a campaign skips the recipients that were already sent, and retries the failed and pending
+// ones. Starting a campaign that already exists without resume returns ErrCampaignExists, so a blast is never
+// sent twice by accident.
+//
+// A recipient is recorded as pending before its message is sent, so if the process dies after the server
+// accepted the message but before it's recorded as sent, it will be sent again on resume.
+func (b *BulkSender) SendCampaign(ctx context.Context, ledger CampaignLedger, campaign string, resume bool, mails []*Mail) (CampaignReport, error) {
+	if campaign == "" {
+		return CampaignReport{}, fmt.Errorf("campaign is empty")
+	}
+
+	entries, err := ledger.ListEntries(ctx, campaign)
+	if err != nil {
+		return CampaignReport{}, fmt.Errorf("listing ledger entries: %w", err)
+	}
+
+	if len(entries) > 0 && !resume {
+		return CampaignReport{}, fmt.Errorf("%w: %q has %d recipients", ErrCampaignExists, campaign, len(entries))
+	}
+
+	previous := make(map[string]LedgerEntry, len(entries))
+	for _, entry := range entries {
+		previous[entry.RecipientEmail] = entry
+	}
+
+	var report CampaignReport
+	var pendingMails []*Mail
+	var pendingEntries []LedgerEntry
+	seen := make(map[string]struct{}, len(mails))
+	now := time.Now()
+	for _, mail := range mails {
+		key := ledgerKey(mail.RecipientEmail)
+		if _, ok := seen[key]; ok {
+			report.Duplicates++
+			continue
+		}
+		seen[key] = struct{}{}
+
+		entry, ok := previous[key]
+		if ok && entry.Status == LedgerStatusSent {
+			report.Skipped++
+			continue
+		}
+
+		pendingMails = append(pendingMails, mail)
+		pendingEntries = append(pendingEntries, LedgerEntry{
+			Campaign:       campaign,
+			RecipientEmail: key,
+			RecipientName:  mail.RecipientName,
+			Status:         LedgerStatusPending,
+			Attempts:       entry.Attempts,
+			LastError:      entry.LastError,
+			UpdatedAt:      now,
+		})
+	}
+
+	if len(pendingEntries) > 0 {
+		if err := ledger.RecordEntries(ctx, pendingEntries); err != nil {
+			return CampaignReport{}, fmt.Errorf("recording pending recipients: %w", err)
+		}
+	}
+
+	var recordErrors []error
+	report.BulkReport = b.SendAll(ctx, pendingMails, func(index int, result BulkResult) {
+		entry := pendingEntries[index]
+		entry.UpdatedAt = time.Now()
+		if errors.Is(result.Err, context.Canceled) || errors.Is(result.Err, context.DeadlineExceeded) {
+			// The blast was interrupted before the message went out, it stays pending for the next run.
+			return
+		}
+
+		entry.Attempts++
+		if result.Err != nil {
+			entry.Status = LedgerStatusFailed
+			entry.LastError = result.Err.Error()
+		} else {
+			entry.Status = LedgerStatusSent
+			entry.LastError = ""
+		}
+
+		// The context might be cancelled in the middle of the blast, the results that came in must still be
+		// recorded.
+		if err := ledger.RecordEntries(context.WithoutCancel(ctx), []LedgerEntry{entry}); err != nil {
+			recordErrors = append(recordErrors, fmt.Errorf("recording %s: %w", entry.RecipientEmail, err))
+		}
+	})
+
+	return report, errors.Join(recordErrors...)
+}
+
+func ledgerKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,263 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"conf/mailer"
+)
+
+// selectiveTransport fails the deliveries to the recipients on reject, and keeps the rest in memory.
+type selectiveTransport struct {
+	mutex     sync.Mutex
+	reject    []string
+	delivered []string
+}
+
+func (s *selectiveTransport) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if slices.Contains(s.reject, recipients[0]) {
+		return errors.New("550 mailbox unavailable")
+	}
+
+	s.delivered = append(s.delivered, recipients[0])
+	return nil
+}
+
+func newCampaignLedger(t *testing.T) *mailer.FileCampaignLedger {
+	t.Helper()
+
+	ledger, err := mailer.NewFileCampaignLedger(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating ledger: %s", err.Error())
+	}
+
+	return ledger
+}
+
+func ledgerStatuses(t *testing.T, ledger mailer.CampaignLedger, campaign string) map[string]mailer.LedgerEntry {
+	t.Helper()
+
+	entries, err := ledger.ListEntries(context.Background(), campaign)
+	if err != nil {
+		t.Fatalf("listing entries: %s", err.Error())
+	}
+
+	statuses := make(map[string]mailer.LedgerEntry, len(entries))
+	for _, entry := range entries {
+		statuses[entry.RecipientEmail] = entry
+	}
+
+	return statuses
+}
+
+func TestSendCampaign_Resume(t *testing.T) {
+	ledger := newCampaignLedger(t)
+	transport := &selectiveTransport{reject: []string{"recipient2@example.com"}}
+	bulkSender, err := mailer.NewBulkSender(mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.BulkOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	mails := append(bulkMails(4), &mailer.Mail{RecipientEmail: "RECIPIENT0@example.com", Subject: "Bulk"})
+	report, err := bulkSender.SendCampaign(context.Background(), ledger, "october-update", false, mails)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if report.Sent() != 3 || len(report.Failed()) != 1 || report.Duplicates != 1 {
+		t.Errorf("expecting 3 sent, 1 failed, and 1 duplicate, got %d sent, %d failed, %d duplicates", report.Sent(), len(report.Failed()), report.Duplicates)
+	}
+
+	statuses := ledgerStatuses(t, ledger, "october-update")
+	if len(statuses) != 4 {
+		t.Fatalf("expecting 4 entries, got %d", len(statuses))
+	}
+
+	if statuses["recipient2@example.com"].Status != mailer.LedgerStatusFailed || statuses["recipient2@example.com"].LastError == "" {
+		t.Errorf("expecting recipient2 to be failed with an error, got %+v", statuses["recipient2@example.com"])
+	}
+
+	if statuses["recipient1@example.com"].Status != mailer.LedgerStatusSent || statuses["recipient1@example.com"].Attempts != 1 {
+		t.Errorf("expecting recipient1 to be sent on the first attempt, got %+v", statuses["recipient1@example.com"])
+	}
+
+	// Running it again without resume must not send anything.
+	_, err = bulkSender.SendCampaign(context.Background(), ledger, "october-update", false, mails)
+	if !errors.Is(err, mailer.ErrCampaignExists) {
+		t.Fatalf("expecting ErrCampaignExists, got %v", err)
+	}
+
+	transport.reject = nil
+	transport.delivered = nil
+	report, err = bulkSender.SendCampaign(context.Background(), ledger, "october-update", true, mails)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if report.Skipped != 3 || report.Sent() != 1 {
+		t.Errorf("expecting 3 skipped and 1 sent, got %d skipped and %d sent", report.Skipped, report.Sent())
+	}
+
+	if !slices.Equal(transport.delivered, []string{"recipient2@example.com"}) {
+		t.Errorf("expecting only recipient2 to be sent on resume, got %v", transport.delivered)
+	}
+
+	statuses = ledgerStatuses(t, ledger, "october-update")
+	if entry := statuses["recipient2@example.com"]; entry.Status != mailer.LedgerStatusSent || entry.Attempts != 2 || entry.LastError != "" {
+		t.Errorf("expecting recipient2 to be sent on the second attempt, got %+v", entry)
+	}
+
+	entries, err := ledger.ListEntries(context.Background(), "october-update")
+	if err != nil {
+		t.Fatalf("listing entries: %s", err.Error())
+	}
+
+	if summary := mailer.SummarizeLedger(entries); summary != (mailer.LedgerSummary{Sent: 4}) {
+		t.Errorf("expecting every recipient to be sent, got %+v", summary)
+	}
+}
+
+func TestSendCampaign_Interrupted(t *testing.T) {
+	ledger := newCampaignLedger(t)
+	transport := mailer.NewMemoryTransport()
+	bulkSender, err := mailer.NewBulkSender(mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.BulkOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := bulkSender.SendCampaign(ctx, ledger, "interrupted", false, bulkMails(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if report.Sent() != 0 || len(transport.Messages()) != 0 {
+		t.Errorf("expecting nothing to be sent, got %d", report.Sent())
+	}
+
+	entries, err := ledger.ListEntries(context.Background(), "interrupted")
+	if err != nil {
+		t.Fatalf("listing entries: %s", err.Error())
+	}
+
+	summary := mailer.SummarizeLedger(entries)
+	if summary.Pending != 3 || summary.Sent != 0 || summary.Failed != 0 {
+		t.Errorf("expecting 3 pending recipients, got %+v", summary)
+	}
+}
+
+func TestFileCampaignLedger(t *testing.T) {
+	path := t.TempDir()
+	ledger, err := mailer.NewFileCampaignLedger(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	err = ledger.RecordEntries(context.Background(), []mailer.LedgerEntry{
+		{Campaign: "first", RecipientEmail: "b@example.com", Status: mailer.LedgerStatusPending},
+		{Campaign: "first", RecipientEmail: "a@example.com", Status: mailer.LedgerStatusPending},
+		{Campaign: "second", RecipientEmail: "a@example.com", Status: mailer.LedgerStatusSent},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	err = ledger.RecordEntries(context.Background(), []mailer.LedgerEntry{
+		{Campaign: "first", RecipientEmail: "b@example.com", Status: mailer.LedgerStatusSent, Attempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	// Simulate a crash in the middle of a write.
+	file, err := os.OpenFile(filepath.Join(path, "first.jsonl"), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("opening ledger file: %s", err.Error())
+	}
+	_, _ = file.WriteString(`{"Campaign":"first","RecipientEmail":"a@exa`)
+	_ = file.Close()
+
+	entries, err := ledger.ListEntries(context.Background(), "first")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expecting 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].RecipientEmail != "a@example.com" || entries[0].Status != mailer.LedgerStatusPending {
+		t.Errorf("expecting a@example.com to be pending, got %+v", entries[0])
+	}
+
+	if entries[1].RecipientEmail != "b@example.com" || entries[1].Status != mailer.LedgerStatusSent || entries[1].Attempts != 1 {
+		t.Errorf("expecting the latest entry of b@example.com, got %+v", entries[1])
+	}
+
+	// The partial line must not swallow the next record.
+	err = ledger.RecordEntries(context.Background(), []mailer.LedgerEntry{
+		{Campaign: "first", RecipientEmail: "a@example.com", Status: mailer.LedgerStatusFailed, Attempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	entries, err = ledger.ListEntries(context.Background(), "first")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(entries) != 2 || entries[0].Status != mailer.LedgerStatusFailed {
+		t.Errorf("expecting a@example.com to be failed after the crash, got %+v", entries)
+	}
+
+	entries, err = ledger.ListEntries(context.Background(), "missing")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expecting no entries for a missing campaign, got %v and %v", entries, err)
+	}
+
+	for _, campaign := range []string{"", "../escape", "with space", ".hidden"} {
+		if _, err := ledger.ListEntries(context.Background(), campaign); !errors.Is(err, mailer.ErrInvalidCampaign) {
+			t.Errorf("expecting ErrInvalidCampaign for %q, got %v", campaign, err)
+		}
+	}
+}
+
+func TestFileCampaignLedger_CreatesDirectoryOnRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blast-ledger")
+	ledger, err := mailer.NewFileCampaignLedger(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	entries, err := ledger.ListEntries(context.Background(), "first")
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expecting no entries, got %d entries and %v", len(entries), err)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expecting the ledger directory not to be created before a record, got %v", err)
+	}
+
+	err = ledger.RecordEntries(context.Background(), []mailer.LedgerEntry{
+		{Campaign: "first", RecipientEmail: "a@example.com", Status: mailer.LedgerStatusPending},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	entries, err = ledger.ListEntries(context.Background(), "first")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expecting 1 entry, got %d entries and %v", len(entries), err)
+	}
+}
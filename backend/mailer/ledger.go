@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"context"
+	"time"
+)
+
+// LedgerStatus is the delivery status of a recipient of a blast campaign.
+type LedgerStatus string
+
+const (
+	// LedgerStatusPending is recorded before the message is sent. A recipient stays pending if the blast
+	// crashed before the delivery finished.
+	LedgerStatusPending LedgerStatus = "pending"
+	LedgerStatusSent    LedgerStatus = "sent"
+	LedgerStatusFailed  LedgerStatus = "failed"
+)
+
+// LedgerEntry is the latest delivery status of a recipient, keyed by Campaign and RecipientEmail.
+type LedgerEntry struct {
+	Campaign       string
+	RecipientEmail string
+	RecipientName  string
+	Status         LedgerStatus
+	Attempts       int
+	LastError      string
+	UpdatedAt      time.Time
+}
+
+// CampaignLedger persists the delivery status of every recipient of a blast campaign, so an interrupted blast
+// can be resumed without mailing the same recipient twice.
+type CampaignLedger interface {
+	// RecordEntries inserts the entries, replacing the existing entry of the same campaign and recipient.
+	RecordEntries(ctx context.Context, entries []LedgerEntry) error
+	// ListEntries returns the entries of the campaign ordered by recipient email. It returns an empty list if
+	// the campaign does not exist.
+	ListEntries(ctx context.Context, campaign string) ([]LedgerEntry, error)
+}
+
+// LedgerSummary counts the recipients of a campaign by their status.
+type LedgerSummary struct {
+	Sent    int
+	Failed  int
+	Pending int
+}
+
+func SummarizeLedger(entries []LedgerEntry) LedgerSummary {
+	var summary LedgerSummary
+	for _, entry := range entries {
+		switch entry.Status {
+		case LedgerStatusSent:
+			summary.Sent++
+		case LedgerStatusFailed:
+			summary.Failed++
+		default:
+			summary.Pending++
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,164 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// ErrInvalidCampaign is returned when the campaign name can't be used as a file name.
+var ErrInvalidCampaign = errors.New("campaign may only contain letters, digits, dots, dashes, and underscores")
+
+var campaignNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// FileCampaignLedger implements CampaignLedger as a directory of JSON lines files, one per campaign. Every
+// record is appended and synced to the file, the latest line of a recipient wins when the file is read. The
+// directory is created on the first record, not by NewFileCampaignLedger, so reading a ledger leaves the path
+// alone.
+type FileCampaignLedger struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFileCampaignLedger(path string) (*FileCampaignLedger, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	return &FileCampaignLedger{path: path}, nil
+}
+
+func (f *FileCampaignLedger) RecordEntries(ctx context.Context, entries []LedgerEntry) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	// Group the lines per campaign, so each file is opened once.
+	lines := make(map[string]*bytes.Buffer)
+	for _, entry := range entries {
+		if !campaignNamePattern.MatchString(entry.Campaign) {
+			return ErrInvalidCampaign
+		}
+
+		content, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("marshaling ledger entry: %w", err)
+		}
+
+		if lines[entry.Campaign] == nil {
+			lines[entry.Campaign] = &bytes.Buffer{}
+		}
+		lines[entry.Campaign].Write(content)
+		lines[entry.Campaign].WriteByte('\n')
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	err := os.MkdirAll(f.path, 0o700)
+	if err != nil {
+		return fmt.Errorf("creating ledger directory: %w", err)
+	}
+
+	for campaign, content := range lines {
+		file, err := os.OpenFile(f.filename(campaign), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("opening ledger file: %w", err)
+		}
+
+		err = terminateLastLine(file)
+		if err == nil {
+			_, err = file.Write(content.Bytes())
+		}
+		if err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("writing ledger file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (f *FileCampaignLedger) ListEntries(ctx context.Context, campaign string) ([]LedgerEntry, error) {
+	if !campaignNamePattern.MatchString(campaign) {
+		return nil, ErrInvalidCampaign
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	file, err := os.Open(f.filename(campaign))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []LedgerEntry{}, nil
+		}
+
+		return nil, fmt.Errorf("opening ledger file: %w", err)
+	}
+	defer file.Close()
+
+	latest := make(map[string]LedgerEntry)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry LedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A crash in the middle of a write leaves a partial last line behind, the recipient is still
+			// pending on the line before it.
+			continue
+		}
+
+		latest[entry.RecipientEmail] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading ledger file: %w", err)
+	}
+
+	entries := make([]LedgerEntry, 0, len(latest))
+	for _, entry := range latest {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].RecipientEmail < entries[j].RecipientEmail
+	})
+
+	return entries, nil
+}
+
+// terminateLastLine ends the partial line left by a crash, so it does not swallow the next record.
+func terminateLastLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+
+	if last[0] == '\n' {
+		return nil
+	}
+
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
+
+func (f *FileCampaignLedger) filename(campaign string) string {
+	return filepath.Join(f.path, campaign+".jsonl")
+}
@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PostgresCampaignLedger implements CampaignLedger on top of the `blast_ledger` table.
+type PostgresCampaignLedger struct {
+	db *sql.DB
+}
+
+func NewPostgresCampaignLedger(db *sql.DB) (*PostgresCampaignLedger, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &PostgresCampaignLedger{db: db}, nil
+}
+
+func (p *PostgresCampaignLedger) RecordEntries(ctx context.Context, entries []LedgerEntry) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, entry := range entries {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO blast_ledger (campaign, recipient_email, recipient_name, status, attempts, last_error, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (campaign, recipient_email) DO UPDATE SET
+				recipient_name = EXCLUDED.recipient_name,
+				status = EXCLUDED.status,
+				attempts = EXCLUDED.attempts,
+				last_error = EXCLUDED.last_error,
+				updated_at = EXCLUDED.updated_at`,
+			entry.Campaign,
+			entry.RecipientEmail,
+			entry.RecipientName,
+			string(entry.Status),
+			entry.Attempts,
+			entry.LastError,
+			entry.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("recording blast ledger: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing blast ledger: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresCampaignLedger) ListEntries(ctx context.Context, campaign string) ([]LedgerEntry, error) {
+	rows, err := p.db.QueryContext(
+		ctx,
+		`SELECT campaign, recipient_email, recipient_name, status, attempts, last_error, updated_at
+		FROM blast_ledger WHERE campaign = $1 ORDER BY recipient_email`,
+		campaign,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing blast ledger: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	entries := []LedgerEntry{}
+	for rows.Next() {
+		var entry LedgerEntry
+		var status string
+		err := rows.Scan(
+			&entry.Campaign,
+			&entry.RecipientEmail,
+			&entry.RecipientName,
+			&status,
+			&entry.Attempts,
+			&entry.LastError,
+			&entry.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning blast ledger: %w", err)
+		}
+
+		entry.Status = LedgerStatus(status)
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating blast ledger: %w", err)
+	}
+
+	return entries, nil
+}
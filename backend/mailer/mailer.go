@@ -1,26 +1,40 @@
 package mailer
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"net"
-	"net/smtp"
-	"strconv"
-	"strings"
+	"crypto/x509"
+	netmail "net/mail"
 	"time"
 
 	"github.com/getsentry/sentry-go"
-	"github.com/google/uuid"
 )
 
 type MailConfiguration struct {
 	SmtpHostname string
 	SmtpPort     string
+	// SmtpFrom is the username to authenticate with, authentication is skipped if it's empty.
 	SmtpFrom     string
 	SmtpPassword string
+	// TLSMode decides how the connection is secured. The zero value upgrades the connection with STARTTLS
+	// only if the server supports it.
+	TLSMode TLSMode
+	// RootCAs verifies the server certificate, the system pool is used if it's nil.
+	RootCAs *x509.CertPool
+	// ServerName is the name on the server certificate. Defaults to SmtpHostname.
+	ServerName string
+	// AuthMechanism defaults to AuthPlain.
+	AuthMechanism AuthMechanism
+	// OAuth2Token returns the access token for AuthXOAuth2. It's called on every delivery, so it can hand
+	// out a refreshed token.
+	OAuth2Token func(ctx context.Context) (string, error)
+	// From is the sender shown on the messages, it's also used as the SMTP envelope sender. Defaults to
+	// DefaultFrom if the address is empty.
+	From netmail.Address
 }
 
+// DefaultFrom is the sender of the messages if none is configured.
+var DefaultFrom = netmail.Address{Name: "Teknologi Umum Conference", Address: "conference@teknologiumum.com"}
+
 type Mail struct {
 	RecipientName  string
 	RecipientEmail string
@@ -28,6 +42,10 @@ type Mail struct {
 	PlainTextBody  string
 	HtmlBody       string
 	Attachments    []Attachment
+	// MessageId and Date are generated when the mail is rendered if they're empty. Setting them makes the
+	// rendered message reproducible.
+	MessageId string
+	Date      time.Time
 }
 
 type ContentDisposition uint8
@@ -49,101 +67,40 @@ type Attachment struct {
 	Payload []byte
 }
 
+// Sender sends a mail. Mailer sends it right away, while Outbox queues it to be sent in the background.
+type Sender interface {
+	Send(ctx context.Context, mail *Mail) error
+}
+
 type Mailer struct {
-	configuration *MailConfiguration
+	transport Transport
+	from      netmail.Address
 }
 
+// NewMailSender creates a Mailer that delivers through the SMTP server on the configuration.
 func NewMailSender(configuration *MailConfiguration) *Mailer {
-	mailer := &Mailer{
-		configuration: configuration,
-	}
-
-	return mailer
+	return NewMailSenderWithTransport(NewSmtpTransport(configuration), configuration.From)
 }
 
-func (m *Mailer) messageBuilder(ctx context.Context, mail *Mail) []byte {
-	span := sentry.StartSpan(ctx, "mailer.message_builder")
-	defer span.Finish()
+// NewMailSenderWithTransport creates a Mailer that delivers through the transport. The from address
+// defaults to DefaultFrom if it's empty.
+func NewMailSenderWithTransport(transport Transport, from netmail.Address) *Mailer {
+	if from.Address == "" {
+		from = DefaultFrom
+	}
 
-	// Refer to https://www.rfc-editor.org/rfc/rfc5322
-	mixedBoundary := strings.ReplaceAll(uuid.NewString(), "-", "")
-	relatedBoundary := strings.ReplaceAll(uuid.NewString(), "-", "")
-	alternateBoundary := strings.ReplaceAll(uuid.NewString(), "-", "")
-
-	var msg bytes.Buffer
-	msg.WriteString("MIME-version: 1.0\n")
-	msg.WriteString("Date: ")
-	msg.WriteString(time.Now().Format(time.RFC1123Z))
-	msg.WriteString("\n")
-	msg.WriteString("From: \"Teknologi Umum Conference\" <conference@teknologiumum.com>\n")
-	msg.WriteString("To: " + strconv.Quote(mail.RecipientName) + " <" + mail.RecipientEmail + ">\n")
-	msg.WriteString("Subject: " + mail.Subject + "\n")
-	msg.WriteString("Content-Type: multipart/mixed; boundary= " + strconv.Quote(mixedBoundary) + "\n\n")
-	msg.WriteString("--" + mixedBoundary + "\n")
-	msg.WriteString("Content-Type: multipart/related; boundary=" + strconv.Quote(relatedBoundary) + "\n\n")
-	msg.WriteString("--" + relatedBoundary + "\n")
-	msg.WriteString("Content-Type: multipart/alternative; boundary=" + strconv.Quote(alternateBoundary) + "\n\n")
-	msg.WriteString("--" + alternateBoundary + "\n")
-	msg.WriteString("Content-Type: text/plain; charset=\"us-ascii\"\n")
-	msg.WriteString("Content-Transfer-Encoding: 8bit\n")
-	msg.WriteString("\n")
-	msg.WriteString(mail.PlainTextBody)
-	msg.WriteString("\n\n")
-	msg.WriteString("--" + alternateBoundary + "\n")
-	msg.WriteString("Content-Type: text/html; charset=\"utf-8\"\n")
-	msg.WriteString("Content-Transfer-Encoding: 8bit\n")
-	msg.WriteString("\n")
-	msg.WriteString(mail.HtmlBody)
-	msg.WriteString("\n")
-	msg.WriteString("--" + alternateBoundary + "--\n")
-	for _, attachment := range mail.Attachments {
-		if attachment.ContentDisposition == ContentDispositionInline {
-			msg.WriteString("\n--" + relatedBoundary + "\n")
-			msg.WriteString("Content-Type: " + attachment.ContentType + "\n")
-			msg.WriteString("Content-Disposition: inline; filename=" + strconv.Quote(attachment.Name) + ";\n")
-			msg.WriteString("Content-Description: " + attachment.Description + "\n")
-			msg.WriteString("Content-ID: <" + attachment.ContentId + ">\n")
-			msg.WriteString("Content-Transfer-Encoding: base64\n")
-			msg.WriteString("\n")
-			msg.WriteString(base64.StdEncoding.EncodeToString(attachment.Payload))
-			msg.WriteString("\n")
-			msg.WriteString("\n--" + relatedBoundary + "--\n")
-		}
-
-		if attachment.ContentDisposition == ContentDispositionAttachment {
-			msg.WriteString("\n--" + mixedBoundary + "\n")
-			msg.WriteString("Content-Type: " + attachment.ContentType + "\n")
-			msg.WriteString("Content-Disposition: attachment; filename=" + strconv.Quote(attachment.Name) + ";\n")
-			msg.WriteString("Content-Description: " + attachment.Description + "\n")
-			msg.WriteString("Content-Transfer-Encoding: base64\n")
-			msg.WriteString("\n")
-			msg.WriteString(base64.StdEncoding.EncodeToString(attachment.Payload))
-			msg.WriteString("\n")
-			msg.WriteString("\n--" + mixedBoundary + "--\n")
-		}
+	mailer := &Mailer{
+		transport: transport,
+		from:      from,
 	}
-	return msg.Bytes()
+
+	return mailer
 }
 
+// Send renders the mail and delivers it synchronously.
 func (m *Mailer) Send(ctx context.Context, mail *Mail) error {
 	span := sentry.StartSpan(ctx, "mailer.send")
 	defer span.Finish()
 
-	if m.configuration.SmtpFrom == "" || m.configuration.SmtpPassword == "" {
-		return smtp.SendMail(
-			net.JoinHostPort(m.configuration.SmtpHostname, m.configuration.SmtpPort),
-			nil, // no auth
-			"conference@teknologiumum.com",
-			[]string{mail.RecipientEmail},
-			m.messageBuilder(ctx, mail),
-		)
-	}
-
-	return smtp.SendMail(
-		net.JoinHostPort(m.configuration.SmtpHostname, m.configuration.SmtpPort),
-		smtp.PlainAuth("", m.configuration.SmtpFrom, m.configuration.SmtpPassword, m.configuration.SmtpHostname),
-		"conference@teknologiumum.com",
-		[]string{mail.RecipientEmail},
-		m.messageBuilder(ctx, mail),
-	)
+	return m.transport.Deliver(ctx, m.from.Address, []string{mail.RecipientEmail}, m.Render(ctx, mail))
 }
@@ -0,0 +1,260 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+)
+
+// maxLineLength is the line length that header folding and base64 wrapping aim for, as recommended by
+// RFC 5322 section 2.1.1.
+const maxLineLength = 76
+
+// messagePart is a MIME entity. A part with children is rendered as a multipart entity of the subtype,
+// otherwise the body is rendered as is.
+type messagePart struct {
+	header   [][2]string
+	body     []byte
+	subtype  string
+	children []messagePart
+}
+
+// Render builds the RFC 5322 message of the mail with CRLF line endings. Non-ASCII header text is encoded
+// with RFC 2047 encoded words, text bodies are quoted-printable, and attachments are base64 wrapped at 76
+// characters. The body is nested as multipart/mixed (attachments) containing multipart/related (inline
+// attachments) containing multipart/alternative (plain text and HTML), leaving out the levels that are not
+// needed.
+func (m *Mailer) Render(ctx context.Context, mail *Mail) []byte {
+	span := sentry.StartSpan(ctx, "mailer.render")
+	defer span.Finish()
+
+	messageId := mail.MessageId
+	if messageId == "" {
+		_, domain, _ := strings.Cut(m.from.Address, "@")
+		messageId = uuid.NewString() + "@" + domain
+	}
+	messageId = "<" + strings.Trim(messageId, "<>") + ">"
+
+	date := mail.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	recipient := netmail.Address{Name: mail.RecipientName, Address: mail.RecipientEmail}
+
+	var msg bytes.Buffer
+	writeHeader(&msg, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&msg, "Message-ID", messageId)
+	writeHeader(&msg, "MIME-Version", "1.0")
+	writeHeader(&msg, "From", formatAddress(m.from))
+	writeHeader(&msg, "To", formatAddress(recipient))
+	writeHeader(&msg, "Subject", encodeHeaderText(mail.Subject))
+	writePart(&msg, buildBody(mail), messageId, "0")
+
+	return msg.Bytes()
+}
+
+func buildBody(mail *Mail) messagePart {
+	var textParts []messagePart
+	if mail.PlainTextBody != "" || mail.HtmlBody == "" {
+		textParts = append(textParts, textPart("text/plain", mail.PlainTextBody))
+	}
+
+	if mail.HtmlBody != "" {
+		textParts = append(textParts, textPart("text/html", mail.HtmlBody))
+	}
+
+	body := textParts[0]
+	if len(textParts) > 1 {
+		body = messagePart{subtype: "alternative", children: textParts}
+	}
+
+	var inlineParts, attachmentParts []messagePart
+	for _, attachment := range mail.Attachments {
+		if attachment.ContentDisposition == ContentDispositionInline {
+			inlineParts = append(inlineParts, attachmentPart(attachment))
+		} else {
+			attachmentParts = append(attachmentParts, attachmentPart(attachment))
+		}
+	}
+
+	if len(inlineParts) > 0 {
+		body = messagePart{subtype: "related", children: append([]messagePart{body}, inlineParts...)}
+	}
+
+	if len(attachmentParts) > 0 {
+		body = messagePart{subtype: "mixed", children: append([]messagePart{body}, attachmentParts...)}
+	}
+
+	return body
+}
+
+func textPart(contentType string, text string) messagePart {
+	var body bytes.Buffer
+	writer := quotedprintable.NewWriter(&body)
+	// The writer turns every LF into CRLF, normalize them first so CRLF input doesn't end up doubled.
+	_, _ = writer.Write([]byte(strings.ReplaceAll(text, "\r\n", "\n")))
+	_ = writer.Close()
+
+	return messagePart{
+		header: [][2]string{
+			{"Content-Type", mime.FormatMediaType(contentType, map[string]string{"charset": "utf-8"})},
+			{"Content-Transfer-Encoding", "quoted-printable"},
+		},
+		body: bytes.TrimSuffix(body.Bytes(), []byte("\r\n")),
+	}
+}
+
+func attachmentPart(attachment Attachment) messagePart {
+	mediaType, params, err := mime.ParseMediaType(attachment.ContentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = attachment.Name
+
+	disposition := "attachment"
+	if attachment.ContentDisposition == ContentDispositionInline {
+		disposition = "inline"
+	}
+
+	header := [][2]string{
+		{"Content-Type", mime.FormatMediaType(mediaType, params)},
+		{"Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Name})},
+	}
+
+	if attachment.Description != "" {
+		header = append(header, [2]string{"Content-Description", encodeHeaderText(attachment.Description)})
+	}
+
+	if attachment.ContentId != "" {
+		header = append(header, [2]string{"Content-ID", "<" + strings.Trim(attachment.ContentId, "<>") + ">"})
+	}
+
+	header = append(header, [2]string{"Content-Transfer-Encoding", "base64"})
+
+	encoded := base64.StdEncoding.EncodeToString(attachment.Payload)
+	var body bytes.Buffer
+	for len(encoded) > maxLineLength {
+		body.WriteString(encoded[:maxLineLength])
+		body.WriteString("\r\n")
+		encoded = encoded[maxLineLength:]
+	}
+	body.WriteString(encoded)
+
+	return messagePart{header: header, body: body.Bytes()}
+}
+
+// writePart writes the headers and the body of the part. The boundaries are derived from the Message-ID and
+// the position of the part, so the same mail always renders the same way. They start with "=_", which
+// never shows up on quoted-printable or base64 content.
+func writePart(msg *bytes.Buffer, part messagePart, messageId string, position string) {
+	if len(part.children) == 0 {
+		for _, field := range part.header {
+			writeHeader(msg, field[0], field[1])
+		}
+		msg.WriteString("\r\n")
+		msg.Write(part.body)
+		return
+	}
+
+	checksum := sha256.Sum256([]byte(messageId + "/" + position))
+	boundary := "=_" + hex.EncodeToString(checksum[:14])
+
+	writeHeader(msg, "Content-Type", mime.FormatMediaType("multipart/"+part.subtype, map[string]string{"boundary": boundary}))
+	msg.WriteString("\r\n")
+	for i, child := range part.children {
+		msg.WriteString("--" + boundary + "\r\n")
+		writePart(msg, child, messageId, position+"."+strconv.Itoa(i))
+		msg.WriteString("\r\n")
+	}
+	msg.WriteString("--" + boundary + "--")
+	if position == "0" {
+		msg.WriteString("\r\n")
+	}
+}
+
+// maxEncodedWordLength keeps every folded line with an encoded word within the 76 characters limit of
+// RFC 2047, including the longest header name we put them on.
+const maxEncodedWordLength = 60
+
+// encodeHeaderText encodes the text with RFC 2047 Q encoded words if it has any non-ASCII character.
+// Unlike mime.QEncoding, the words are kept short enough to be folded within the line length limit.
+func encodeHeaderText(text string) string {
+	if mime.QEncoding.Encode("utf-8", text) == text {
+		return text
+	}
+
+	var words []string
+	var word strings.Builder
+	for _, r := range text {
+		encoded := qEncodeRune(r)
+		if word.Len() > 0 && len("=?utf-8?q?")+word.Len()+len(encoded)+len("?=") > maxEncodedWordLength {
+			words = append(words, "=?utf-8?q?"+word.String()+"?=")
+			word.Reset()
+		}
+
+		word.WriteString(encoded)
+	}
+	words = append(words, "=?utf-8?q?"+word.String()+"?=")
+
+	return strings.Join(words, " ")
+}
+
+// qEncodeRune encodes the rune as RFC 2047 section 4.2 describes, runes are never split across words.
+func qEncodeRune(r rune) string {
+	switch {
+	case r == ' ':
+		return "_"
+	case r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!*+-/", r)):
+		return string(r)
+	}
+
+	var encoded strings.Builder
+	for _, b := range []byte(string(r)) {
+		encoded.WriteString(fmt.Sprintf("=%02X", b))
+	}
+
+	return encoded.String()
+}
+
+func formatAddress(address netmail.Address) string {
+	if address.Name == "" || encodeHeaderText(address.Name) == address.Name {
+		return address.String()
+	}
+
+	return encodeHeaderText(address.Name) + " <" + address.Address + ">"
+}
+
+// writeHeader writes the header field, folding it before a space to keep the lines within maxLineLength
+// whenever possible. Unfolding the lines gives back the exact value.
+func writeHeader(msg *bytes.Buffer, name string, value string) {
+	// Line breaks on the value would start a new header field.
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+
+	line := name + ":"
+	for i, word := range strings.Split(value, " ") {
+		if i > 0 && len(line)+1+len(word) > maxLineLength && strings.TrimSpace(line) != "" {
+			msg.WriteString(line)
+			msg.WriteString("\r\n")
+			line = ""
+		}
+
+		line += " " + word
+	}
+
+	msg.WriteString(line)
+	msg.WriteString("\r\n")
+}
@@ -0,0 +1,294 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"flag"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"conf/mailer"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files on testdata")
+
+// messageEntity is a parsed MIME entity, flattened for comparison.
+type messageEntity struct {
+	mediaType string
+	params    map[string]string
+	header    map[string][]string
+	body      []byte
+	children  []messageEntity
+}
+
+func parseEntity(t *testing.T, header map[string][]string, body io.Reader) messageEntity {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(mail.Header(header).Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("parsing content type %q: %s", mail.Header(header).Get("Content-Type"), err.Error())
+	}
+
+	entity := messageEntity{mediaType: mediaType, params: params, header: header}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		entity.body, err = io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("reading body: %s", err.Error())
+		}
+
+		return entity
+	}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading %s part: %s", mediaType, err.Error())
+		}
+
+		child := parseEntity(t, part.Header, part)
+		entity.children = append(entity.children, child)
+	}
+
+	return entity
+}
+
+func decodeBody(t *testing.T, entity messageEntity) string {
+	t.Helper()
+
+	var reader io.Reader = bytes.NewReader(entity.body)
+	switch mail.Header(entity.header).Get("Content-Transfer-Encoding") {
+	case "quoted-printable":
+		reader = quotedprintable.NewReader(reader)
+	case "base64":
+		reader = base64.NewDecoder(base64.StdEncoding, reader)
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("decoding body: %s", err.Error())
+	}
+
+	return string(content)
+}
+
+func TestMailer_Render(t *testing.T) {
+	date := time.Date(2023, time.October, 13, 9, 30, 0, 0, time.FixedZone("WIB", 7*60*60))
+	png := bytes.Repeat([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}, 40)
+
+	tests := []struct {
+		name      string
+		from      mail.Address
+		mail      mailer.Mail
+		structure string
+	}{
+		{
+			name: "plain_text",
+			from: mail.Address{Name: "Panitia TeknumConf", Address: "panitia@teknologiumum.com"},
+			mail: mailer.Mail{
+				RecipientEmail: "johndoe@example.com",
+				Subject:        "Plain text only",
+				PlainTextBody:  "Hello!\nThis line is long enough that quoted-printable has to wrap it with a soft line break, because it goes past seventy six characters.\nBye.",
+			},
+			structure: "text/plain",
+		},
+		{
+			name: "alternative",
+			from: mailer.DefaultFrom,
+			mail: mailer.Mail{
+				RecipientName:  "Budi Doremi",
+				RecipientEmail: "budi@example.com",
+				Subject:        "TeknumConf 2023: Tiket Anda! 💃",
+				PlainTextBody:  "Hai! Ini dia email yang kamu tunggu-tunggu💃\r\n\r\nSampai jumpa!",
+				HtmlBody:       "<h1>Hai! Ini dia email yang kamu tunggu-tunggu💃</h1>\n<p style=\"color: red\">Sampai jumpa!</p>",
+			},
+			structure: "multipart/alternative(text/plain,text/html)",
+		},
+		{
+			name: "mixed_related",
+			from: mail.Address{Name: "Teknologi Umum — Conference", Address: "conference@teknologiumum.com"},
+			mail: mailer.Mail{
+				RecipientName:  "Çelik Ñandú",
+				RecipientEmail: "celik@example.com",
+				Subject:        "Tiket kamu sudah terbit, jangan lupa bawa kartu pelajar atau kartu mahasiswa ya! 🎟️🎉",
+				PlainTextBody:  "Tiket terlampir.",
+				HtmlBody:       "<p>Tiket terlampir.</p><img src=\"cid:qr-1\"><img src=\"cid:qr-2\">",
+				Attachments: []mailer.Attachment{
+					{Name: "qr-1.png", Description: "QR code tiket", ContentType: "image/png", ContentDisposition: mailer.ContentDispositionInline, ContentId: "qr-1", Payload: png},
+					{Name: "ticket.pdf", Description: "Tiket TeknumConf 2023", ContentType: "application/pdf", ContentDisposition: mailer.ContentDispositionAttachment, Payload: []byte("%PDF-1.4 not really")},
+					{Name: "qr-2.png", ContentType: "image/png", ContentDisposition: mailer.ContentDispositionInline, ContentId: "qr-2", Payload: png},
+					{Name: "tiket ñ.pkpass", ContentType: "application/vnd.apple.pkpass", ContentDisposition: mailer.ContentDispositionAttachment, Payload: []byte("PK")},
+				},
+			},
+			structure: "multipart/mixed(multipart/related(multipart/alternative(text/plain,text/html),image/png,image/png),application/pdf,application/vnd.apple.pkpass)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mail.MessageId = "golden-" + test.name + "@teknologiumum.com"
+			test.mail.Date = date
+
+			rendered := mailer.NewMailSenderWithTransport(mailer.NewMemoryTransport(), test.from).Render(context.Background(), &test.mail)
+
+			goldenPath := filepath.Join("testdata", test.name+".eml")
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, rendered, 0o644); err != nil {
+					t.Fatalf("writing golden file: %s", err.Error())
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err.Error())
+			}
+
+			if !bytes.Equal(rendered, golden) {
+				t.Errorf("rendered message does not match %s, run the test with -update if the change is intended\n%s", goldenPath, rendered)
+			}
+
+			for i, line := range bytes.Split(bytes.TrimSuffix(rendered, []byte("\r\n")), []byte("\r\n")) {
+				if bytes.ContainsAny(line, "\r\n") {
+					t.Errorf("line %d has a bare line break: %q", i+1, line)
+				}
+
+				if len(line) > 998 {
+					t.Errorf("line %d is longer than 998 characters", i+1)
+				}
+			}
+
+			message, err := mail.ReadMessage(bytes.NewReader(rendered))
+			if err != nil {
+				t.Fatalf("parsing message: %s", err.Error())
+			}
+
+			decoder := new(mime.WordDecoder)
+			subject, err := decoder.DecodeHeader(message.Header.Get("Subject"))
+			if err != nil {
+				t.Fatalf("decoding subject: %s", err.Error())
+			}
+
+			if subject != test.mail.Subject {
+				t.Errorf("expecting subject %q, got %q", test.mail.Subject, subject)
+			}
+
+			from, err := message.Header.AddressList("From")
+			if err != nil {
+				t.Fatalf("parsing from: %s", err.Error())
+			}
+
+			if len(from) != 1 || *from[0] != test.from {
+				t.Errorf("expecting from %v, got %v", test.from, from)
+			}
+
+			to, err := message.Header.AddressList("To")
+			if err != nil {
+				t.Fatalf("parsing to: %s", err.Error())
+			}
+
+			if len(to) != 1 || to[0].Name != test.mail.RecipientName || to[0].Address != test.mail.RecipientEmail {
+				t.Errorf("expecting to %q <%s>, got %v", test.mail.RecipientName, test.mail.RecipientEmail, to)
+			}
+
+			if message.Header.Get("Message-ID") != "<"+test.mail.MessageId+">" {
+				t.Errorf("unexpected message id %q", message.Header.Get("Message-ID"))
+			}
+
+			if message.Header.Get("MIME-Version") != "1.0" {
+				t.Errorf("unexpected mime version %q", message.Header.Get("MIME-Version"))
+			}
+
+			root := parseEntity(t, message.Header, message.Body)
+			if structure := describeEntity(root); structure != test.structure {
+				t.Errorf("expecting structure %s, got %s", test.structure, structure)
+			}
+
+			texts := map[string]string{}
+			attachments := map[string]messageEntity{}
+			walkEntity(root, func(entity messageEntity) {
+				if strings.HasPrefix(entity.mediaType, "text/") {
+					if entity.params["charset"] != "utf-8" {
+						t.Errorf("expecting utf-8 charset on %s, got %q", entity.mediaType, entity.params["charset"])
+					}
+
+					texts[entity.mediaType] = decodeBody(t, entity)
+					return
+				}
+
+				_, params, err := mime.ParseMediaType(mail.Header(entity.header).Get("Content-Disposition"))
+				if err != nil {
+					t.Errorf("parsing content disposition: %s", err.Error())
+				}
+
+				attachments[params["filename"]] = entity
+			})
+
+			normalize := func(s string) string { return strings.ReplaceAll(s, "\r\n", "\n") }
+			if test.mail.PlainTextBody != "" && normalize(texts["text/plain"]) != normalize(test.mail.PlainTextBody) {
+				t.Errorf("expecting plain text %q, got %q", test.mail.PlainTextBody, texts["text/plain"])
+			}
+
+			if test.mail.HtmlBody != "" && normalize(texts["text/html"]) != normalize(test.mail.HtmlBody) {
+				t.Errorf("expecting html %q, got %q", test.mail.HtmlBody, texts["text/html"])
+			}
+
+			for _, attachment := range test.mail.Attachments {
+				entity, ok := attachments[attachment.Name]
+				if !ok {
+					t.Errorf("expecting attachment %q", attachment.Name)
+					continue
+				}
+
+				if decodeBody(t, entity) != string(attachment.Payload) {
+					t.Errorf("attachment %q payload does not match", attachment.Name)
+				}
+
+				if attachment.ContentId != "" && mail.Header(entity.header).Get("Content-ID") != "<"+attachment.ContentId+">" {
+					t.Errorf("unexpected content id %q on %q", mail.Header(entity.header).Get("Content-ID"), attachment.Name)
+				}
+
+				for _, line := range bytes.Split(entity.body, []byte("\r\n")) {
+					if len(line) > 76 {
+						t.Errorf("expecting base64 lines of %q to be wrapped at 76 characters, got %d", attachment.Name, len(line))
+					}
+				}
+			}
+		})
+	}
+}
+
+func describeEntity(entity messageEntity) string {
+	if len(entity.children) == 0 {
+		return entity.mediaType
+	}
+
+	var children []string
+	for _, child := range entity.children {
+		children = append(children, describeEntity(child))
+	}
+
+	return entity.mediaType + "(" + strings.Join(children, ",") + ")"
+}
+
+func walkEntity(entity messageEntity, fn func(messageEntity)) {
+	if len(entity.children) == 0 {
+		fn(entity)
+		return
+	}
+
+	for _, child := range entity.children {
+		walkEntity(child, fn)
+	}
+}
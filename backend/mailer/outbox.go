@@ -0,0 +1,255 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// OutboxStatus is the delivery status of a message on the outbox.
+type OutboxStatus string
+
+const (
+	// OutboxStatusPending is waiting for its next attempt.
+	OutboxStatusPending OutboxStatus = "pending"
+	// OutboxStatusSending has been claimed by a worker. If the worker dies, it's picked up again once
+	// NextAttemptAt passes.
+	OutboxStatusSending OutboxStatus = "sending"
+	OutboxStatusSent    OutboxStatus = "sent"
+	// OutboxStatusFailed has run out of attempts, it won't be retried anymore.
+	OutboxStatusFailed OutboxStatus = "failed"
+)
+
+// ErrOutboxMessageNotFound is returned when the message does not exist on the outbox.
+var ErrOutboxMessageNotFound = errors.New("outbox message not found")
+
+// OutboxMessage is a rendered message on the outbox. The message is rendered once when it's enqueued, so
+// every attempt delivers the exact same bytes.
+type OutboxMessage struct {
+	Id            string
+	From          string
+	Recipients    []string
+	Message       []byte
+	Status        OutboxStatus
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// OutboxStore persists the outbox messages.
+type OutboxStore interface {
+	InsertMessage(ctx context.Context, message OutboxMessage) error
+	// ClaimMessages marks up to limit messages that are due at now as OutboxStatusSending, and moves their
+	// NextAttemptAt to leaseUntil so no other worker claims them in the meantime.
+	ClaimMessages(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]OutboxMessage, error)
+	UpdateMessage(ctx context.Context, message OutboxMessage) error
+	// GetMessage returns ErrOutboxMessageNotFound if the message does not exist.
+	GetMessage(ctx context.Context, id string) (OutboxMessage, error)
+}
+
+type OutboxOptions struct {
+	// Workers is the number of messages delivered concurrently. Defaults to 4.
+	Workers int
+	// MaxAttempts is the number of attempts before a message is marked as failed. Defaults to 8.
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failed attempt, it's doubled on every following attempt.
+	// Defaults to 30 seconds.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Defaults to 1 hour.
+	MaxBackoff time.Duration
+	// Lease is how long a claimed message is reserved for a worker. Defaults to 5 minutes.
+	Lease time.Duration
+	// PollInterval is how often the store is checked for due messages. Defaults to 5 seconds.
+	PollInterval time.Duration
+}
+
+// Outbox queues mails on a durable store and delivers them in the background, retrying with an exponential
+// backoff. It implements Sender, so domains can enqueue mails instead of waiting on the SMTP server.
+type Outbox struct {
+	store   OutboxStore
+	mailer  *Mailer
+	options OutboxOptions
+	wake    chan struct{}
+}
+
+func NewOutbox(store OutboxStore, mailer *Mailer, options OutboxOptions) (*Outbox, error) {
+	if store == nil {
+		return nil, fmt.Errorf("store is nil")
+	}
+
+	if mailer == nil {
+		return nil, fmt.Errorf("mailer is nil")
+	}
+
+	if options.Workers <= 0 {
+		options.Workers = 4
+	}
+
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 8
+	}
+
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = 30 * time.Second
+	}
+
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = time.Hour
+	}
+
+	if options.Lease <= 0 {
+		options.Lease = 5 * time.Minute
+	}
+
+	if options.PollInterval <= 0 {
+		options.PollInterval = 5 * time.Second
+	}
+
+	return &Outbox{
+		store:   store,
+		mailer:  mailer,
+		options: options,
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Send enqueues the mail. It returns once the message is stored, not when it's delivered.
+func (o *Outbox) Send(ctx context.Context, mail *Mail) error {
+	_, err := o.Enqueue(ctx, mail)
+	return err
+}
+
+// Enqueue renders the mail and stores it on the outbox. It returns the ID of the outbox message, which can
+// be used to check its status.
+func (o *Outbox) Enqueue(ctx context.Context, mail *Mail) (string, error) {
+	span := sentry.StartSpan(ctx, "mailer.outbox.enqueue")
+	defer span.Finish()
+
+	now := time.Now()
+	message := OutboxMessage{
+		Id:            uuid.NewString(),
+		From:          o.mailer.from.Address,
+		Recipients:    []string{mail.RecipientEmail},
+		Message:       o.mailer.Render(ctx, mail),
+		Status:        OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	err := o.store.InsertMessage(ctx, message)
+	if err != nil {
+		return "", fmt.Errorf("inserting outbox message: %w", err)
+	}
+
+	// Let the workers know there is something to deliver, without waiting for the next poll.
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return message.Id, nil
+}
+
+// Status returns the outbox message identified by the id, so callers can check its delivery status.
+func (o *Outbox) Status(ctx context.Context, id string) (OutboxMessage, error) {
+	return o.store.GetMessage(ctx, id)
+}
+
+// Run delivers the due messages until the context is canceled.
+func (o *Outbox) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := o.ProcessDue(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("processing mail outbox")
+		}
+
+		// Keep going while there might be more due messages, otherwise wait for the next poll or enqueue.
+		if processed > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// ProcessDue claims the messages that are due at now and delivers them. It returns the number of messages
+// that have been attempted.
+func (o *Outbox) ProcessDue(ctx context.Context, now time.Time) (int, error) {
+	messages, err := o.store.ClaimMessages(ctx, now, now.Add(o.options.Lease), o.options.Workers)
+	if err != nil {
+		return 0, fmt.Errorf("claiming outbox messages: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(messages))
+	for i, message := range messages {
+		wg.Add(1)
+		go func(i int, message OutboxMessage) {
+			defer wg.Done()
+			errs[i] = o.deliver(ctx, message, now)
+		}(i, message)
+	}
+	wg.Wait()
+
+	return len(messages), errors.Join(errs...)
+}
+
+func (o *Outbox) deliver(ctx context.Context, message OutboxMessage, now time.Time) error {
+	span := sentry.StartSpan(ctx, "mailer.outbox.deliver")
+	defer span.Finish()
+
+	message.Attempts++
+	message.UpdatedAt = now
+
+	err := o.mailer.transport.Deliver(ctx, message.From, message.Recipients, message.Message)
+	switch {
+	case err == nil:
+		message.Status = OutboxStatusSent
+		message.LastError = ""
+	case message.Attempts >= o.options.MaxAttempts:
+		message.Status = OutboxStatusFailed
+		message.LastError = err.Error()
+		log.Error().Err(err).Str("outbox_id", message.Id).Int("attempts", message.Attempts).Msg("giving up on outbox message")
+	default:
+		message.Status = OutboxStatusPending
+		message.LastError = err.Error()
+		message.NextAttemptAt = now.Add(o.backoff(message.Attempts))
+		log.Warn().Err(err).Str("outbox_id", message.Id).Int("attempts", message.Attempts).Msg("delivering outbox message")
+	}
+
+	// The delivery outcome is recorded even if the context is canceled in the meantime, otherwise a sent
+	// message would be sent again once its lease expires.
+	err = o.store.UpdateMessage(context.WithoutCancel(ctx), message)
+	if err != nil {
+		return fmt.Errorf("updating outbox message %s: %w", message.Id, err)
+	}
+
+	return nil
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.options.BaseBackoff
+	for i := 1; i < attempts && delay < o.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, o.options.MaxBackoff)
+}
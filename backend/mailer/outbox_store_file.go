@@ -0,0 +1,192 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileOutboxStore implements OutboxStore as a directory of JSON files, one per message. Claims are only
+// guarded within a single process, use PostgresOutboxStore if you are running more than one instance.
+//
+// Sent and failed messages are moved to the archive subdirectory, so ClaimMessages only reads the messages
+// that still wait for delivery. The directories are created on the first write, not by NewFileOutboxStore, so
+// commands that never queue a message leave the path alone.
+type FileOutboxStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+const outboxArchiveDirectory = "archive"
+
+func NewFileOutboxStore(path string) (*FileOutboxStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	return &FileOutboxStore{path: path}, nil
+}
+
+func (f *FileOutboxStore) InsertMessage(ctx context.Context, message OutboxMessage) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.write(message)
+}
+
+func (f *FileOutboxStore) ClaimMessages(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]OutboxMessage, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	entries, err := os.ReadDir(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading outbox directory: %w", err)
+	}
+
+	var due []OutboxMessage
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !found {
+			continue
+		}
+
+		message, err := f.read(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if message.Status != OutboxStatusPending && message.Status != OutboxStatusSending {
+			continue
+		}
+
+		if message.NextAttemptAt.After(now) {
+			continue
+		}
+
+		due = append(due, message)
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].Status = OutboxStatusSending
+		due[i].NextAttemptAt = leaseUntil
+		due[i].UpdatedAt = now
+		if err := f.write(due[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return due, nil
+}
+
+func (f *FileOutboxStore) UpdateMessage(ctx context.Context, message OutboxMessage) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.write(message)
+}
+
+func (f *FileOutboxStore) GetMessage(ctx context.Context, id string) (OutboxMessage, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.read(id)
+}
+
+func (f *FileOutboxStore) read(id string) (OutboxMessage, error) {
+	if strings.ContainsAny(id, `/\`) {
+		return OutboxMessage{}, ErrOutboxMessageNotFound
+	}
+
+	content, err := os.ReadFile(filepath.Join(f.path, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		content, err = os.ReadFile(filepath.Join(f.path, outboxArchiveDirectory, id+".json"))
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return OutboxMessage{}, ErrOutboxMessageNotFound
+		}
+
+		return OutboxMessage{}, fmt.Errorf("reading outbox message: %w", err)
+	}
+
+	var message OutboxMessage
+	err = json.Unmarshal(content, &message)
+	if err != nil {
+		return OutboxMessage{}, fmt.Errorf("parsing outbox message %s: %w", id, err)
+	}
+
+	return message, nil
+}
+
+// write replaces the message file atomically, so a crash never leaves a half written message behind. Messages
+// that won't be attempted anymore are moved to the archive directory.
+func (f *FileOutboxStore) write(message OutboxMessage) error {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("marshaling outbox message: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Join(f.path, outboxArchiveDirectory), 0o700)
+	if err != nil {
+		return fmt.Errorf("creating outbox directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(f.path, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating outbox message file: %w", err)
+	}
+
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("writing outbox message file: %w", err)
+	}
+
+	activePath := filepath.Join(f.path, message.Id+".json")
+	if message.Status != OutboxStatusSent && message.Status != OutboxStatusFailed {
+		err = os.Rename(file.Name(), activePath)
+		if err != nil {
+			_ = os.Remove(file.Name())
+			return fmt.Errorf("moving outbox message file: %w", err)
+		}
+
+		return nil
+	}
+
+	err = os.Rename(file.Name(), filepath.Join(f.path, outboxArchiveDirectory, message.Id+".json"))
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("archiving outbox message file: %w", err)
+	}
+
+	err = os.Remove(activePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing archived outbox message file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package mailer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PostgresOutboxStore implements OutboxStore on top of the `mail_outbox` table. Claims use
+// `FOR UPDATE SKIP LOCKED`, so multiple instances can run their workers against the same table.
+type PostgresOutboxStore struct {
+	db *sql.DB
+}
+
+func NewPostgresOutboxStore(db *sql.DB) (*PostgresOutboxStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &PostgresOutboxStore{db: db}, nil
+}
+
+func (p *PostgresOutboxStore) InsertMessage(ctx context.Context, message OutboxMessage) error {
+	recipients, err := json.Marshal(message.Recipients)
+	if err != nil {
+		return fmt.Errorf("marshaling recipients: %w", err)
+	}
+
+	_, err = p.db.ExecContext(
+		ctx,
+		`INSERT INTO mail_outbox (id, sender, recipients, message, status, attempts, last_error, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		message.Id,
+		message.From,
+		string(recipients),
+		message.Message,
+		string(message.Status),
+		message.Attempts,
+		message.LastError,
+		message.NextAttemptAt,
+		message.CreatedAt,
+		message.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting mail outbox: %w", err)
+	}
+
+	return nil
+}
+
+const outboxColumns = `id, sender, recipients, message, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+func (p *PostgresOutboxStore) ClaimMessages(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]OutboxMessage, error) {
+	rows, err := p.db.QueryContext(
+		ctx,
+		`UPDATE mail_outbox SET status = $1, next_attempt_at = $2, updated_at = $3
+		WHERE id IN (
+			SELECT id FROM mail_outbox
+			WHERE status IN ($4, $1) AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+outboxColumns,
+		string(OutboxStatusSending),
+		leaseUntil,
+		now,
+		string(OutboxStatusPending),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming mail outbox: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating mail outbox: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (p *PostgresOutboxStore) UpdateMessage(ctx context.Context, message OutboxMessage) error {
+	_, err := p.db.ExecContext(
+		ctx,
+		`UPDATE mail_outbox SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5, updated_at = $6 WHERE id = $1`,
+		message.Id,
+		string(message.Status),
+		message.Attempts,
+		message.LastError,
+		message.NextAttemptAt,
+		message.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("updating mail outbox: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresOutboxStore) GetMessage(ctx context.Context, id string) (OutboxMessage, error) {
+	row := p.db.QueryRowContext(ctx, `SELECT `+outboxColumns+` FROM mail_outbox WHERE id = $1`, id)
+	message, err := scanOutboxMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return OutboxMessage{}, ErrOutboxMessageNotFound
+	}
+
+	return message, err
+}
+
+func scanOutboxMessage(row interface{ Scan(dest ...any) error }) (OutboxMessage, error) {
+	var message OutboxMessage
+	var recipients, status string
+	err := row.Scan(
+		&message.Id,
+		&message.From,
+		&recipients,
+		&message.Message,
+		&status,
+		&message.Attempts,
+		&message.LastError,
+		&message.NextAttemptAt,
+		&message.CreatedAt,
+		&message.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return OutboxMessage{}, err
+		}
+
+		return OutboxMessage{}, fmt.Errorf("scanning mail outbox: %w", err)
+	}
+
+	message.Status = OutboxStatus(status)
+	err = json.Unmarshal([]byte(recipients), &message.Recipients)
+	if err != nil {
+		return OutboxMessage{}, fmt.Errorf("parsing recipients of %s: %w", message.Id, err)
+	}
+
+	return message, nil
+}
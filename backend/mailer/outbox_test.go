@@ -0,0 +1,318 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"conf/mailer"
+)
+
+// flakyTransport fails the first failures deliveries, then hands the rest over to the memory transport.
+type flakyTransport struct {
+	mutex    sync.Mutex
+	failures int
+	*mailer.MemoryTransport
+}
+
+func (f *flakyTransport) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	f.mutex.Lock()
+	if f.failures > 0 {
+		f.failures--
+		f.mutex.Unlock()
+		return errors.New("421 service not available")
+	}
+	f.mutex.Unlock()
+
+	return f.MemoryTransport.Deliver(ctx, from, recipients, message)
+}
+
+func newTestOutbox(t *testing.T, transport mailer.Transport, options mailer.OutboxOptions) *mailer.Outbox {
+	t.Helper()
+
+	store, err := mailer.NewFileOutboxStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating outbox store: %s", err.Error())
+	}
+
+	outbox, err := mailer.NewOutbox(store, mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), options)
+	if err != nil {
+		t.Fatalf("creating outbox: %s", err.Error())
+	}
+
+	return outbox
+}
+
+func TestOutbox_Retry(t *testing.T) {
+	ctx := context.Background()
+	transport := &flakyTransport{failures: 2, MemoryTransport: mailer.NewMemoryTransport()}
+	outbox := newTestOutbox(t, transport, mailer.OutboxOptions{BaseBackoff: time.Minute, MaxBackoff: time.Hour})
+
+	id, err := outbox.Enqueue(ctx, &mailer.Mail{RecipientEmail: "johndoe@example.com", Subject: "Retry"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	now := time.Now()
+	expectStatus := func(status mailer.OutboxStatus, attempts int) mailer.OutboxMessage {
+		t.Helper()
+
+		message, err := outbox.Status(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if message.Status != status || message.Attempts != attempts {
+			t.Fatalf("expecting %s after %d attempts, got %s after %d attempts", status, attempts, message.Status, message.Attempts)
+		}
+
+		return message
+	}
+
+	// First attempt fails, the next one is a minute later.
+	if _, err := outbox.ProcessDue(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	message := expectStatus(mailer.OutboxStatusPending, 1)
+	if !message.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("expecting next attempt after 1 minute, got %s", message.NextAttemptAt.Sub(now))
+	}
+
+	if message.LastError == "" {
+		t.Error("expecting last error to be recorded")
+	}
+
+	// Not due yet
+	processed, _ := outbox.ProcessDue(ctx, now.Add(30*time.Second))
+	if processed != 0 {
+		t.Errorf("expecting no message to be due, got %d", processed)
+	}
+
+	// Second attempt fails, the backoff is doubled.
+	now = now.Add(time.Minute)
+	if _, err := outbox.ProcessDue(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	message = expectStatus(mailer.OutboxStatusPending, 2)
+	if !message.NextAttemptAt.Equal(now.Add(2 * time.Minute)) {
+		t.Errorf("expecting next attempt after 2 minutes, got %s", message.NextAttemptAt.Sub(now))
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := outbox.ProcessDue(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expectStatus(mailer.OutboxStatusSent, 3)
+
+	if len(transport.Messages()) != 1 {
+		t.Errorf("expecting the message to be delivered once, got %d", len(transport.Messages()))
+	}
+
+	// Sent messages are not delivered again.
+	processed, _ = outbox.ProcessDue(ctx, now.Add(24*time.Hour))
+	if processed != 0 {
+		t.Errorf("expecting no message to be due, got %d", processed)
+	}
+}
+
+func TestOutbox_MaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	transport := &flakyTransport{failures: 100, MemoryTransport: mailer.NewMemoryTransport()}
+	outbox := newTestOutbox(t, transport, mailer.OutboxOptions{MaxAttempts: 3, BaseBackoff: time.Second})
+
+	id, err := outbox.Enqueue(ctx, &mailer.Mail{RecipientEmail: "johndoe@example.com", Subject: "Give up"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		_, _ = outbox.ProcessDue(ctx, now)
+		now = now.Add(time.Hour)
+	}
+
+	message, err := outbox.Status(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if message.Status != mailer.OutboxStatusFailed || message.Attempts != 3 {
+		t.Errorf("expecting failed after 3 attempts, got %s after %d attempts", message.Status, message.Attempts)
+	}
+}
+
+// blockingStore claims messages but never records the outcome, like a worker that dies mid delivery.
+type blockingStore struct {
+	mailer.OutboxStore
+}
+
+func (b blockingStore) UpdateMessage(ctx context.Context, message mailer.OutboxMessage) error {
+	return errors.New("worker died")
+}
+
+func TestOutbox_ExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	store, err := mailer.NewFileOutboxStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating outbox store: %s", err.Error())
+	}
+
+	transport := mailer.NewMemoryTransport()
+	dyingOutbox, err := mailer.NewOutbox(blockingStore{store}, mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.OutboxOptions{Lease: time.Minute})
+	if err != nil {
+		t.Fatalf("creating outbox: %s", err.Error())
+	}
+
+	outbox, err := mailer.NewOutbox(store, mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom), mailer.OutboxOptions{Lease: time.Minute})
+	if err != nil {
+		t.Fatalf("creating outbox: %s", err.Error())
+	}
+
+	id, err := dyingOutbox.Enqueue(ctx, &mailer.Mail{RecipientEmail: "johndoe@example.com", Subject: "Lease"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	now := time.Now()
+	if _, err := dyingOutbox.ProcessDue(ctx, now); err == nil {
+		t.Fatal("expecting an error, got nil")
+	}
+
+	// The claimed message is held by the lease...
+	processed, _ := outbox.ProcessDue(ctx, now.Add(30*time.Second))
+	if processed != 0 {
+		t.Errorf("expecting the leased message not to be claimed, got %d", processed)
+	}
+
+	// ...and picked up again once it expires.
+	if _, err := outbox.ProcessDue(ctx, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	message, err := outbox.Status(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if message.Status != mailer.OutboxStatusSent {
+		t.Errorf("expecting sent, got %s", message.Status)
+	}
+}
+
+func TestOutbox_Run(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transport := mailer.NewMemoryTransport()
+	outbox := newTestOutbox(t, transport, mailer.OutboxOptions{PollInterval: time.Hour})
+
+	done := make(chan struct{})
+	go func() {
+		_ = outbox.Run(ctx)
+		close(done)
+	}()
+
+	// Enqueue wakes the workers up without waiting for the poll interval.
+	err := outbox.Send(ctx, &mailer.Mail{RecipientEmail: "johndoe@example.com", Subject: "Run"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for len(transport.Messages()) == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for delivery")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	<-done
+}
+
+func TestOutbox_StatusNotFound(t *testing.T) {
+	outbox := newTestOutbox(t, mailer.NewMemoryTransport(), mailer.OutboxOptions{})
+
+	_, err := outbox.Status(context.Background(), "does-not-exist")
+	if !errors.Is(err, mailer.ErrOutboxMessageNotFound) {
+		t.Errorf("expecting ErrOutboxMessageNotFound, got %v", err)
+	}
+}
+
+func TestFileOutboxStore_Archive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	path := t.TempDir()
+	store, err := mailer.NewFileOutboxStore(path)
+	if err != nil {
+		t.Fatalf("creating outbox store: %s", err.Error())
+	}
+
+	now := time.Now()
+	message := mailer.OutboxMessage{Id: "archived", Status: mailer.OutboxStatusPending, NextAttemptAt: now, CreatedAt: now}
+	if err := store.InsertMessage(ctx, message); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	message.Status = mailer.OutboxStatusSent
+	if err := store.UpdateMessage(ctx, message); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "archived.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expecting the sent message to be moved out of the outbox directory, got %v", err)
+	}
+
+	archived, err := store.GetMessage(ctx, "archived")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if archived.Status != mailer.OutboxStatusSent {
+		t.Errorf("expecting the archived message to be sent, got %s", archived.Status)
+	}
+
+	claimed, err := store.ClaimMessages(ctx, now.Add(time.Hour), now.Add(2*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(claimed) != 0 {
+		t.Errorf("expecting no message to be claimed, got %d", len(claimed))
+	}
+}
+
+func TestFileOutboxStore_CreatesDirectoryOnWrite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "mail-outbox")
+	store, err := mailer.NewFileOutboxStore(path)
+	if err != nil {
+		t.Fatalf("creating outbox store: %s", err.Error())
+	}
+
+	now := time.Now()
+	claimed, err := store.ClaimMessages(ctx, now, now.Add(time.Hour), 10)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("expecting no message to be claimed, got %d messages and %v", len(claimed), err)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expecting the outbox directory not to be created before a write, got %v", err)
+	}
+
+	err = store.InsertMessage(ctx, mailer.OutboxMessage{Id: "first", Status: mailer.OutboxStatusPending, NextAttemptAt: now, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if _, err := store.GetMessage(ctx, "first"); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+}
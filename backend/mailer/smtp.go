@@ -0,0 +1,324 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// TLSMode decides how the SMTP connection is secured.
+type TLSMode string
+
+const (
+	// TLSModeOpportunistic upgrades the connection with STARTTLS if the server advertises it.
+	TLSModeOpportunistic TLSMode = ""
+	// TLSModeNone never encrypts the connection.
+	TLSModeNone TLSMode = "none"
+	// TLSModeStartTLS requires the server to support STARTTLS, usually on port 587.
+	TLSModeStartTLS TLSMode = "starttls"
+	// TLSModeImplicit opens a TLS connection right away, usually on port 465.
+	TLSModeImplicit TLSMode = "implicit"
+)
+
+// AuthMechanism is the SASL mechanism used to authenticate to the SMTP server.
+type AuthMechanism string
+
+const (
+	AuthPlain   AuthMechanism = "plain"
+	AuthLogin   AuthMechanism = "login"
+	AuthCramMD5 AuthMechanism = "cram-md5"
+	AuthXOAuth2 AuthMechanism = "xoauth2"
+)
+
+// ErrStartTLSNotSupported is returned when TLSModeStartTLS is required but the server does not advertise it.
+var ErrStartTLSNotSupported = errors.New("smtp server does not support STARTTLS")
+
+// smtpTimeout bounds a single delivery if the context does not have a deadline.
+const smtpTimeout = 2 * time.Minute
+
+// SmtpTransport delivers messages to an SMTP server.
+type SmtpTransport struct {
+	configuration *MailConfiguration
+}
+
+func NewSmtpTransport(configuration *MailConfiguration) *SmtpTransport {
+	return &SmtpTransport{configuration: configuration}
+}
+
+func (s *SmtpTransport) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	session, err := s.OpenSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return session.Deliver(ctx, from, recipients, message)
+}
+
+// OpenSession connects and authenticates to the SMTP server, so many messages can be delivered on the same
+// connection.
+func (s *SmtpTransport) OpenSession(ctx context.Context) (Session, error) {
+	conn, client, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &smtpSession{conn: conn, client: client}
+	if err := s.handshake(ctx, client); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// handshake secures the connection according to the TLS mode, then authenticates.
+func (s *SmtpTransport) handshake(ctx context.Context, client *smtp.Client) error {
+	if s.configuration.TLSMode != TLSModeImplicit && s.configuration.TLSMode != TLSModeNone {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tlsConfig()); err != nil {
+				return fmt.Errorf("starting tls: %w", err)
+			}
+		} else if s.configuration.TLSMode == TLSModeStartTLS {
+			return ErrStartTLSNotSupported
+		}
+	}
+
+	auth, err := s.auth(ctx)
+	if err != nil {
+		return err
+	}
+
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server does not support AUTH")
+		}
+
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// dial connects to the SMTP server and reads its greeting. The connection is bound to the context deadline.
+func (s *SmtpTransport) dial(ctx context.Context) (net.Conn, *smtp.Client, error) {
+	address := net.JoinHostPort(s.configuration.SmtpHostname, s.configuration.SmtpPort)
+	deadline := smtpDeadline(ctx)
+
+	var conn net.Conn
+	var err error
+	if s.configuration.TLSMode == TLSModeImplicit {
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Deadline: deadline}, Config: s.tlsConfig()}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		dialer := &net.Dialer{Deadline: deadline}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to %s: %w", address, err)
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("setting deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, s.configuration.SmtpHostname)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("reading greeting: %w", err)
+	}
+
+	return conn, client, nil
+}
+
+func smtpDeadline(ctx context.Context) time.Time {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+
+	return deadline
+}
+
+// smtpSession delivers messages on an authenticated SMTP connection.
+type smtpSession struct {
+	conn   net.Conn
+	client *smtp.Client
+}
+
+func (s *smtpSession) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	if err := s.conn.SetDeadline(smtpDeadline(ctx)); err != nil {
+		return fmt.Errorf("setting deadline: %w", err)
+	}
+
+	err := s.deliver(from, recipients, message)
+	if err != nil {
+		// Drop the half-finished transaction so the next message starts clean. The error is ignored, a broken
+		// connection fails the next message anyway.
+		_ = s.client.Reset()
+	}
+
+	return err
+}
+
+func (s *smtpSession) deliver(from string, recipients []string, message []byte) error {
+	if err := s.client.Mail(from); err != nil {
+		return fmt.Errorf("sending MAIL FROM: %w", err)
+	}
+
+	for _, recipient := range recipients {
+		if err := s.client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("sending RCPT TO %s: %w", recipient, err)
+		}
+	}
+
+	writer, err := s.client.Data()
+	if err != nil {
+		return fmt.Errorf("sending DATA: %w", err)
+	}
+
+	if _, err := writer.Write(message); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("finishing message: %w", err)
+	}
+
+	return nil
+}
+
+// Close says QUIT to the server, the connection is closed even if the server does not reply.
+func (s *smtpSession) Close() error {
+	err := s.client.Quit()
+	if err != nil {
+		_ = s.client.Close()
+	}
+
+	return err
+}
+
+func (s *SmtpTransport) tlsConfig() *tls.Config {
+	serverName := s.configuration.ServerName
+	if serverName == "" {
+		serverName = s.configuration.SmtpHostname
+	}
+
+	return &tls.Config{
+		ServerName: serverName,
+		RootCAs:    s.configuration.RootCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// auth returns nil if the transport is not configured to authenticate.
+func (s *SmtpTransport) auth(ctx context.Context) (smtp.Auth, error) {
+	username := s.configuration.SmtpFrom
+	if username == "" {
+		return nil, nil
+	}
+
+	switch s.configuration.AuthMechanism {
+	case "", AuthPlain:
+		if s.configuration.SmtpPassword == "" {
+			return nil, nil
+		}
+
+		return smtp.PlainAuth("", username, s.configuration.SmtpPassword, s.configuration.SmtpHostname), nil
+	case AuthLogin:
+		return &loginAuth{username: username, password: s.configuration.SmtpPassword, host: s.configuration.SmtpHostname}, nil
+	case AuthCramMD5:
+		return smtp.CRAMMD5Auth(username, s.configuration.SmtpPassword), nil
+	case AuthXOAuth2:
+		if s.configuration.OAuth2Token == nil {
+			return nil, fmt.Errorf("xoauth2 requires an oauth2 token")
+		}
+
+		token, err := s.configuration.OAuth2Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("acquiring oauth2 token: %w", err)
+		}
+
+		return &xoauth2Auth{username: username, token: token, host: s.configuration.SmtpHostname}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mechanism %q", s.configuration.AuthMechanism)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which sends the credentials in plain text just like PLAIN.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if err := requireEncryption(server, a.host); err != nil {
+		return "", nil, err
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	prompt := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+// xoauth2Auth implements the XOAUTH2 mechanism of Google and Microsoft.
+type xoauth2Auth struct {
+	username string
+	token    string
+	host     string
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if err := requireEncryption(server, a.host); err != nil {
+		return "", nil, err
+	}
+
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sends a JSON error as the challenge, an empty response makes it reply with the
+		// actual failure.
+		return []byte{}, nil
+	}
+
+	return nil, nil
+}
+
+// requireEncryption refuses to send credentials on an unencrypted connection, except to localhost, the same
+// way smtp.PlainAuth does.
+func requireEncryption(server *smtp.ServerInfo, host string) error {
+	if server.Name != host {
+		return errors.New("wrong host name")
+	}
+
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" && server.Name != "::1" {
+		return errors.New("unencrypted connection")
+	}
+
+	return nil
+}
@@ -0,0 +1,547 @@
+package mailer_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"conf/mailer"
+)
+
+const (
+	smtpServerName = "mail.test.local"
+	smtpUsername   = "sender@example.com"
+	smtpPassword   = "correct horse battery staple"
+	smtpToken      = "ya29.access-token"
+)
+
+func TestSmtpTransport(t *testing.T) {
+	certificate, rootCAs := generateSelfSignedCertificate(t)
+
+	testCases := []struct {
+		name          string
+		server        smtpServerOptions
+		configuration mailer.MailConfiguration
+		expectTLS     bool
+		expectAuth    string
+		expectError   string
+	}{
+		{
+			name:   "implicit tls with plain",
+			server: smtpServerOptions{implicitTLS: true, mechanisms: []string{"PLAIN", "LOGIN"}},
+			configuration: mailer.MailConfiguration{
+				TLSMode:      mailer.TLSModeImplicit,
+				SmtpFrom:     smtpUsername,
+				SmtpPassword: smtpPassword,
+			},
+			expectTLS:  true,
+			expectAuth: "PLAIN",
+		},
+		{
+			name:   "required starttls with login",
+			server: smtpServerOptions{startTLS: true, mechanisms: []string{"LOGIN"}},
+			configuration: mailer.MailConfiguration{
+				TLSMode:       mailer.TLSModeStartTLS,
+				SmtpFrom:      smtpUsername,
+				SmtpPassword:  smtpPassword,
+				AuthMechanism: mailer.AuthLogin,
+			},
+			expectTLS:  true,
+			expectAuth: "LOGIN",
+		},
+		{
+			name:   "opportunistic starttls with cram-md5",
+			server: smtpServerOptions{startTLS: true, mechanisms: []string{"CRAM-MD5"}},
+			configuration: mailer.MailConfiguration{
+				SmtpFrom:      smtpUsername,
+				SmtpPassword:  smtpPassword,
+				AuthMechanism: mailer.AuthCramMD5,
+			},
+			expectTLS:  true,
+			expectAuth: "CRAM-MD5",
+		},
+		{
+			name:   "implicit tls with xoauth2",
+			server: smtpServerOptions{implicitTLS: true, mechanisms: []string{"XOAUTH2"}},
+			configuration: mailer.MailConfiguration{
+				TLSMode:       mailer.TLSModeImplicit,
+				SmtpFrom:      smtpUsername,
+				AuthMechanism: mailer.AuthXOAuth2,
+				OAuth2Token: func(ctx context.Context) (string, error) {
+					return smtpToken, nil
+				},
+			},
+			expectTLS:  true,
+			expectAuth: "XOAUTH2",
+		},
+		{
+			name:   "xoauth2 with expired token",
+			server: smtpServerOptions{implicitTLS: true, mechanisms: []string{"XOAUTH2"}},
+			configuration: mailer.MailConfiguration{
+				TLSMode:       mailer.TLSModeImplicit,
+				SmtpFrom:      smtpUsername,
+				AuthMechanism: mailer.AuthXOAuth2,
+				OAuth2Token: func(ctx context.Context) (string, error) {
+					return "expired", nil
+				},
+			},
+			expectError: "535",
+		},
+		{
+			name:   "wrong password",
+			server: smtpServerOptions{startTLS: true, mechanisms: []string{"PLAIN"}},
+			configuration: mailer.MailConfiguration{
+				TLSMode:      mailer.TLSModeStartTLS,
+				SmtpFrom:     smtpUsername,
+				SmtpPassword: "wrong",
+			},
+			expectError: "535",
+		},
+		{
+			name:          "starttls is not supported",
+			server:        smtpServerOptions{},
+			configuration: mailer.MailConfiguration{TLSMode: mailer.TLSModeStartTLS},
+			expectError:   mailer.ErrStartTLSNotSupported.Error(),
+		},
+		{
+			name:          "tls disabled",
+			server:        smtpServerOptions{startTLS: true},
+			configuration: mailer.MailConfiguration{TLSMode: mailer.TLSModeNone},
+			expectTLS:     false,
+		},
+		{
+			name:          "unknown certificate authority",
+			server:        smtpServerOptions{implicitTLS: true},
+			configuration: mailer.MailConfiguration{TLSMode: mailer.TLSModeImplicit, RootCAs: x509.NewCertPool()},
+			expectError:   "certificate",
+		},
+		{
+			name:   "wrong server name",
+			server: smtpServerOptions{startTLS: true},
+			configuration: mailer.MailConfiguration{
+				TLSMode:    mailer.TLSModeStartTLS,
+				ServerName: "other.test.local",
+			},
+			expectError: "certificate",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.server.certificate = certificate
+			server := startSmtpServer(t, testCase.server)
+
+			configuration := testCase.configuration
+			configuration.SmtpHostname, configuration.SmtpPort, _ = net.SplitHostPort(server.address())
+			if configuration.ServerName == "" {
+				configuration.ServerName = smtpServerName
+			}
+			if configuration.RootCAs == nil {
+				configuration.RootCAs = rootCAs
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			transport := mailer.NewSmtpTransport(&configuration)
+			err := transport.Deliver(ctx, "conference@example.com", []string{"first@example.com", "second@example.com"}, []byte("Subject: Hello\r\n\r\nHello over SMTP\r\n.leading dot\r\n"))
+
+			if testCase.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectError) {
+					t.Fatalf("expecting error containing %q, got %v", testCase.expectError, err)
+				}
+
+				if messages := server.messages(); len(messages) != 0 {
+					t.Errorf("expecting no message to be delivered, got %d", len(messages))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			messages := server.messages()
+			if len(messages) != 1 {
+				t.Fatalf("expecting 1 message, got %d", len(messages))
+			}
+
+			message := messages[0]
+			if message.from != "conference@example.com" {
+				t.Errorf("expecting sender conference@example.com, got %q", message.from)
+			}
+
+			if strings.Join(message.recipients, ",") != "first@example.com,second@example.com" {
+				t.Errorf("unexpected recipients %v", message.recipients)
+			}
+
+			if message.data != "Subject: Hello\r\n\r\nHello over SMTP\r\n.leading dot\r\n" {
+				t.Errorf("unexpected message %q", message.data)
+			}
+
+			if message.tls != testCase.expectTLS {
+				t.Errorf("expecting tls to be %t, got %t", testCase.expectTLS, message.tls)
+			}
+
+			if message.auth != testCase.expectAuth {
+				t.Errorf("expecting auth mechanism %q, got %q", testCase.expectAuth, message.auth)
+			}
+		})
+	}
+}
+
+func TestSmtpTransport_OAuth2TokenError(t *testing.T) {
+	certificate, rootCAs := generateSelfSignedCertificate(t)
+	server := startSmtpServer(t, smtpServerOptions{certificate: certificate, implicitTLS: true, mechanisms: []string{"XOAUTH2"}})
+
+	hostname, port, _ := net.SplitHostPort(server.address())
+	tokenErr := errors.New("refresh token revoked")
+	transport := mailer.NewSmtpTransport(&mailer.MailConfiguration{
+		SmtpHostname:  hostname,
+		SmtpPort:      port,
+		SmtpFrom:      smtpUsername,
+		TLSMode:       mailer.TLSModeImplicit,
+		ServerName:    smtpServerName,
+		RootCAs:       rootCAs,
+		AuthMechanism: mailer.AuthXOAuth2,
+		OAuth2Token: func(ctx context.Context) (string, error) {
+			return "", tokenErr
+		},
+	})
+
+	err := transport.Deliver(context.Background(), "conference@example.com", []string{"first@example.com"}, []byte("Subject: Hello\r\n\r\nHello\r\n"))
+	if !errors.Is(err, tokenErr) {
+		t.Errorf("expecting token error, got %v", err)
+	}
+}
+
+type smtpServerOptions struct {
+	certificate tls.Certificate
+	implicitTLS bool
+	startTLS    bool
+	// mechanisms are advertised on EHLO, authentication is required before MAIL if it's not empty.
+	mechanisms []string
+	// rejectRecipients are refused on RCPT.
+	rejectRecipients []string
+}
+
+type receivedMessage struct {
+	from       string
+	recipients []string
+	data       string
+	tls        bool
+	auth       string
+}
+
+// smtpServer is a minimal SMTP server that accepts the credentials of smtpUsername.
+type smtpServer struct {
+	options  smtpServerOptions
+	listener net.Listener
+
+	mutex       sync.Mutex
+	received    []receivedMessage
+	connections int
+}
+
+func startSmtpServer(t *testing.T, options smtpServerOptions) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %s", err.Error())
+	}
+
+	server := &smtpServer{options: options, listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			server.mutex.Lock()
+			server.connections++
+			server.mutex.Unlock()
+
+			go server.serve(conn)
+		}
+	}()
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	return server
+}
+
+func (s *smtpServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpServer) messages() []receivedMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]receivedMessage(nil), s.received...)
+}
+
+func (s *smtpServer) connectionCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.connections
+}
+
+func (s *smtpServer) tlsConfig() *tls.Config {
+	return &tls.Config{Certificates: []tls.Certificate{s.options.certificate}}
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	isTLS := false
+	if s.options.implicitTLS {
+		conn = tls.Server(conn, s.tlsConfig())
+		isTLS = true
+	}
+
+	text := textproto.NewConn(conn)
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	reply := func(format string, args ...any) bool {
+		return text.PrintfLine(format, args...) == nil
+	}
+
+	if !reply("220 %s ESMTP", smtpServerName) {
+		return
+	}
+
+	var authenticated string
+	var message receivedMessage
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, argument, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{smtpServerName}
+			if s.options.startTLS && !isTLS {
+				lines = append(lines, "STARTTLS")
+			}
+			if len(s.options.mechanisms) > 0 {
+				lines = append(lines, "AUTH "+strings.Join(s.options.mechanisms, " "))
+			}
+			lines = append(lines, "8BITMIME")
+
+			for i, extension := range lines {
+				separator := "-"
+				if i == len(lines)-1 {
+					separator = " "
+				}
+				reply("250%s%s", separator, extension)
+			}
+		case "STARTTLS":
+			if !s.options.startTLS || isTLS {
+				reply("502 5.5.1 STARTTLS not available")
+				continue
+			}
+
+			reply("220 2.0.0 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig())
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			isTLS = true
+			authenticated = ""
+		case "AUTH":
+			mechanism, initialResponse, _ := strings.Cut(argument, " ")
+			mechanism = strings.ToUpper(mechanism)
+			if !slices.Contains(s.options.mechanisms, mechanism) {
+				reply("504 5.5.4 Unrecognized authentication type")
+				continue
+			}
+
+			if s.authenticate(text, mechanism, initialResponse) {
+				authenticated = mechanism
+				reply("235 2.7.0 Authentication successful")
+			} else {
+				reply("535 5.7.8 Authentication credentials invalid")
+			}
+		case "MAIL":
+			if len(s.options.mechanisms) > 0 && authenticated == "" {
+				reply("530 5.7.0 Authentication required")
+				continue
+			}
+
+			message = receivedMessage{from: trimPath(argument, "FROM:"), tls: isTLS, auth: authenticated}
+			reply("250 2.1.0 Ok")
+		case "RCPT":
+			if slices.Contains(s.options.rejectRecipients, trimPath(argument, "TO:")) {
+				reply("550 5.1.1 Mailbox unavailable")
+				continue
+			}
+
+			message.recipients = append(message.recipients, trimPath(argument, "TO:"))
+			reply("250 2.1.5 Ok")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			// ReadDotBytes converts the line endings to LF, restore them to compare the original message.
+			message.data = strings.ReplaceAll(string(data), "\n", "\r\n")
+			s.mutex.Lock()
+			s.received = append(s.received, message)
+			s.mutex.Unlock()
+			reply("250 2.0.0 Ok: queued")
+		case "RSET":
+			message = receivedMessage{}
+			reply("250 2.0.0 Ok")
+		case "NOOP":
+			reply("250 2.0.0 Ok")
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+// authenticate runs the SASL exchange of the mechanism and reports whether the credentials are valid.
+func (s *smtpServer) authenticate(text *textproto.Conn, mechanism string, initialResponse string) bool {
+	challenge := func(prompt string) (string, bool) {
+		if err := text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt))); err != nil {
+			return "", false
+		}
+
+		line, err := text.ReadLine()
+		if err != nil {
+			return "", false
+		}
+
+		response, err := base64.StdEncoding.DecodeString(line)
+		return string(response), err == nil
+	}
+
+	response := func(prompt string) (string, bool) {
+		if initialResponse == "" {
+			return challenge(prompt)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(initialResponse)
+		return string(decoded), err == nil
+	}
+
+	switch mechanism {
+	case "PLAIN":
+		credentials, ok := response("")
+		return ok && credentials == "\x00"+smtpUsername+"\x00"+smtpPassword
+	case "LOGIN":
+		username, ok := challenge("Username:")
+		if !ok {
+			return false
+		}
+
+		password, ok := challenge("Password:")
+		return ok && username == smtpUsername && password == smtpPassword
+	case "CRAM-MD5":
+		nonce := "<1896.697170952@" + smtpServerName + ">"
+		answer, ok := challenge(nonce)
+		if !ok {
+			return false
+		}
+
+		hash := hmac.New(md5.New, []byte(smtpPassword))
+		hash.Write([]byte(nonce))
+		return answer == smtpUsername+" "+hex.EncodeToString(hash.Sum(nil))
+	case "XOAUTH2":
+		credentials, ok := response("")
+		if !ok {
+			return false
+		}
+
+		if credentials == "user="+smtpUsername+"\x01auth=Bearer "+smtpToken+"\x01\x01" {
+			return true
+		}
+
+		// Mimic Gmail by sending the error as a challenge, the client answers with an empty line.
+		_, _ = challenge(`{"status":"401","schemes":"bearer","scope":"https://mail.google.com/"}`)
+		return false
+	default:
+		return false
+	}
+}
+
+func trimPath(argument string, prefix string) string {
+	argument = strings.TrimSpace(argument)
+	if len(argument) >= len(prefix) && strings.EqualFold(argument[:len(prefix)], prefix) {
+		argument = argument[len(prefix):]
+	}
+
+	path, _, _ := strings.Cut(strings.TrimSpace(argument), " ")
+	return strings.Trim(path, "<>")
+}
+
+func generateSelfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: smtpServerName},
+		DNSNames:              []string{smtpServerName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatalf("creating certificate: %s", err.Error())
+	}
+
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %s", err.Error())
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: privateKey, Leaf: parsed}, pool
+}
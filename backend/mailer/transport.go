@@ -0,0 +1,127 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transport delivers a rendered message to its recipients.
+type Transport interface {
+	Deliver(ctx context.Context, from string, recipients []string, message []byte) error
+}
+
+// Session delivers many messages over one connection. It's not safe for concurrent use.
+type Session interface {
+	Deliver(ctx context.Context, from string, recipients []string, message []byte) error
+	Close() error
+}
+
+// SessionTransport is a Transport that can keep a connection open for many messages, like SmtpTransport.
+type SessionTransport interface {
+	Transport
+	OpenSession(ctx context.Context) (Session, error)
+}
+
+// MaildirTransport writes every message as a file on a Maildir, for local development or to hand the
+// messages over to another program.
+type MaildirTransport struct {
+	path     string
+	hostname string
+}
+
+// NewMaildirTransport creates the `tmp`, `new`, and `cur` directories under the path if they don't exist.
+func NewMaildirTransport(path string) (*MaildirTransport, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	for _, directory := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(path, directory), 0o700)
+		if err != nil {
+			return nil, fmt.Errorf("creating maildir directory: %w", err)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &MaildirTransport{path: path, hostname: hostname}, nil
+}
+
+func (m *MaildirTransport) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	// Maildir requires the file to be written on tmp first, then moved to new once it's complete.
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + "." + uuid.NewString() + "." + m.hostname
+	temporaryPath := filepath.Join(m.path, "tmp", name)
+
+	file, err := os.OpenFile(temporaryPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating message file: %w", err)
+	}
+
+	_, err = file.Write(message)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(temporaryPath)
+		return fmt.Errorf("writing message file: %w", err)
+	}
+
+	err = os.Rename(temporaryPath, filepath.Join(m.path, "new", name))
+	if err != nil {
+		_ = os.Remove(temporaryPath)
+		return fmt.Errorf("moving message file: %w", err)
+	}
+
+	return nil
+}
+
+// DeliveredMessage is a message that has been delivered by MemoryTransport.
+type DeliveredMessage struct {
+	From       string
+	Recipients []string
+	Message    []byte
+}
+
+// MemoryTransport keeps every delivered message in memory. It's meant for tests.
+type MemoryTransport struct {
+	mutex    sync.Mutex
+	messages []DeliveredMessage
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (m *MemoryTransport) Deliver(ctx context.Context, from string, recipients []string, message []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, DeliveredMessage{
+		From:       from,
+		Recipients: slices.Clone(recipients),
+		Message:    slices.Clone(message),
+	})
+	return nil
+}
+
+// Messages returns the delivered messages in the order they are delivered.
+func (m *MemoryTransport) Messages() []DeliveredMessage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return slices.Clone(m.messages)
+}
@@ -0,0 +1,79 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"conf/mailer"
+)
+
+func TestMaildirTransport(t *testing.T) {
+	path := t.TempDir()
+	transport, err := mailer.NewMaildirTransport(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	sender := mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom)
+	err = sender.Send(context.Background(), &mailer.Mail{
+		RecipientName:  "John Doe",
+		RecipientEmail: "johndoe@example.com",
+		Subject:        "Maildir",
+		PlainTextBody:  "Hello from maildir",
+		HtmlBody:       "<p>Hello from maildir</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	entries, err := os.ReadDir(filepath.Join(path, "new"))
+	if err != nil {
+		t.Fatalf("reading maildir: %s", err.Error())
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expecting 1 message on new, got %d", len(entries))
+	}
+
+	content, err := os.ReadFile(filepath.Join(path, "new", entries[0].Name()))
+	if err != nil {
+		t.Fatalf("reading message: %s", err.Error())
+	}
+
+	if !bytes.Contains(content, []byte("Hello from maildir")) {
+		t.Errorf("expecting the message body on the file, got %q", content)
+	}
+
+	temporaryEntries, _ := os.ReadDir(filepath.Join(path, "tmp"))
+	if len(temporaryEntries) != 0 {
+		t.Errorf("expecting tmp to be empty, got %d entries", len(temporaryEntries))
+	}
+}
+
+func TestMemoryTransport(t *testing.T) {
+	transport := mailer.NewMemoryTransport()
+	sender := mailer.NewMailSenderWithTransport(transport, mailer.DefaultFrom)
+
+	for _, email := range []string{"first@example.com", "second@example.com"} {
+		err := sender.Send(context.Background(), &mailer.Mail{RecipientEmail: email, Subject: "Memory"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	messages := transport.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expecting 2 messages, got %d", len(messages))
+	}
+
+	if messages[1].Recipients[0] != "second@example.com" {
+		t.Errorf("expecting messages in delivery order, got %v", messages[1].Recipients)
+	}
+
+	if messages[0].From == "" {
+		t.Error("expecting envelope sender to be set")
+	}
+}
@@ -0,0 +1,190 @@
+package mailtemplate
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockElements start on a new line, and end with one.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Footer: true, atom.Form: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true,
+	atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Table: true, atom.Tr: true, atom.Ul: true,
+}
+
+// paragraphElements are separated from their siblings by an empty line.
+var paragraphElements = map[atom.Atom]bool{
+	atom.Blockquote: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Hr: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Table: true, atom.Ul: true,
+}
+
+// hiddenElements never show up on the text, along with their content.
+var hiddenElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Template: true, atom.Title: true,
+}
+
+// PlainText derives the plaintext alternative of an HTML mail. The text of the body is kept with its whitespace
+// collapsed, block elements are put on their own lines, list items are prefixed with a dash, and links are
+// followed by their target in brackets. Images are dropped.
+func PlainText(document string) string {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		// The parser accepts anything, it only fails on a broken reader.
+		return document
+	}
+
+	var writer plainTextWriter
+	writer.node(root)
+
+	return writer.String()
+}
+
+type plainTextWriter struct {
+	builder strings.Builder
+	// newlines is the number of newlines the next text should be preceded by.
+	newlines int
+	// space is set when whitespace was skipped since the last written text.
+	space bool
+	// pre is the number of pre elements the writer is in, their whitespace is kept as is.
+	pre int
+}
+
+func (w *plainTextWriter) String() string {
+	return w.builder.String()
+}
+
+func (w *plainTextWriter) breakLine(count int) {
+	if w.builder.Len() == 0 {
+		return
+	}
+
+	w.newlines = max(w.newlines, count)
+	w.space = false
+}
+
+// flush writes the pending newlines or space before the next text.
+func (w *plainTextWriter) flush() {
+	if w.newlines > 0 {
+		w.builder.WriteString(strings.Repeat("\n", w.newlines))
+	} else if w.space && w.builder.Len() > 0 {
+		w.builder.WriteByte(' ')
+	}
+
+	w.newlines = 0
+	w.space = false
+}
+
+func (w *plainTextWriter) write(text string) {
+	if text == "" {
+		return
+	}
+
+	w.flush()
+	w.builder.WriteString(text)
+}
+
+func (w *plainTextWriter) text(text string) {
+	if w.pre > 0 {
+		w.flush()
+		w.builder.WriteString(strings.TrimSuffix(text, "\n"))
+		return
+	}
+
+	if text != "" && isSpace(text[0]) {
+		w.space = true
+	}
+
+	for _, word := range strings.Fields(text) {
+		w.write(word)
+		w.space = true
+	}
+
+	if text != "" && !isSpace(text[len(text)-1]) {
+		w.space = false
+	}
+}
+
+func (w *plainTextWriter) node(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		w.text(node.Data)
+		return
+	case html.CommentNode, html.DoctypeNode:
+		return
+	case html.ElementNode:
+		if hiddenElements[node.DataAtom] {
+			return
+		}
+	}
+
+	switch node.DataAtom {
+	case atom.Br:
+		// Consecutive line breaks are kept.
+		if w.builder.Len() > 0 {
+			w.newlines++
+			w.space = false
+		}
+		return
+	case atom.Img:
+		return
+	case atom.Pre:
+		w.pre++
+		defer func() { w.pre-- }()
+	}
+
+	lines := 1
+	if paragraphElements[node.DataAtom] {
+		lines = 2
+	}
+
+	if blockElements[node.DataAtom] {
+		w.breakLine(lines)
+	}
+
+	if node.DataAtom == atom.Li {
+		w.write("-")
+		w.space = true
+	}
+
+	if node.DataAtom == atom.Td || node.DataAtom == atom.Th {
+		if node.PrevSibling != nil {
+			w.space = true
+		}
+	}
+
+	start := w.builder.Len()
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		w.node(child)
+	}
+
+	if node.DataAtom == atom.A {
+		href := strings.TrimSpace(attribute(node, "href"))
+		linkText := strings.TrimSpace(w.builder.String()[start:])
+		if href != "" && !strings.HasPrefix(href, "#") && linkText != href && linkText != strings.TrimPrefix(href, "mailto:") {
+			w.space = true
+			w.write("(" + href + ")")
+		}
+	}
+
+	if blockElements[node.DataAtom] {
+		w.breakLine(lines)
+	}
+}
+
+func attribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
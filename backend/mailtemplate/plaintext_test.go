@@ -0,0 +1,59 @@
+package mailtemplate_test
+
+import (
+	"testing"
+
+	"conf/mailtemplate"
+)
+
+func TestPlainText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name: "document",
+			html: `<!DOCTYPE html>
+<html>
+    <head>
+        <title>Ignored</title>
+        <style>* { color: red; }</style>
+    </head>
+    <body>
+        <h1>Hai   Aji!</h1>
+        <p>
+            Pembayaran kamu telah <b>di konfirmasi</b>,
+            sampai jumpa.
+        </p>
+        <!-- a comment -->
+        <p>Baris satu<br>baris dua<br><br>baris empat</p>
+    </body>
+</html>`,
+			expected: "Hai Aji!\n\nPembayaran kamu telah di konfirmasi, sampai jumpa.\n\nBaris satu\nbaris dua\n\nbaris empat",
+		},
+		{
+			name:     "links",
+			html:     `<p>Simpan ke <a href="https://pay.google.com/save/abc">Google Wallet</a> atau email <a href="mailto:conf@example.com">conf@example.com</a>, <a href="https://example.com">https://example.com</a> <a href="#top">atas</a></p>`,
+			expected: "Simpan ke Google Wallet (https://pay.google.com/save/abc) atau email conf@example.com, https://example.com atas",
+		},
+		{
+			name:     "lists and images",
+			html:     `<p>Rekening:</p><ul><li>BCA <i>123</i></li><li>Mandiri 456</li></ul><img src="cid:qr" alt="QR"><div>Terima kasih &amp; salam</div>`,
+			expected: "Rekening:\n\n- BCA 123\n- Mandiri 456\n\nTerima kasih & salam",
+		},
+		{
+			name:     "preformatted",
+			html:     "<p>Kode:</p><pre>  a\n    b</pre><p>Selesai</p>",
+			expected: "Kode:\n\n  a\n    b\n\nSelesai",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if text := mailtemplate.PlainText(testCase.html); text != testCase.expected {
+				t.Errorf("expecting %q, got %q", testCase.expected, text)
+			}
+		})
+	}
+}
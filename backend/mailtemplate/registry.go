@@ -0,0 +1,366 @@
+package mailtemplate
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"conf/i18n"
+	"github.com/flowchartsman/handlebars/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the transactional templates, they are always available on Builtin.
+const (
+	// Ticket is sent when the payment is approved, along with the QR code and the PDF ticket.
+	Ticket = "ticket"
+	// PaymentReceived is sent when a payment receipt is uploaded.
+	PaymentReceived = "payment_received"
+	// PaymentRejected is sent when the payment receipt is rejected, along with the reason.
+	PaymentRejected = "payment_rejected"
+	// EmailConfirmation is sent on registration, along with the link that confirms the email address.
+	EmailConfirmation = "email_confirmation"
+)
+
+//go:embed templates
+var builtin embed.FS
+
+// Builtin holds the templates that are compiled into the binary.
+var Builtin, _ = fs.Sub(builtin, "templates")
+
+// ErrTemplateNotFound is returned when rendering a name that's not on the registry.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// Rendered is a template rendered for a single recipient.
+type Rendered struct {
+	Subject       string
+	HtmlBody      string
+	PlainTextBody string
+}
+
+// Registry holds the named mail templates. The templates are handlebars files on the root of a file system:
+//
+//   - `<name>.html` is the template, with an optional YAML front matter between `---` lines that sets the
+//     `subject` and the `layout`. Without a front matter, the `<title>` of the HTML is the subject.
+//   - `<name>.txt` is the plaintext alternative. If it does not exist, it's derived from the rendered HTML.
+//   - `layouts/<layout>.html` wraps the rendered template, which is available as `{{{body}}}`.
+//   - `partials/<partial>.html` can be included on any template and layout with `{{> partial}}`.
+//
+// Subjects are templates too, the rendered subject is available to the template and its layout as
+// `{{subject}}`. Translated messages of the catalog are rendered with `{{t "key" name=value}}`, the hash
+// arguments fill the `{name}` placeholders of the message and are escaped. The locale being rendered is
+// available as `{{@locale}}`. A Registry is safe for concurrent use.
+type Registry struct {
+	templates map[string]*mailTemplate
+	variables map[string]any
+}
+
+type mailTemplate struct {
+	subject   *handlebars.Template
+	html      *handlebars.Template
+	plainText *handlebars.Template
+	layout    *handlebars.Template
+}
+
+type frontMatter struct {
+	Subject string `yaml:"subject"`
+	Layout  string `yaml:"layout"`
+}
+
+// Load parses the templates of every file system. A template, layout, or partial on a later file system
+// replaces the one with the same name on the earlier ones, so a directory can override some of the Builtin
+// templates while keeping the rest. The variables are available to every template, the data given to Render
+// takes precedence. The i18n.Builtin catalog is used if catalog is nil.
+func Load(variables map[string]any, catalog *i18n.Catalog, fileSystems ...fs.FS) (*Registry, error) {
+	if catalog == nil {
+		var err error
+		catalog, err = i18n.LoadCatalog(i18n.Builtin)
+		if err != nil {
+			return nil, fmt.Errorf("loading builtin catalog: %w", err)
+		}
+	}
+
+	sources := make(map[string]string)
+	plainTextSources := make(map[string]string)
+	layoutSources := make(map[string]string)
+	partialSources := make(map[string]string)
+
+	for _, fileSystem := range fileSystems {
+		if fileSystem == nil {
+			continue
+		}
+
+		err := readTemplates(fileSystem, ".", ".html", sources)
+		if err != nil {
+			return nil, err
+		}
+
+		// A later HTML without its own plaintext alternative drops the earlier one, so it's derived instead of
+		// getting out of sync.
+		plainTexts := make(map[string]string)
+		err = readTemplates(fileSystem, ".", ".txt", plainTexts)
+		if err != nil {
+			return nil, err
+		}
+
+		for name := range sources {
+			if plainText, ok := plainTexts[name]; ok {
+				plainTextSources[name] = plainText
+			} else if _, err := fs.Stat(fileSystem, name+".html"); err == nil {
+				delete(plainTextSources, name)
+			}
+		}
+
+		err = readTemplates(fileSystem, "layouts", ".html", layoutSources)
+		if err != nil {
+			return nil, err
+		}
+
+		err = readTemplates(fileSystem, "partials", ".html", partialSources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	partials := make(map[string]*handlebars.Template, len(partialSources))
+	for name, source := range partialSources {
+		partial, err := handlebars.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("parsing partial %s: %w", name, err)
+		}
+
+		partials[name] = partial
+	}
+
+	translate := translateHelper(catalog)
+	parse := func(source string) (*handlebars.Template, error) {
+		template, err := handlebars.Parse(source)
+		if err != nil {
+			return nil, err
+		}
+
+		// Partials are evaluated with the helpers of the template that includes them.
+		template.RegisterHelper("t", translate)
+		for name, partial := range partials {
+			template.RegisterPartialTemplate(name, partial)
+		}
+
+		return template, nil
+	}
+
+	layouts := make(map[string]*handlebars.Template, len(layoutSources))
+	for name, source := range layoutSources {
+		layout, err := parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("parsing layout %s: %w", name, err)
+		}
+
+		layouts[name] = layout
+	}
+
+	registry := &Registry{templates: make(map[string]*mailTemplate, len(sources)), variables: maps.Clone(variables)}
+	for name, source := range sources {
+		matter, body, err := splitFrontMatter(source)
+		if err != nil {
+			return nil, fmt.Errorf("parsing front matter of %s: %w", name, err)
+		}
+
+		var template mailTemplate
+		if matter.Layout != "" {
+			layout, ok := layouts[matter.Layout]
+			if !ok {
+				return nil, fmt.Errorf("template %s: layout %q does not exist", name, matter.Layout)
+			}
+
+			template.layout = layout
+		}
+
+		subject := matter.Subject
+		if subject == "" {
+			subject = htmlTitle(body)
+		}
+
+		template.subject, err = parse(subject)
+		if err != nil {
+			return nil, fmt.Errorf("parsing subject of %s: %w", name, err)
+		}
+
+		template.html, err = parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+
+		if plainText, ok := plainTextSources[name]; ok {
+			template.plainText, err = parse(plainText)
+			if err != nil {
+				return nil, fmt.Errorf("parsing plaintext of %s: %w", name, err)
+			}
+		}
+
+		registry.templates[name] = &template
+	}
+
+	return registry, nil
+}
+
+// readTemplates reads the files with the extension directly inside the directory, keyed by their name without
+// the extension. A missing directory is skipped.
+func readTemplates(fileSystem fs.FS, directory string, extension string, sources map[string]string) error {
+	entries, err := fs.ReadDir(fileSystem, directory)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", directory, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != extension {
+			continue
+		}
+
+		content, err := fs.ReadFile(fileSystem, path.Join(directory, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path.Join(directory, entry.Name()), err)
+		}
+
+		sources[strings.TrimSuffix(entry.Name(), extension)] = string(content)
+	}
+
+	return nil
+}
+
+// translateHelper renders the message of the locale on the `locale` private data. The message is trusted, only
+// the arguments are escaped.
+func translateHelper(catalog *i18n.Catalog) func(key string, options *handlebars.Options) handlebars.SafeString {
+	return func(key string, options *handlebars.Options) handlebars.SafeString {
+		hash := options.Hash()
+		args := make([]any, 0, len(hash)*2)
+		for name, value := range hash {
+			if safe, ok := value.(handlebars.SafeString); ok {
+				args = append(args, name, string(safe))
+				continue
+			}
+
+			args = append(args, name, handlebars.Escape(handlebars.Str(value)))
+		}
+
+		locale, _ := options.Data("locale").(i18n.Locale)
+		return handlebars.SafeString(catalog.Message(locale, key, args...))
+	}
+}
+
+func splitFrontMatter(source string) (frontMatter, string, error) {
+	var matter frontMatter
+	normalized := strings.ReplaceAll(source, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return matter, source, nil
+	}
+
+	header, body, ok := strings.Cut(strings.TrimPrefix(normalized, "---\n"), "\n---\n")
+	if !ok {
+		return matter, "", fmt.Errorf("front matter is not closed")
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(header)))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&matter)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return matter, "", err
+	}
+
+	return matter, body, nil
+}
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+func htmlTitle(source string) string {
+	match := titlePattern.FindStringSubmatch(source)
+	if match == nil {
+		return ""
+	}
+
+	return strings.Join(strings.Fields(match[1]), " ")
+}
+
+// Names lists the templates on the registry, sorted by name.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Has returns true if the template exists on the registry.
+func (r *Registry) Has(name string) bool {
+	_, ok := r.templates[name]
+	return ok
+}
+
+// Render renders the subject, the HTML body, and the plaintext body of the template in the locale, an
+// unsupported locale is rendered in i18n.Default. Values on data are escaped on the HTML body unless they
+// are referred to with triple braces.
+func (r *Registry) Render(name string, locale i18n.Locale, data map[string]any) (Rendered, error) {
+	template, ok := r.templates[name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	if parsed, ok := i18n.Parse(string(locale)); ok {
+		locale = parsed
+	} else {
+		locale = i18n.Default
+	}
+
+	privateData := handlebars.NewDataFrame()
+	privateData.Set("locale", locale)
+
+	values := make(map[string]any, len(r.variables)+len(data)+2)
+	maps.Copy(values, r.variables)
+	maps.Copy(values, data)
+
+	subject, err := template.subject.ExecWith(values, privateData)
+	if err != nil {
+		return Rendered{}, fmt.Errorf("rendering subject of %s: %w", name, err)
+	}
+	// The subject is a header, not HTML.
+	subject = strings.Join(strings.Fields(html.UnescapeString(subject)), " ")
+	values["subject"] = subject
+
+	htmlBody, err := template.html.ExecWith(values, privateData)
+	if err != nil {
+		return Rendered{}, fmt.Errorf("rendering %s: %w", name, err)
+	}
+
+	if template.layout != nil {
+		values["body"] = handlebars.SafeString(htmlBody)
+		htmlBody, err = template.layout.ExecWith(values, privateData)
+		if err != nil {
+			return Rendered{}, fmt.Errorf("rendering layout of %s: %w", name, err)
+		}
+	}
+
+	plainTextBody := PlainText(htmlBody)
+	if template.plainText != nil {
+		plainTextBody, err = template.plainText.ExecWith(values, privateData)
+		if err != nil {
+			return Rendered{}, fmt.Errorf("rendering plaintext of %s: %w", name, err)
+		}
+
+		plainTextBody = html.UnescapeString(plainTextBody)
+	}
+
+	return Rendered{Subject: subject, HtmlBody: htmlBody, PlainTextBody: plainTextBody}, nil
+}
@@ -0,0 +1,175 @@
+package mailtemplate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"conf/i18n"
+	"conf/mailtemplate"
+)
+
+func TestLoad_Builtin(t *testing.T) {
+	registry, err := mailtemplate.Load(map[string]any{"conferenceName": "TeknumConf 2024"}, nil, mailtemplate.Builtin)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for _, name := range []string{mailtemplate.Ticket, mailtemplate.PaymentReceived, mailtemplate.PaymentRejected, mailtemplate.EmailConfirmation} {
+		if !registry.Has(name) {
+			t.Errorf("expecting %s to be a builtin template", name)
+		}
+	}
+
+	rendered, err := registry.Render(mailtemplate.PaymentRejected, i18n.Indonesian, map[string]any{"reason": "<b>Nominal</b> kurang"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if rendered.Subject != "TeknumConf 2024: Bukti Pembayaran Anda Ditolak" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+
+	if !strings.Contains(rendered.HtmlBody, "<title>TeknumConf 2024: Bukti Pembayaran Anda Ditolak</title>") {
+		t.Errorf("expecting the layout to render the subject as the title, got %q", rendered.HtmlBody)
+	}
+
+	if !strings.Contains(rendered.HtmlBody, "&lt;b&gt;Nominal&lt;/b&gt; kurang") {
+		t.Errorf("expecting the reason to be escaped, got %q", rendered.HtmlBody)
+	}
+
+	if !strings.Contains(rendered.PlainTextBody, "Alasan: <b>Nominal</b> kurang") {
+		t.Errorf("expecting the reason as is on the plaintext body, got %q", rendered.PlainTextBody)
+	}
+
+	if !strings.Contains(rendered.PlainTextBody, "tidak mendaftar untuk TeknumConf 2024") {
+		t.Errorf("expecting the footer partial on the plaintext body, got %q", rendered.PlainTextBody)
+	}
+
+	if !strings.Contains(rendered.HtmlBody, `<html lang="id"`) {
+		t.Errorf("expecting the layout to render the locale, got %q", rendered.HtmlBody)
+	}
+
+	_, err = registry.Render("does_not_exist", i18n.Indonesian, nil)
+	if !errors.Is(err, mailtemplate.ErrTemplateNotFound) {
+		t.Errorf("expecting ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestRegistry_Render_Locale(t *testing.T) {
+	registry, err := mailtemplate.Load(map[string]any{"conferenceName": "TeknumConf 2024"}, nil, mailtemplate.Builtin)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	rendered, err := registry.Render(mailtemplate.PaymentReceived, i18n.English, map[string]any{"name": "Aji & Wah"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if rendered.Subject != "TeknumConf 2024: Payment Receipt Received" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+
+	for _, expect := range []string{`<html lang="en"`, "Hi, Aji &amp; Wah!", "If you did not register for TeknumConf 2024"} {
+		if !strings.Contains(rendered.HtmlBody, expect) {
+			t.Errorf("expecting the html body to contain %q, got %q", expect, rendered.HtmlBody)
+		}
+	}
+
+	// Unsupported locales fall back to the default one.
+	rendered, err = registry.Render(mailtemplate.Ticket, "fr-FR", map[string]any{"qrCodeContentId": "qr"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if rendered.Subject != "TeknumConf 2024: Tiket Anda!" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+
+	if !strings.Contains(rendered.HtmlBody, `alt="QR code tiket"`) {
+		t.Errorf("expecting the translated alt text, got %q", rendered.HtmlBody)
+	}
+}
+
+func TestLoad_Override(t *testing.T) {
+	directory := fstest.MapFS{
+		// Legacy templates of the emails directory, without a front matter.
+		"payment_success.html": {Data: []byte(`<html><head><title>{{conferenceName}} - Payment &amp; Confirmation</title></head><body><p>Hello, {{ name }}</p></body></html>`)},
+		"payment_success.txt":  {Data: []byte(`Hello, {{ name }}`)},
+		// Overrides the builtin template and partial, keeping the builtin layout.
+		"payment_rejected.html": {Data: []byte("---\nsubject: Ditolak\nlayout: default\n---\n<p>{{reason}}</p>")},
+		"partials/footer.html":  {Data: []byte(`<p>Footer {{conferenceName}}</p>`)},
+		"attendees-sample.csv":  {Data: []byte("name,email\n")},
+	}
+
+	registry, err := mailtemplate.Load(map[string]any{"conferenceName": "TeknumConf"}, nil, mailtemplate.Builtin, directory)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expectedNames := []string{"email_confirmation", "payment_received", "payment_rejected", "payment_success", "ticket"}
+	if names := registry.Names(); strings.Join(names, ",") != strings.Join(expectedNames, ",") {
+		t.Errorf("expecting names %v, got %v", expectedNames, names)
+	}
+
+	rendered, err := registry.Render("payment_success", i18n.Indonesian, map[string]any{"name": "Aji & Wah"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if rendered.Subject != "TeknumConf - Payment & Confirmation" {
+		t.Errorf("expecting the subject from the title, got %q", rendered.Subject)
+	}
+
+	if rendered.PlainTextBody != "Hello, Aji & Wah" {
+		t.Errorf("expecting the plaintext template, got %q", rendered.PlainTextBody)
+	}
+
+	rendered, err = registry.Render(mailtemplate.PaymentRejected, i18n.Indonesian, map[string]any{"reason": "Buram"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if rendered.Subject != "Ditolak" || rendered.PlainTextBody != "Buram\n\nFooter TeknumConf" {
+		t.Errorf("unexpected rendered template %+v", rendered)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{
+			name:  "unknown layout",
+			files: fstest.MapFS{"a.html": {Data: []byte("---\nlayout: fancy\n---\n<p>a</p>")}},
+		},
+		{
+			name:  "unclosed front matter",
+			files: fstest.MapFS{"a.html": {Data: []byte("---\nsubject: a\n<p>a</p>")}},
+		},
+		{
+			name:  "unknown front matter field",
+			files: fstest.MapFS{"a.html": {Data: []byte("---\ntitle: a\n---\n<p>a</p>")}},
+		},
+		{
+			name:  "syntax error",
+			files: fstest.MapFS{"a.html": {Data: []byte("<p>{{#if a}}</p>")}},
+		},
+		{
+			name:  "partial syntax error",
+			files: fstest.MapFS{"partials/b.html": {Data: []byte("{{/if}}")}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := mailtemplate.Load(nil, nil, testCase.files)
+			if err == nil {
+				t.Error("expecting an error, got nil")
+			}
+		})
+	}
+}
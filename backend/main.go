@@ -52,36 +52,124 @@ func App() *cli.App {
 						Name:     "subject",
 						Value:    "",
 						Usage:    "Email subject",
-						Required: true,
+						Required: false,
 					},
 					&cli.StringFlag{
 						Name:     "plaintext-body",
 						Value:    "",
 						Usage:    "Path to plaintext body file",
-						Required: true,
+						Required: false,
 					},
 					&cli.StringFlag{
 						Name:     "html-body",
 						Value:    "",
 						Usage:    "Path to HTML body file",
-						Required: true,
+						Required: false,
 					},
 					&cli.StringFlag{
 						Name:     "recipients",
 						Value:    "",
-						Usage:    "Path to CSV file containing list of emails",
+						Usage:    "Path to CSV file containing list of emails, every column is available to the templates",
 						Required: false,
 					},
+					&cli.StringSliceFlag{
+						Name:  "segment",
+						Usage: "Send to the recipients queried from the database, repeat it to combine segments: no-receipt, unpaid, paid, checked-in, speakers, or students",
+					},
+					&cli.StringFlag{
+						Name:  "delimiter",
+						Usage: "Field delimiter of the recipients CSV, use \"tab\" for tabs. Detected from the header when empty",
+					},
 					&cli.StringFlag{
 						Name:     "single-recipient",
 						Value:    "",
 						Required: false,
 					},
+					&cli.StringFlag{
+						Name:  "campaign",
+						Usage: "Campaign name on the send ledger, defaults to the subject in lowercase and dashes",
+					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Continue an existing campaign, skipping the recipients that were already sent",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Render every message to --dry-run-output as .eml files instead of sending them",
+					},
+					&cli.StringFlag{
+						Name:  "dry-run-output",
+						Value: "blast-dry-run",
+						Usage: "Directory of the rendered messages on a dry run",
+					},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "status",
+						Usage: "Show the sent, failed, and pending recipients of a campaign",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "campaign",
+								Usage:    "Campaign name",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "failures-csv",
+								Usage: "Path to export the failed recipients as CSV",
+							},
+						},
+						Action: BlastMailStatusHandlerAction,
+					},
 				},
 				Usage:     "blast-email [subject] [template-plaintext] [template-html-body] [csv-file list destination of emails]",
 				ArgsUsage: "[subject] [template-plaintext] [template-html-body] [path-csv-file]",
 				Action:    BlastMailHandlerAction,
 			},
+			{
+				Name:  "signature-keygen",
+				Usage: "Generate a new ticket signing key and print the configuration snippet",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "id",
+						Value:    "",
+						Usage:    "Key ID, defaults to today's date",
+						Required: false,
+					},
+				},
+				Action: SignatureKeygenHandlerAction,
+			},
+			{
+				Name:  "gate-bundle",
+				Usage: "Offline ticket verification bundle for the gates",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "export",
+						Usage: "Export a signed bundle of valid tickets",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "output",
+								Value:    "-",
+								Usage:    "Path to the bundle file, use - to write to stdout",
+								Required: false,
+							},
+						},
+						Action: GateBundleExportHandlerAction,
+					},
+					{
+						Name:  "reconcile",
+						Usage: "Record redemptions queued by the gates while offline",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "input",
+								Value:    "",
+								Usage:    "Path to JSON file containing list of redemptions",
+								Required: true,
+							},
+						},
+						Action: GateBundleReconcileHandlerAction,
+					},
+				},
+			},
 		},
 		Copyright: `   Copyright 2023 Teknologi Umum
 
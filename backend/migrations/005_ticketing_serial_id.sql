@@ -0,0 +1,48 @@
+-- +goose Up
+-- A single email might upload more than one payment receipt, the latest one wins.
+ALTER TABLE ticketing
+    DROP CONSTRAINT IF EXISTS ticketing_email_key;
+-- The UUID key is kept as legacy_id, so anything keyed on it can still be matched to its ticket. Existing tickets
+-- are numbered by upload time, and the sequence continues after them.
+ALTER TABLE ticketing
+    RENAME COLUMN id TO legacy_id;
+ALTER TABLE ticketing
+    DROP CONSTRAINT ticketing_pkey;
+ALTER TABLE ticketing
+    ALTER COLUMN legacy_id DROP NOT NULL;
+ALTER TABLE ticketing
+    ADD COLUMN id BIGSERIAL;
+UPDATE ticketing
+SET id = numbered.number
+FROM (SELECT legacy_id, ROW_NUMBER() OVER (ORDER BY created_at, legacy_id) AS number FROM ticketing) AS numbered
+WHERE ticketing.legacy_id = numbered.legacy_id;
+SELECT setval(pg_get_serial_sequence('ticketing', 'id'), COALESCE(MAX(id), 0) + 1, FALSE) FROM ticketing;
+ALTER TABLE ticketing
+    ADD PRIMARY KEY (id);
+CREATE UNIQUE INDEX IF NOT EXISTS ticketing_legacy_id_key ON ticketing (legacy_id);
+ALTER TABLE ticketing
+    ALTER COLUMN sha256sum TYPE TEXT USING encode(sha256sum, 'hex');
+CREATE INDEX IF NOT EXISTS ticketing_email_created_at_idx ON ticketing (email, created_at DESC);
+CREATE INDEX IF NOT EXISTS users_email_idx ON users (email);
+
+-- +goose Down
+DROP INDEX IF EXISTS users_email_idx;
+DROP INDEX IF EXISTS ticketing_email_created_at_idx;
+ALTER TABLE ticketing
+    ALTER COLUMN sha256sum TYPE BYTEA USING decode(sha256sum, 'hex');
+DROP INDEX IF EXISTS ticketing_legacy_id_key;
+ALTER TABLE ticketing
+    DROP CONSTRAINT ticketing_pkey;
+ALTER TABLE ticketing
+    DROP COLUMN id;
+ALTER TABLE ticketing
+    RENAME COLUMN legacy_id TO id;
+UPDATE ticketing
+SET id = gen_random_uuid()
+WHERE id IS NULL;
+ALTER TABLE ticketing
+    ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE ticketing
+    ADD PRIMARY KEY (id);
+ALTER TABLE ticketing
+    ADD CONSTRAINT ticketing_email_key UNIQUE (email);
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE ticketing
+    ADD COLUMN IF NOT EXISTS rejected BOOLEAN DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS rejection_reason TEXT NULL;
+
+-- +goose Down
+ALTER TABLE ticketing
+    DROP COLUMN rejected,
+    DROP COLUMN rejection_reason;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE ticketing
+    ADD COLUMN IF NOT EXISTS entitlements TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS ticket_redemptions
+(
+    ticket_id   BIGINT      NOT NULL REFERENCES ticketing (id) ON DELETE CASCADE,
+    checkpoint  VARCHAR(32) NOT NULL,
+    redeemed_at TIMESTAMP   NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ticket_id, checkpoint)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS ticket_redemptions;
+
+ALTER TABLE ticketing
+    DROP COLUMN entitlements;
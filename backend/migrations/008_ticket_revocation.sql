@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE ticketing
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS revoked BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE ticketing
+    DROP COLUMN version,
+    DROP COLUMN revoked;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Timestamps carry the time zone, the lease on claimed messages is compared against the worker's clock.
+CREATE TABLE IF NOT EXISTS mail_outbox
+(
+    id              VARCHAR(36) PRIMARY KEY,
+    sender          TEXT        NOT NULL,
+    recipients      TEXT        NOT NULL, -- JSON array of addresses
+    message         BYTEA       NOT NULL,
+    status          VARCHAR(16) NOT NULL,
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS mail_outbox_due_idx ON mail_outbox (next_attempt_at) WHERE status IN ('pending', 'sending');
+
+-- +goose Down
+DROP TABLE IF EXISTS mail_outbox;
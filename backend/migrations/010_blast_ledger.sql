@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS blast_ledger
+(
+    campaign        VARCHAR(255) NOT NULL,
+    recipient_email VARCHAR(255) NOT NULL,
+    recipient_name  VARCHAR(255) NOT NULL DEFAULT '',
+    status          VARCHAR(16)  NOT NULL,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    last_error      TEXT         NOT NULL DEFAULT '',
+    updated_at      TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (campaign, recipient_email)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS blast_ledger;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(16) NOT NULL DEFAULT 'id';
+
+ALTER TABLE ticketing
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(16) NOT NULL DEFAULT 'id';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN locale;
+
+ALTER TABLE ticketing DROP COLUMN locale;
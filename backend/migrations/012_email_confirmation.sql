@@ -0,0 +1,11 @@
+-- +goose Up
+-- Existing users registered before the double opt-in, they stay eligible for the payment instructions. NocoDB
+-- entries without the EmailConfirmed column count as confirmed the same way, see user.NocoDBRepository.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_confirmed BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE users
+    ALTER COLUMN email_confirmed SET DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN email_confirmed;
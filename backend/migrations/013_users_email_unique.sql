@@ -0,0 +1,37 @@
+-- +goose Up
+-- Registrations made before the email normalization may differ only by case. The earliest entry stays, it's the one
+-- the lookups already return, and the later ones are moved to users_duplicates as they are so someone can resolve
+-- them by hand, e.g. a speaker entry shadowed by a participant one.
+CREATE TABLE IF NOT EXISTS users_duplicates
+(
+    LIKE users INCLUDING DEFAULTS
+);
+
+INSERT INTO users_duplicates
+SELECT *
+FROM users
+WHERE EXISTS (SELECT 1
+              FROM users AS earlier
+              WHERE lower(earlier.email) = lower(users.email)
+                AND earlier.id < users.id);
+
+DELETE
+FROM users
+WHERE id IN (SELECT id FROM users_duplicates);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
+
+-- The last time the confirmation link was mailed, so registering again does not mail it over and over.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS confirmation_sent_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN confirmation_sent_at;
+
+DROP INDEX IF EXISTS users_email_lower_key;
+
+INSERT INTO users
+SELECT *
+FROM users_duplicates;
+
+DROP TABLE IF EXISTS users_duplicates;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Tickets are looked up by email case-insensitively, the same way as the users are.
+CREATE INDEX IF NOT EXISTS ticketing_email_lower_created_at_idx ON ticketing (lower(email), created_at DESC);
+
+DROP INDEX IF EXISTS ticketing_email_created_at_idx;
+
+-- +goose Down
+CREATE INDEX IF NOT EXISTS ticketing_email_created_at_idx ON ticketing (email, created_at DESC);
+
+DROP INDEX IF EXISTS ticketing_email_lower_created_at_idx;
@@ -0,0 +1,189 @@
+package migrations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// advisoryLockId guards the migration process so multiple instances starting at the same time
+// won't execute the same migration twice.
+const advisoryLockId = 7_165_738_101
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+}
+
+// Up applies every pending migration file within this directory in order. Each migration runs on its own
+// transaction. Applied versions are recorded on the `goose_db_version` table, so it stays compatible with
+// databases that were migrated using the goose CLI before.
+func Up(ctx context.Context, db *sql.DB) error {
+	migrations, err := parse()
+	if err != nil {
+		return fmt.Errorf("parsing migration files: %w", err)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockId)
+	if err != nil {
+		return fmt.Errorf("acquiring advisory lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockId)
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS goose_db_version (
+		id         SERIAL PRIMARY KEY,
+		version_id BIGINT  NOT NULL,
+		is_applied BOOLEAN NOT NULL,
+		tstamp     TIMESTAMP NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating version table: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("beginning transaction for %s: %w", m.name, err)
+		}
+
+		_, err = tx.ExecContext(ctx, m.up)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("executing %s: %w", m.name, err)
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)", m.version)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("recording %s: %w", m.name, err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return fmt.Errorf("committing %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version_id, is_applied FROM goose_db_version ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("querying applied versions: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	// Goose appends a new row on every up and down, the latest row of a version wins.
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, fmt.Errorf("scanning applied version: %w", err)
+		}
+
+		applied[version] = isApplied
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating applied versions: %w", err)
+	}
+
+	return applied, nil
+}
+
+func parse() ([]migration, error) {
+	entries, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, name := range entries {
+		rawVersion, _, found := strings.Cut(path.Base(name), "_")
+		if !found {
+			return nil, fmt.Errorf("%s: missing version prefix", name)
+		}
+
+		version, err := strconv.ParseInt(rawVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version prefix: %w", name, err)
+		}
+
+		content, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    name,
+			up:      upSection(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// upSection takes everything between the `-- +goose Up` and `-- +goose Down` annotations.
+func upSection(content []byte) string {
+	var up strings.Builder
+	inUp := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			inUp = true
+			continue
+		case "-- +goose Down":
+			inUp = false
+			continue
+		}
+
+		if inUp {
+			up.WriteString(line)
+			up.WriteString("\n")
+		}
+	}
+
+	return up.String()
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	migrations, err := parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("expecting migrations to be found, got none")
+	}
+
+	for i, m := range migrations {
+		if i > 0 && migrations[i-1].version >= m.version {
+			t.Errorf("expecting %s to be ordered after %s", m.name, migrations[i-1].name)
+		}
+
+		if strings.TrimSpace(m.up) == "" {
+			t.Errorf("expecting %s to have an up section", m.name)
+		}
+	}
+}
+
+func TestUpSection(t *testing.T) {
+	content := `-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE a (id INT);
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE a;
+`
+
+	up := upSection([]byte(content))
+	if !strings.Contains(up, "CREATE TABLE a") {
+		t.Errorf("expecting up section to contain the create statement, got %q", up)
+	}
+
+	if strings.Contains(up, "DROP TABLE a") {
+		t.Errorf("expecting up section to exclude the down statement, got %q", up)
+	}
+}
@@ -1,12 +1,8 @@
 package nocodb
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"net/url"
 )
 
 // CreateTableRecords allows the creation of new records within a specified table. Records to be inserted are input as
@@ -19,47 +15,24 @@ import (
 // Certain read-only field types will be disregarded if included in the request. These field types include 'Look Up,'
 // 'Roll Up,' 'Formula,' 'Auto Number,' 'Created By,' 'Updated By,' 'Created At,' 'Updated At,' 'Barcode,' and 'QR Code.'
 func (c *Client) CreateTableRecords(ctx context.Context, tableId string, records []any) error {
-	requestUrl, err := url.Parse(c.baseUrl + "/api/v2/tables/" + tableId + "/records")
-	if err != nil {
-		return fmt.Errorf("parsing url: %w", err)
-	}
-
-	requestBody, err := json.Marshal(records)
-	if err != nil {
-		return fmt.Errorf("marshaling records: %w", err)
-	}
+	_, err := c.createTableRecords(ctx, tableId, records)
+	return err
+}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestUrl.String(), bytes.NewReader(requestBody))
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+// createTableRecords creates the records and returns their IDs, in the order of the records.
+func (c *Client) createTableRecords(ctx context.Context, tableId string, records any) ([]int64, error) {
+	var response []struct {
+		Id int64 `json:"Id"`
 	}
-
-	request.Header.Add("xc-auth", c.apiToken)
-	request.Header.Add("Content-Type", "application/json")
-
-	response, err := c.httpClient.Do(request)
+	err := c.do(ctx, http.MethodPost, "/api/v2/tables/"+tableId+"/records", nil, records, &response)
 	if err != nil {
-		return fmt.Errorf("executing http request: %w", err)
+		return nil, err
 	}
-	defer func() {
-		if response.Body != nil {
-			err := response.Body.Close()
-			if err != nil {
-				if c.logger != nil {
-					_, _ = c.logger.Write([]byte("Closing response body: " + err.Error()))
-				}
-			}
-		}
-	}()
 
-	if response.StatusCode == 400 {
-		var badRequestError BadRequestError
-		err = json.NewDecoder(response.Body).Decode(&badRequestError)
-		if err != nil {
-			return fmt.Errorf("unmarshaling bad request error: %w", err)
-		}
-		return badRequestError
+	ids := make([]int64, 0, len(response))
+	for _, entry := range response {
+		ids = append(ids, entry.Id)
 	}
 
-	return nil
+	return ids, nil
 }
@@ -1,5 +1,7 @@
 package nocodb
 
+import "errors"
+
 type BadRequestError struct {
 	Message string `json:"msg"`
 }
@@ -7,3 +9,6 @@ type BadRequestError struct {
 func (b BadRequestError) Error() string {
 	return b.Message
 }
+
+// ErrRecordNotFound is returned when NocoDB does not have the requested record.
+var ErrRecordNotFound = errors.New("record not found")
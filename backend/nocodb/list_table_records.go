@@ -2,8 +2,6 @@ package nocodb
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -33,8 +31,9 @@ type ListTableRecordOptions struct {
 	// Please remember to maintain the specified format, and do not include spaces between the different
 	// condition components
 	//
-	// SDK implementation note: I am too lazy to create a proper struct that can make the where clause
-	// easy, so I'll leave this to the users.
+	// Build the clause with Filter instead of formatting it by hand, so the values are escaped:
+	//
+	//	Where: nocodb.And(nocodb.Eq("Email", email), nocodb.Eq("Used", false)).String()
 	Where string
 	// Offset enables you to control the pagination of your API response by specifying the number of records you
 	// want to skip from the beginning of the result set. The default value for this parameter is set to 0, meaning
@@ -64,6 +63,7 @@ type ListTableRecordOptions struct {
 }
 
 type listTableRecordsResponse struct {
+	// List holds the caller's output pointer, so the records are decoded straight into it.
 	List     any      `json:"list"`
 	PageInfo PageInfo `json:"pageInfo"`
 }
@@ -76,7 +76,7 @@ type listTableRecordsResponse struct {
 //
 // Note: `out` parameter MUST BE a pointer to a struct array.
 func (c *Client) ListTableRecords(ctx context.Context, tableId string, out any, options ListTableRecordOptions) (PageInfo, error) {
-	queryParams := &url.Values{}
+	queryParams := url.Values{}
 	if len(options.Fields) > 0 {
 		queryParams.Set("fields", strings.Join(options.Fields, ","))
 	}
@@ -98,66 +98,17 @@ func (c *Client) ListTableRecords(ctx context.Context, tableId string, out any,
 	}
 
 	if options.Limit > 0 {
-		queryParams.Set("limit", strconv.FormatInt(options.Offset, 10))
+		queryParams.Set("limit", strconv.FormatInt(options.Limit, 10))
 	}
 
 	if options.ViewId != "" {
 		queryParams.Set("viewId", options.ViewId)
 	}
 
-	requestUrl, err := url.Parse(c.baseUrl + "/api/v2/tables/" + tableId + "/records")
-	if err != nil {
-		return PageInfo{}, fmt.Errorf("parsing url: %w", err)
-	}
-
-	requestUrl.RawQuery = queryParams.Encode()
-
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl.String(), nil)
-	if err != nil {
-		return PageInfo{}, fmt.Errorf("creating request: %w", err)
-	}
-
-	request.Header.Add("xc-auth", c.apiToken)
-
-	response, err := c.httpClient.Do(request)
-	if err != nil {
-		return PageInfo{}, fmt.Errorf("executing http request: %w", err)
-	}
-	defer func() {
-		if response.Body != nil {
-			err := response.Body.Close()
-			if err != nil {
-				if c.logger != nil {
-					_, _ = c.logger.Write([]byte("Closing response body: " + err.Error()))
-				}
-			}
-		}
-	}()
-
-	if response.StatusCode == 400 {
-		var badRequestError BadRequestError
-		err = json.NewDecoder(response.Body).Decode(&badRequestError)
-		if err != nil {
-			return PageInfo{}, fmt.Errorf("unmarshaling bad request error: %w", err)
-		}
-		return PageInfo{}, badRequestError
-	}
-
-	var responseBody listTableRecordsResponse
-	err = json.NewDecoder(response.Body).Decode(&responseBody)
-	if err != nil {
-		return PageInfo{}, fmt.Errorf("deserializing json: %w", err)
-	}
-
-	// Re-marshal list response, unmarshal to request output
-	marshalledList, err := json.Marshal(responseBody.List)
-	if err != nil {
-		return PageInfo{}, fmt.Errorf("re-marshalling list: %w", err)
-	}
-
-	err = json.Unmarshal(marshalledList, out)
+	responseBody := listTableRecordsResponse{List: out}
+	err := c.do(ctx, http.MethodGet, "/api/v2/tables/"+tableId+"/records", queryParams, nil, &responseBody)
 	if err != nil {
-		return PageInfo{}, fmt.Errorf("unmarshalling list: %w", err)
+		return PageInfo{}, err
 	}
 
 	return responseBody.PageInfo, nil
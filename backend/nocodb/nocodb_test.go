@@ -126,3 +126,64 @@ func TestIntegration(t *testing.T) {
 		t.Errorf("expecting Age to be updated to 320, got %d", anotherOutPayload.Age)
 	}
 }
+
+func TestListTableRecords_Pagination(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	pagedTableId := tableId
+	if os.Getenv("NOCODB_BASE_URL") == "" {
+		pagedTableId = "paged"
+	}
+
+	randomBytes := make([]byte, 16)
+	rand.Read(randomBytes)
+	randomText := base64.StdEncoding.EncodeToString(randomBytes)
+
+	var payloads []any
+	for i := 0; i < 7; i++ {
+		payloads = append(payloads, testBody{Title: "Page " + strconv.Itoa(i), Age: i, RandomText: randomText})
+	}
+
+	err := client.CreateTableRecords(ctx, pagedTableId, payloads)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var titles []string
+	var offset int64
+	for {
+		var outPayload []testBody
+		pageInfo, err := client.ListTableRecords(ctx, pagedTableId, &outPayload, nocodb.ListTableRecordOptions{
+			Where:  "(RandomText,eq," + randomText + ")",
+			Offset: offset,
+			Limit:  3,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(outPayload) > 3 {
+			t.Fatalf("expecting at most 3 records on a page, got %d", len(outPayload))
+		}
+
+		for _, out := range outPayload {
+			titles = append(titles, out.Title)
+		}
+		offset += int64(len(outPayload))
+
+		if pageInfo.IsLastPage || len(outPayload) == 0 {
+			break
+		}
+	}
+
+	if len(titles) != len(payloads) {
+		t.Fatalf("expecting %d records across the pages, got %v", len(payloads), titles)
+	}
+
+	for i, title := range titles {
+		if title != "Page "+strconv.Itoa(i) {
+			t.Errorf("expecting record %d to be Page %d, got %s", i, i, title)
+		}
+	}
+}
@@ -2,58 +2,48 @@ package nocodbmock
 
 import (
 	"errors"
+	"slices"
 	"sync"
 )
 
 var errNotFound = errors.New("not found")
-var errInvalidType = errors.New("invalid type")
 
 type storage struct {
-	m *sync.Map
+	mu     sync.RWMutex
+	tables map[string][]map[string]any
+	// lastIds keeps the last assigned Id of each table, so the Ids of deleted records are not reused.
+	lastIds map[string]int64
 }
 
 func newInMemoryStorage() *storage {
-	return &storage{m: &sync.Map{}}
+	return &storage{tables: make(map[string][]map[string]any), lastIds: make(map[string]int64)}
 }
 
+// GetByTableId returns a copy of every record on the table, so the caller can read it
+// without holding the lock.
 func (s *storage) GetByTableId(tableId string) (records []map[string]any, err error) {
-	value, ok := s.m.Load(tableId)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.tables[tableId]
 	if !ok {
 		return nil, errNotFound
 	}
 
-	v, ok := value.([]map[string]any)
-	if !ok {
-		return nil, errInvalidType
+	for _, record := range v {
+		records = append(records, copyRecord(record))
 	}
 
-	return v, nil
+	return records, nil
 }
 
 func (s *storage) GetByRecordId(tableId string, recordId int64) (record map[string]any, err error) {
-	records, err := s.GetByTableId(tableId)
-	if err != nil {
-		return nil, err
-	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	for _, record := range records {
-		var id int64
-		switch v := record["Id"].(type) {
-		case float64:
-			id = int64(v)
-			break
-		case float32:
-			id = int64(v)
-			break
-		case int:
-			id = int64(v)
-			break
-		case int64:
-			id = v
-			break
-		}
-		if recordId == id {
-			return record, nil
+	for _, record := range s.tables[tableId] {
+		if recordId == toInt64(record["Id"]) {
+			return copyRecord(record), nil
 		}
 	}
 
@@ -61,15 +51,11 @@ func (s *storage) GetByRecordId(tableId string, recordId int64) (record map[stri
 }
 
 func (s *storage) Insert(tableId string, records []map[string]any) (ids []int64, err error) {
-	var lastRecordId int64 = 0
-	if oldRecords, err := s.GetByTableId(tableId); err == nil {
-		lastIndex := len(oldRecords) - 1
-		if v, ok := oldRecords[lastIndex]["Id"]; ok {
-			if i, ok := v.(int64); ok {
-				lastRecordId = i
-			}
-		}
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldRecords := s.tables[tableId]
+	lastRecordId := s.lastIds[tableId]
 
 	var recordIds []int64
 	for i := 0; i < len(records); i++ {
@@ -79,14 +65,18 @@ func (s *storage) Insert(tableId string, records []map[string]any) (ids []int64,
 		lastRecordId++
 	}
 
-	s.m.Store(tableId, records)
+	s.tables[tableId] = append(oldRecords, records...)
+	s.lastIds[tableId] = lastRecordId
 	return recordIds, nil
 }
 
 func (s *storage) Update(tableId string, records []map[string]any) (ids []int64, err error) {
-	oldRecords, err := s.GetByTableId(tableId)
-	if err != nil {
-		return nil, err
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldRecords, ok := s.tables[tableId]
+	if !ok {
+		return nil, errNotFound
 	}
 
 	var recordIds []int64
@@ -94,38 +84,62 @@ func (s *storage) Update(tableId string, records []map[string]any) (ids []int64,
 	for i := 0; i < len(oldRecords); i++ {
 		// I know that this is O(n^2) but because this is a mock, I don't really care
 		for _, record := range records {
-			var recordId int64
-			switch v := record["Id"].(type) {
-			case float64:
-				recordId = int64(v)
-				break
-			case float32:
-				recordId = int64(v)
-				break
-			case int:
-				recordId = int64(v)
-				break
-			case int64:
-				recordId = v
-				break
-			}
+			recordId := toInt64(record["Id"])
 
-			if oldRecords[i]["Id"] == recordId {
+			if toInt64(oldRecords[i]["Id"]) == recordId {
 				// Found one
 				for key, value := range record {
 					oldRecords[i][key] = value
 				}
+				oldRecords[i]["Id"] = recordId
 
-				id, ok := record["Id"].(int64)
-				if ok {
-					recordIds = append(recordIds, id)
-				}
-
+				recordIds = append(recordIds, recordId)
 				break
 			}
 		}
 	}
 
-	s.m.Store(tableId, oldRecords)
 	return recordIds, nil
 }
+
+// Delete removes the records, nothing is removed if any of them does not exist.
+func (s *storage) Delete(tableId string, recordIds []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldRecords := s.tables[tableId]
+	for _, recordId := range recordIds {
+		if !slices.ContainsFunc(oldRecords, func(record map[string]any) bool { return toInt64(record["Id"]) == recordId }) {
+			return errNotFound
+		}
+	}
+
+	s.tables[tableId] = slices.DeleteFunc(oldRecords, func(record map[string]any) bool {
+		return slices.Contains(recordIds, toInt64(record["Id"]))
+	})
+	return nil
+}
+
+func copyRecord(record map[string]any) map[string]any {
+	out := make(map[string]any, len(record))
+	for key, value := range record {
+		out[key] = value
+	}
+
+	return out
+}
+
+func toInt64(value any) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case float32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	}
+
+	return 0
+}
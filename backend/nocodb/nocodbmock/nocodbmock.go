@@ -46,16 +46,38 @@ func NewNocoDBMockServer() (*httptest.Server, error) {
 			return
 		}
 
+		where, err := parseWhere(r.URL.Query().Get("where"))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(errorResponse{Message: "BadRequest [ERROR]: " + err.Error()})
+			return
+		}
+
+		records = filterRecords(records, where)
+		sortRecords(records, r.URL.Query().Get("sort"))
+
+		offset, limit, err := parsePagination(r.URL.Query())
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(errorResponse{Message: "BadRequest [ERROR]: " + err.Error()})
+			return
+		}
+
+		totalRows := int64(len(records))
+		page := records[min(offset, totalRows):min(offset+limit, totalRows)]
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(listTableResponse{
-			List: records,
+			List: page,
 			PageInfo: nocodb.PageInfo{
-				TotalRows:   int64(len(records)),
-				Page:        1,
-				PageSize:    1,
-				IsFirstPage: true,
-				IsLastPage:  true,
+				TotalRows:   totalRows,
+				Page:        offset/limit + 1,
+				PageSize:    limit,
+				IsFirstPage: offset == 0,
+				IsLastPage:  offset+limit >= totalRows,
 			},
 		})
 		return
@@ -163,8 +185,8 @@ func NewNocoDBMockServer() (*httptest.Server, error) {
 		record, err := documentStorage.GetByRecordId(tableId, recordIdAsInt64)
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(errorResponse{Message: "BadRequest [ERROR]: " + err.Error()})
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(errorResponse{Message: "Record '" + recordId + "' not found"})
 			return
 		}
 
@@ -174,6 +196,48 @@ func NewNocoDBMockServer() (*httptest.Server, error) {
 		return
 	})
 
+	r.Delete("/api/v2/tables/{tableId}/records", func(w http.ResponseWriter, r *http.Request) {
+		tableId := chi.URLParam(r, "tableId")
+		if tableId == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(errorResponse{Message: "BadRequest [ERROR]: tableId is empty"})
+			return
+		}
+
+		var records []map[string]any
+		err := json.NewDecoder(r.Body).Decode(&records)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(errorResponse{Message: "BadRequest [ERROR]: Invalid request body"})
+			return
+		}
+
+		var recordIds []int64
+		for _, record := range records {
+			recordIds = append(recordIds, toInt64(record["Id"]))
+		}
+
+		err = documentStorage.Delete(tableId, recordIds)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(errorResponse{Message: "Record not found"})
+			return
+		}
+
+		var response []creationSuccessfulResponse
+		for _, id := range recordIds {
+			response = append(response, creationSuccessfulResponse{ID: id})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	})
+
 	server := httptest.NewServer(r)
 
 	return server, nil
@@ -0,0 +1,358 @@
+package nocodbmock
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filter is a parsed where clause.
+type filter interface {
+	match(record map[string]any) bool
+}
+
+type condition struct {
+	field    string
+	operator string
+	values   []string
+}
+
+// group evaluates its filters from left to right, operators[i] joins filters[i] and filters[i+1].
+type group struct {
+	filters   []filter
+	operators []string
+}
+
+// parseWhere parses a subset of NocoDB's where clause: conditions and parenthesized groups joined by `~and` or
+// `~or`, e.g. `(Email,eq,john@example.com)~and((Used,eq,false)~or(Id,gt,10))`. Values can be double-quoted with
+// `"` and `\` escaped by a backslash. Only the `eq`, `neq`, `gt`, `like`, `in` and `is` operators are supported.
+func parseWhere(where string) (filter, error) {
+	if where == "" {
+		return nil, nil
+	}
+
+	p := &whereParser{input: where}
+	out, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.position != len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at %d", p.input[p.position:], p.position)
+	}
+
+	return out, nil
+}
+
+type whereParser struct {
+	input    string
+	position int
+}
+
+func (p *whereParser) expression() (filter, error) {
+	first, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	out := group{filters: []filter{first}}
+	for {
+		var operator string
+		switch {
+		case strings.HasPrefix(p.input[p.position:], "~and"):
+			operator = "and"
+		case strings.HasPrefix(p.input[p.position:], "~or"):
+			operator = "or"
+		default:
+			return out, nil
+		}
+		p.position += len(operator) + 1
+
+		next, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		out.filters = append(out.filters, next)
+		out.operators = append(out.operators, operator)
+	}
+}
+
+func (p *whereParser) term() (filter, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+
+	if p.peek() == '(' {
+		out, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		return out, p.expect(')')
+	}
+
+	field, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	operator, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	c := condition{field: field, operator: operator}
+	for p.peek() == ',' {
+		p.position++
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		c.values = append(c.values, value)
+	}
+
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+
+	switch c.operator {
+	case "eq", "neq", "gt", "like", "is":
+		if len(c.values) != 1 {
+			return nil, fmt.Errorf("operator %s expects a single value, got %d", c.operator, len(c.values))
+		}
+	case "in":
+		if len(c.values) == 0 {
+			return nil, fmt.Errorf("operator in expects at least one value")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operator: %s", c.operator)
+	}
+
+	if c.operator == "is" && c.values[0] != "null" && c.values[0] != "notnull" {
+		return nil, fmt.Errorf("unsupported value for operator is: %s", c.values[0])
+	}
+
+	return c, nil
+}
+
+// value reads either a double-quoted string or the raw characters up to the next `,` or `)`.
+func (p *whereParser) value() (string, error) {
+	if p.peek() != '"' {
+		end := strings.IndexAny(p.input[p.position:], ",)")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated condition at %d", p.position)
+		}
+
+		out := p.input[p.position : p.position+end]
+		if strings.ContainsAny(out, `(~"\`) {
+			return "", fmt.Errorf("unquoted special character in %q", out)
+		}
+
+		p.position += end
+		return out, nil
+	}
+
+	var builder strings.Builder
+	for p.position++; p.position < len(p.input); p.position++ {
+		switch character := p.input[p.position]; character {
+		case '\\':
+			p.position++
+			if p.position >= len(p.input) {
+				return "", fmt.Errorf("unterminated escape sequence")
+			}
+
+			builder.WriteByte(p.input[p.position])
+		case '"':
+			p.position++
+			return builder.String(), nil
+		default:
+			builder.WriteByte(character)
+		}
+	}
+
+	return "", fmt.Errorf("unterminated quoted value")
+}
+
+func (p *whereParser) peek() byte {
+	if p.position >= len(p.input) {
+		return 0
+	}
+
+	return p.input[p.position]
+}
+
+func (p *whereParser) expect(character byte) error {
+	if p.peek() != character {
+		return fmt.Errorf("expecting %q at %d of %q", character, p.position, p.input)
+	}
+
+	p.position++
+	return nil
+}
+
+func (g group) match(record map[string]any) bool {
+	matched := g.filters[0].match(record)
+	for i, operator := range g.operators {
+		if operator == "and" {
+			matched = matched && g.filters[i+1].match(record)
+		} else {
+			matched = matched || g.filters[i+1].match(record)
+		}
+	}
+
+	return matched
+}
+
+func (c condition) match(record map[string]any) bool {
+	switch c.operator {
+	case "neq":
+		return !equals(record[c.field], c.values[0])
+	case "gt":
+		return record[c.field] != nil && compare(record[c.field], c.values[0]) > 0
+	case "like":
+		pattern := strings.ToLower(strings.Trim(c.values[0], "%"))
+		return strings.Contains(strings.ToLower(stringify(record[c.field])), pattern)
+	case "in":
+		for _, value := range c.values {
+			if equals(record[c.field], value) {
+				return true
+			}
+		}
+
+		return false
+	case "is":
+		isNull := stringify(record[c.field]) == ""
+		return isNull == (c.values[0] == "null")
+	default:
+		return equals(record[c.field], c.values[0])
+	}
+}
+
+func equals(field any, value string) bool {
+	// NocoDB treats missing boolean fields as false
+	if field == nil && value == "false" {
+		return true
+	}
+
+	return stringify(field) == value
+}
+
+func filterRecords(records []map[string]any, where filter) []map[string]any {
+	if where == nil {
+		return records
+	}
+
+	var out []map[string]any
+	for _, record := range records {
+		if where.match(record) {
+			out = append(out, record)
+		}
+	}
+
+	return out
+}
+
+// sortRecords sorts the records by a comma separated list of field names, a `-` prefix means descending.
+func sortRecords(records []map[string]any, sortParam string) {
+	if sortParam == "" {
+		return
+	}
+
+	fields := strings.Split(sortParam, ",")
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, field := range fields {
+			descending := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+
+			comparison := compare(records[i][field], records[j][field])
+			if comparison == 0 {
+				continue
+			}
+
+			if descending {
+				return comparison > 0
+			}
+
+			return comparison < 0
+		}
+
+		return false
+	})
+}
+
+// defaultPageSize is NocoDB's page size when the request does not set a limit.
+const defaultPageSize = 25
+
+// parsePagination reads the `offset` and `limit` query parameters the way NocoDB does, a missing limit
+// falls back to defaultPageSize.
+func parsePagination(query url.Values) (offset int64, limit int64, err error) {
+	limit = defaultPageSize
+
+	if value := query.Get("offset"); value != "" {
+		offset, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset: %s", value)
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit: %s", value)
+		}
+	}
+
+	return offset, limit, nil
+}
+
+func compare(a, b any) int {
+	aString, bString := stringify(a), stringify(b)
+
+	aTime, aErr := time.Parse(time.RFC3339Nano, aString)
+	bTime, bErr := time.Parse(time.RFC3339Nano, bString)
+	if aErr == nil && bErr == nil {
+		return aTime.Compare(bTime)
+	}
+
+	aNumber, aErr := strconv.ParseFloat(aString, 64)
+	bNumber, bErr := strconv.ParseFloat(bString, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case aNumber < bNumber:
+			return -1
+		case aNumber > bNumber:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(aString, bString)
+}
+
+func stringify(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
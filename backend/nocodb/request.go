@@ -0,0 +1,83 @@
+package nocodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// do sends a request to the NocoDB API, body is encoded as JSON if it's not nil. The JSON response is decoded into
+// out if it's not nil, a 400 response is returned as BadRequestError and a 404 response as ErrRecordNotFound.
+func (c *Client) do(ctx context.Context, method string, path string, queryParams url.Values, body any, out any) error {
+	requestUrl, err := url.Parse(c.baseUrl + path)
+	if err != nil {
+		return fmt.Errorf("parsing url: %w", err)
+	}
+
+	requestUrl.RawQuery = queryParams.Encode()
+
+	var requestBody io.Reader
+	if body != nil {
+		marshaledBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshaling records: %w", err)
+		}
+
+		requestBody = bytes.NewReader(marshaledBody)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestUrl.String(), requestBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	request.Header.Add("xc-auth", c.apiToken)
+	if body != nil {
+		request.Header.Add("Content-Type", "application/json")
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("executing http request: %w", err)
+	}
+	defer func() {
+		if response.Body != nil {
+			err := response.Body.Close()
+			if err != nil {
+				if c.logger != nil {
+					_, _ = c.logger.Write([]byte("Closing response body: " + err.Error()))
+				}
+			}
+		}
+	}()
+
+	switch {
+	case response.StatusCode == http.StatusBadRequest:
+		var badRequestError BadRequestError
+		err = json.NewDecoder(response.Body).Decode(&badRequestError)
+		if err != nil {
+			return fmt.Errorf("unmarshaling bad request error: %w", err)
+		}
+		return badRequestError
+	case response.StatusCode == http.StatusNotFound:
+		return ErrRecordNotFound
+	case response.StatusCode >= 300:
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("unexpected status code %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(response.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("decoding response body: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package nocodb
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+)
+
+// Table is a typed view of a single NocoDB table. T is the JSON shape of a record, usually a struct whose field
+// names or `json` tags match the table's field names.
+type Table[T any] struct {
+	client  *Client
+	tableId string
+}
+
+func NewTable[T any](client *Client, tableId string) (*Table[T], error) {
+	if client == nil {
+		return nil, fmt.Errorf("client is nil")
+	}
+
+	if tableId == "" {
+		return nil, fmt.Errorf("tableId is empty")
+	}
+
+	return &Table[T]{client: client, tableId: tableId}, nil
+}
+
+// List returns a single page of records, see ListTableRecords.
+func (t *Table[T]) List(ctx context.Context, options ListTableRecordOptions) ([]T, PageInfo, error) {
+	var records []T
+	pageInfo, err := t.client.ListTableRecords(ctx, t.tableId, &records, options)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	return records, pageInfo, nil
+}
+
+// All streams every record that matches the options, fetching the pages as the iteration goes. It starts from
+// options.Offset, and options.Limit is the size of each page. The iteration stops after yielding the first error.
+func (t *Table[T]) All(ctx context.Context, options ListTableRecordOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			records, pageInfo, err := t.List(ctx, options)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, record := range records {
+				if !yield(record, nil) {
+					return
+				}
+			}
+
+			if pageInfo.IsLastPage || len(records) == 0 {
+				return
+			}
+
+			options.Offset += int64(len(records))
+		}
+	}
+}
+
+// Get returns a single record by its ID, or ErrRecordNotFound if there is none.
+func (t *Table[T]) Get(ctx context.Context, id int64, options ReadTableRecordsOptions) (T, error) {
+	var record T
+	err := t.client.ReadTableRecords(ctx, t.tableId, strconv.FormatInt(id, 10), &record, options)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return record, nil
+}
+
+// Create inserts the records and returns their IDs, in the same order as the records.
+func (t *Table[T]) Create(ctx context.Context, records ...T) ([]int64, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	return t.client.createTableRecords(ctx, t.tableId, records)
+}
+
+// Update patches the records, matched by their `Id` field. Only the fields present on the JSON encoding of a record
+// are updated, see UpdateTableRecords.
+func (t *Table[T]) Update(ctx context.Context, records ...T) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return t.client.do(ctx, http.MethodPatch, "/api/v2/tables/"+t.tableId+"/records", nil, records, nil)
+}
+
+// Delete removes the records by their IDs.
+func (t *Table[T]) Delete(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	body := make([]map[string]int64, 0, len(ids))
+	for _, id := range ids {
+		body = append(body, map[string]int64{"Id": id})
+	}
+
+	return t.client.do(ctx, http.MethodDelete, "/api/v2/tables/"+t.tableId+"/records", nil, body, nil)
+}
@@ -0,0 +1,168 @@
+package nocodb_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"conf/nocodb"
+)
+
+func TestTable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	typedTableId := tableId
+	if os.Getenv("NOCODB_BASE_URL") == "" {
+		typedTableId = "typed"
+	}
+
+	table, err := nocodb.NewTable[testBody](client, typedTableId)
+	if err != nil {
+		t.Fatalf("creating table: %s", err.Error())
+	}
+
+	randomText := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	var records []testBody
+	for i := 0; i < 12; i++ {
+		records = append(records, testBody{Title: "Record " + strconv.Itoa(i), Age: i, RandomText: randomText})
+	}
+
+	ids, err := table.Create(ctx, records...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(ids) != len(records) {
+		t.Fatalf("expecting %d ids, got %v", len(records), ids)
+	}
+
+	options := nocodb.ListTableRecordOptions{
+		Where: nocodb.Eq("RandomText", randomText).String(),
+		Sort:  []nocodb.Sort{nocodb.SortAscending("Age")},
+		Limit: 5,
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		record, err := table.Get(ctx, ids[3], nocodb.ReadTableRecordsOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if record.Id != ids[3] || record.Title != "Record 3" {
+			t.Errorf("expecting Record 3, got %+v", record)
+		}
+
+		_, err = table.Get(ctx, ids[len(ids)-1]+1000, nocodb.ReadTableRecordsOptions{})
+		if !errors.Is(err, nocodb.ErrRecordNotFound) {
+			t.Errorf("expecting ErrRecordNotFound, got %v", err)
+		}
+	})
+
+	t.Run("List returns a single page", func(t *testing.T) {
+		page, pageInfo, err := table.List(ctx, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(page) != 5 || pageInfo.IsLastPage {
+			t.Errorf("expecting the first 5 records and more pages, got %d records and %+v", len(page), pageInfo)
+		}
+	})
+
+	t.Run("All streams every page", func(t *testing.T) {
+		var ages []int
+		for record, err := range table.All(ctx, options) {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			ages = append(ages, record.Age)
+		}
+
+		if len(ages) != len(records) {
+			t.Fatalf("expecting %d records, got %v", len(records), ages)
+		}
+
+		for i, age := range ages {
+			if age != i {
+				t.Errorf("expecting record %d to have age %d, got %d", i, i, age)
+			}
+		}
+	})
+
+	t.Run("All stops when the loop breaks", func(t *testing.T) {
+		var count int
+		for _, err := range table.All(ctx, options) {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			count++
+			if count == 7 {
+				break
+			}
+		}
+
+		if count != 7 {
+			t.Errorf("expecting 7 records, got %d", count)
+		}
+	})
+
+	t.Run("All yields the error", func(t *testing.T) {
+		var count int
+		for _, err := range table.All(ctx, nocodb.ListTableRecordOptions{Where: "(RandomText,unknown,x)"}) {
+			count++
+			if err == nil {
+				t.Error("expecting an error, got nil")
+			}
+		}
+
+		if count != 1 {
+			t.Errorf("expecting a single error, got %d iterations", count)
+		}
+	})
+
+	t.Run("Update and Delete", func(t *testing.T) {
+		err := table.Update(ctx, testBody{Id: ids[0], Title: "Updated", Age: 100, RandomText: randomText})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		record, err := table.Get(ctx, ids[0], nocodb.ReadTableRecordsOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if record.Title != "Updated" || record.Age != 100 {
+			t.Errorf("expecting the record to be updated, got %+v", record)
+		}
+
+		err = table.Delete(ctx, ids[0], ids[1])
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = table.Get(ctx, ids[0], nocodb.ReadTableRecordsOptions{})
+		if !errors.Is(err, nocodb.ErrRecordNotFound) {
+			t.Errorf("expecting ErrRecordNotFound, got %v", err)
+		}
+
+		var count int
+		for _, err := range table.All(ctx, options) {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			count++
+		}
+
+		if count != len(records)-2 {
+			t.Errorf("expecting %d records after the deletion, got %d", len(records)-2, count)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package nocodb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter is a typed condition for ListTableRecordOptions.Where, build one with Eq, Ne, Gt, Like, In, IsNull, And
+// and Or, then pass its String() as the Where option.
+//
+// Field names and values are escaped, a value like `x)~or(Id,gt,0` is compared as is instead of changing the
+// clause. Values that contain any of `(),~"\` or surrounding spaces are written as a double-quoted string, with
+// `"` and `\` escaped by a backslash, the rest are written as they are.
+type Filter interface {
+	// String returns the where clause, it's empty for an empty And or Or.
+	String() string
+}
+
+// condition is an implementation of Filter for a single comparison.
+type condition struct {
+	field    string
+	operator string
+	values   []string
+}
+
+func (c condition) String() string {
+	var builder strings.Builder
+	builder.WriteString("(")
+	builder.WriteString(quoteWhereValue(c.field))
+	builder.WriteString(",")
+	builder.WriteString(c.operator)
+	for _, value := range c.values {
+		builder.WriteString(",")
+		builder.WriteString(quoteWhereValue(value))
+	}
+	builder.WriteString(")")
+
+	return builder.String()
+}
+
+// group is an implementation of Filter that joins other filters with a logical operator.
+type group struct {
+	operator string
+	filters  []Filter
+}
+
+func (g group) String() string {
+	var clauses []string
+	for _, filter := range g.filters {
+		if filter == nil {
+			continue
+		}
+
+		clause := filter.String()
+		if clause == "" {
+			continue
+		}
+
+		// Nested groups are parenthesized, so `~and` and `~or` never mix on the same level.
+		if nested, ok := filter.(group); ok && nested.size() > 1 {
+			clause = "(" + clause + ")"
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return strings.Join(clauses, "~"+g.operator)
+}
+
+// size returns the number of non-empty filters on the group.
+func (g group) size() int {
+	var size int
+	for _, filter := range g.filters {
+		if filter != nil && filter.String() != "" {
+			size++
+		}
+	}
+
+	return size
+}
+
+// Eq matches the records whose field equals the value.
+func Eq(field string, value any) Filter {
+	return condition{field: field, operator: "eq", values: []string{formatWhereValue(value)}}
+}
+
+// Ne matches the records whose field does not equal the value.
+func Ne(field string, value any) Filter {
+	return condition{field: field, operator: "neq", values: []string{formatWhereValue(value)}}
+}
+
+// Gt matches the records whose field is greater than the value.
+func Gt(field string, value any) Filter {
+	return condition{field: field, operator: "gt", values: []string{formatWhereValue(value)}}
+}
+
+// Like matches the records whose field contains the value, case-insensitively. `%` on the value is a wildcard.
+func Like(field string, value string) Filter {
+	return condition{field: field, operator: "like", values: []string{value}}
+}
+
+// In matches the records whose field equals any of the values, at least one value is required.
+func In[T any](field string, values ...T) Filter {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, formatWhereValue(value))
+	}
+
+	return condition{field: field, operator: "in", values: formatted}
+}
+
+// IsNull matches the records whose field is empty.
+func IsNull(field string) Filter {
+	return condition{field: field, operator: "is", values: []string{"null"}}
+}
+
+// And matches the records that match every filter, nil and empty filters are skipped.
+func And(filters ...Filter) Filter {
+	return group{operator: "and", filters: filters}
+}
+
+// Or matches the records that match any of the filters, nil and empty filters are skipped.
+func Or(filters ...Filter) Filter {
+	return group{operator: "or", filters: filters}
+}
+
+func formatWhereValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func quoteWhereValue(value string) string {
+	if !strings.ContainsAny(value, `(),~"\`) && strings.TrimSpace(value) == value {
+		return value
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,134 @@
+package nocodb_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"conf/nocodb"
+)
+
+func TestFilter_String(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   nocodb.Filter
+		expected string
+	}{
+		{name: "eq", filter: nocodb.Eq("Email", "john@example.com"), expected: "(Email,eq,john@example.com)"},
+		{name: "ne bool", filter: nocodb.Ne("Used", true), expected: "(Used,neq,true)"},
+		{name: "gt int", filter: nocodb.Gt("Id", int64(10)), expected: "(Id,gt,10)"},
+		{name: "like", filter: nocodb.Like("Name", "%doe%"), expected: "(Name,like,%doe%)"},
+		{name: "in", filter: nocodb.In("Id", 1, 2, 3), expected: "(Id,in,1,2,3)"},
+		{name: "is null", filter: nocodb.IsNull("DeletedAt"), expected: "(DeletedAt,is,null)"},
+		{name: "and", filter: nocodb.And(nocodb.Eq("A", 1), nocodb.Eq("B", 2)), expected: "(A,eq,1)~and(B,eq,2)"},
+		{
+			name:     "nested groups are parenthesized",
+			filter:   nocodb.And(nocodb.Eq("A", 1), nocodb.Or(nocodb.Eq("B", 2), nocodb.Eq("C", 3))),
+			expected: "(A,eq,1)~and((B,eq,2)~or(C,eq,3))",
+		},
+		{
+			name:     "empty and nil filters are skipped",
+			filter:   nocodb.And(nil, nocodb.Or(), nocodb.Or(nocodb.Eq("A", 1)), nocodb.Eq("B", 2)),
+			expected: "(A,eq,1)~and(B,eq,2)",
+		},
+		{name: "empty", filter: nocodb.And(), expected: ""},
+		{
+			name:     "clause injection",
+			filter:   nocodb.Eq("Email", "x)~or(Id,gt,0"),
+			expected: `(Email,eq,"x)~or(Id,gt,0")`,
+		},
+		{name: "comma", filter: nocodb.Eq("Name", "Doe, John"), expected: `(Name,eq,"Doe, John")`},
+		{name: "quote and backslash", filter: nocodb.Eq("Name", `a"b\c`), expected: `(Name,eq,"a\"b\\c")`},
+		{name: "trailing quote escape", filter: nocodb.Eq("Name", `\`), expected: `(Name,eq,"\\")`},
+		{name: "surrounding spaces", filter: nocodb.Eq("Name", " John "), expected: `(Name,eq," John ")`},
+		{name: "in with special values", filter: nocodb.In("Name", "a,b", "c"), expected: `(Name,in,"a,b",c)`},
+		{name: "field name", filter: nocodb.Eq("Weird,Field", 1), expected: `("Weird,Field",eq,1)`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := testCase.filter.String(); got != testCase.expected {
+				t.Errorf("expecting %s, got %s", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilter_AdversarialValues(t *testing.T) {
+	if os.Getenv("NOCODB_BASE_URL") != "" {
+		t.Skip("the table schema is only known to the mock server")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	const filterTableId = "filters"
+	titles := []string{
+		"john@example.com",
+		"x)~or(Title,neq,",
+		"Doe, John",
+		`quote " and \ backslash`,
+		`ends with \`,
+		"  padded  ",
+		"~and(Age,eq,1)",
+		"((()))",
+		"",
+	}
+
+	var payloads []any
+	for i, title := range titles {
+		payloads = append(payloads, testBody{Title: title, Age: i})
+	}
+
+	err := client.CreateTableRecords(ctx, filterTableId, payloads)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for i, title := range titles {
+		t.Run(title, func(t *testing.T) {
+			var out []testBody
+			_, err := client.ListTableRecords(ctx, filterTableId, &out, nocodb.ListTableRecordOptions{
+				Where: nocodb.Eq("Title", title).String(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(out) != 1 || out[0].Age != i {
+				t.Errorf("expecting only record %d, got %+v", i, out)
+			}
+
+			out = nil
+			_, err = client.ListTableRecords(ctx, filterTableId, &out, nocodb.ListTableRecordOptions{
+				Where: nocodb.And(nocodb.Eq("Title", title), nocodb.Ne("Age", i)).String(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(out) != 0 {
+				t.Errorf("expecting no record, got %+v", out)
+			}
+		})
+	}
+
+	t.Run("Or, In and Gt", func(t *testing.T) {
+		var out []testBody
+		_, err := client.ListTableRecords(ctx, filterTableId, &out, nocodb.ListTableRecordOptions{
+			Where: nocodb.Or(
+				nocodb.In("Title", titles[1], titles[2]),
+				nocodb.And(nocodb.Gt("Age", 6), nocodb.Like("Title", "(()")),
+			).String(),
+			Sort: []nocodb.Sort{nocodb.SortAscending("Age")},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(out) != 3 || out[0].Age != 1 || out[1].Age != 2 || out[2].Age != 7 {
+			t.Errorf("expecting records 1, 2 and 7, got %+v", out)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"conf/ticketing"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type AdministratorUploadRedemptionsRequest struct {
+	Redemptions []ticketing.OfflineRedemption `json:"redemptions"`
+}
+
+func (s *ServerDependency) AdministratorExportGateBundle(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	bundle, err := s.ticketDomain.ExportGateBundle(r.Context())
+	if err != nil {
+		sentry.GetHubFromContext(r.Context()).CaptureException(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="gate-bundle.json"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bundle)
+	return
+}
+
+func (s *ServerDependency) AdministratorUploadRedemptions(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	var requestBody AdministratorUploadRedemptionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_request_body"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	report, err := s.ticketDomain.ReconcileRedemptions(r.Context(), requestBody.Redemptions)
+	if err != nil {
+		sentry.GetHubFromContext(r.Context()).CaptureException(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"message":    s.message(r, "redemptions_reconciled"),
+		"accepted":   report.Accepted,
+		"conflicts":  report.Conflicts,
+		"request_id": requestId,
+	})
+	return
+}
@@ -29,7 +29,7 @@ func (s *ServerDependency) AdministratorLogin(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Invalid request body",
+			"message":    s.message(r, "invalid_request_body"),
 			"errors":     err.Error(),
 			"request_id": requestId,
 		})
@@ -42,8 +42,8 @@ func (s *ServerDependency) AdministratorLogin(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
 			"request_id": requestId,
 		})
 		return
@@ -53,7 +53,7 @@ func (s *ServerDependency) AdministratorLogin(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Invalid authentication",
+			"message":    s.message(r, "invalid_authentication"),
 			"request_id": requestId,
 		})
 		return
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"conf/blast"
 	"conf/mailer"
 	"github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi/v5/middleware"
@@ -15,6 +16,8 @@ type AdministratorMailBlastRequest struct {
 	PlaintextBody string                                   `json:"plaintextBody"`
 	HtmlBody      string                                   `json:"htmlBody"`
 	Recipients    []AdministratorMailBlastRecipientRequest `json:"recipients"`
+	// Segments adds the recipients queried from the database, see blast.Segments for the available names.
+	Segments []string `json:"segments"`
 }
 
 type AdministratorMailBlastRecipientRequest struct {
@@ -22,6 +25,14 @@ type AdministratorMailBlastRecipientRequest struct {
 	Name  string `json:"name"`
 }
 
+// AdministratorMailBlastResultResponse is the delivery result of a recipient, Status is either "sent" or
+// "failed".
+type AdministratorMailBlastResultResponse struct {
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
 func (s *ServerDependency) AdministratorMailBlast(w http.ResponseWriter, r *http.Request) {
 	requestId := middleware.GetReqID(r.Context())
 	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
@@ -37,7 +48,7 @@ func (s *ServerDependency) AdministratorMailBlast(w http.ResponseWriter, r *http
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Invalid request body",
+			"message":    s.message(r, "invalid_request_body"),
 			"errors":     err.Error(),
 			"request_id": requestId,
 		})
@@ -51,8 +62,8 @@ func (s *ServerDependency) AdministratorMailBlast(w http.ResponseWriter, r *http
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
 			"request_id": requestId,
 		})
 		return
@@ -62,35 +73,92 @@ func (s *ServerDependency) AdministratorMailBlast(w http.ResponseWriter, r *http
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Invalid authentication",
+			"message":    s.message(r, "invalid_authentication"),
 			"request_id": requestId,
 		})
 		return
 	}
 
-	var unsuccessfulDestinations []string
+	var segments []blast.Segment
+	for _, name := range requestBody.Segments {
+		segment, err := blast.ParseSegment(name)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"message":    s.message(r, "invalid_segment"),
+				"errors":     err.Error(),
+				"request_id": requestId,
+			})
+			return
+		}
+
+		segments = append(segments, segment)
+	}
+
+	recipients := make([]blast.Recipient, 0, len(requestBody.Recipients))
 	for _, recipient := range requestBody.Recipients {
-		mail := &mailer.Mail{
+		recipients = append(recipients, blast.Recipient{Name: recipient.Name, Email: recipient.Email})
+	}
+
+	if len(segments) > 0 {
+		segmentRecipients, err := s.segmentResolver.Resolve(r.Context(), segments)
+		if err != nil {
+			sentry.GetHubFromContext(r.Context()).CaptureException(err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"message":    s.message(r, "internal_server_error"),
+				"errors":     s.message(r, "internal_server_error"),
+				"request_id": requestId,
+			})
+			return
+		}
+
+		recipients = append(recipients, segmentRecipients...)
+	}
+
+	recipients = blast.Deduplicate(recipients)
+
+	mails := make([]*mailer.Mail, 0, len(recipients))
+	for _, recipient := range recipients {
+		mails = append(mails, &mailer.Mail{
 			RecipientName:  recipient.Name,
 			RecipientEmail: recipient.Email,
 			Subject:        requestBody.Subject,
 			PlainTextBody:  strings.ReplaceAll(requestBody.PlaintextBody, "___REPLACE_WITH_NAME___", recipient.Name),
 			HtmlBody:       strings.ReplaceAll(requestBody.HtmlBody, "___REPLACE_WITH_NAME___", recipient.Name),
-		}
+		})
+	}
 
-		err := s.mailSender.Send(r.Context(), mail)
-		if err != nil {
-			unsuccessfulDestinations = append(unsuccessfulDestinations, recipient.Email)
-			sentry.GetHubFromContext(r.Context()).CaptureException(err)
+	report := s.bulkMailSender.SendAll(r.Context(), mails, nil)
+
+	var unsuccessfulDestinations []string
+	results := make([]AdministratorMailBlastResultResponse, 0, len(report.Results))
+	for _, result := range report.Results {
+		if result.Err != nil {
+			unsuccessfulDestinations = append(unsuccessfulDestinations, result.RecipientEmail)
+			sentry.GetHubFromContext(r.Context()).CaptureException(result.Err)
+			results = append(results, AdministratorMailBlastResultResponse{
+				Email:  result.RecipientEmail,
+				Status: "failed",
+				Error:  result.Err.Error(),
+			})
 			continue
 		}
+
+		results = append(results, AdministratorMailBlastResultResponse{
+			Email:  result.RecipientEmail,
+			Status: "sent",
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"message":                   "Done",
+		"message":                   s.message(r, "done"),
 		"unsuccessful_destinations": unsuccessfulDestinations,
+		"results":                   results,
 		"request_id":                requestId,
 	})
 	return
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"conf/ticketing"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// receiptUrlExpiry limits how long the signed receipt URL on the review queue can be opened.
+const receiptUrlExpiry = time.Minute * 15
+
+type AdministratorApprovePaymentRequest struct {
+	// Entitlements lists the checkpoint IDs the ticket can be redeemed on. Leave it empty for a general
+	// admission ticket.
+	Entitlements []string `json:"entitlements"`
+}
+
+type AdministratorRejectPaymentRequest struct {
+	Reason string `json:"reason"`
+}
+
+// validateAdministrator writes the error response and returns false if the request does not carry a valid
+// administrator token.
+func (s *ServerDependency) validateAdministrator(w http.ResponseWriter, r *http.Request, requestId string) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	_, ok, err := s.administratorDomain.Validate(r.Context(), token)
+	if err != nil {
+		sentry.GetHubFromContext(r.Context()).CaptureException(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
+			"request_id": requestId,
+		})
+		return false
+	}
+
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_authentication"),
+			"request_id": requestId,
+		})
+		return false
+	}
+
+	return true
+}
+
+func (s *ServerDependency) AdministratorListPayments(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	pendingPayments, err := s.ticketDomain.ListPendingPayments(r.Context(), receiptUrlExpiry)
+	if err != nil {
+		sentry.GetHubFromContext(r.Context()).CaptureException(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	payments := make([]map[string]any, 0, len(pendingPayments))
+	for _, pendingPayment := range pendingPayments {
+		payments = append(payments, map[string]any{
+			"id":          pendingPayment.Id,
+			"email":       pendingPayment.Email,
+			"student":     pendingPayment.Student,
+			"receipt_url": pendingPayment.ReceiptUrl,
+			"created_at":  pendingPayment.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"payments":   payments,
+		"request_id": requestId,
+	})
+	return
+}
+
+func (s *ServerDependency) AdministratorApprovePayment(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	ticketId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_payment_id"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	// The request body is optional, an empty body approves a general admission ticket.
+	var requestBody AdministratorApprovePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_request_body"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	sum, err := s.ticketDomain.ApprovePaymentReceipt(r.Context(), ticketId, requestBody.Entitlements)
+	if err != nil {
+		s.writePaymentReviewError(w, r, requestId, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    s.message(r, "payment_approved"),
+		"sha256sum":  sum,
+		"request_id": requestId,
+	})
+	return
+}
+
+func (s *ServerDependency) AdministratorRejectPayment(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	ticketId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_payment_id"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	var requestBody AdministratorRejectPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_request_body"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	err = s.ticketDomain.RejectPaymentReceipt(r.Context(), ticketId, requestBody.Reason)
+	if err != nil {
+		s.writePaymentReviewError(w, r, requestId, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    s.message(r, "payment_rejected"),
+		"request_id": requestId,
+	})
+	return
+}
+
+func (s *ServerDependency) writePaymentReviewError(w http.ResponseWriter, r *http.Request, requestId string, err error) {
+	var validationError ticketing.ValidationError
+	if errors.As(err, &validationError) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "validation_error"),
+			"errors":     validationError.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	if errors.Is(err, ticketing.ErrInvalidTicket) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "payment_not_found"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	if errors.Is(err, ticketing.ErrPaymentAlreadyReviewed) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "payment_already_reviewed"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	sentry.GetHubFromContext(r.Context()).CaptureException(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    s.message(r, "internal_server_error"),
+		"errors":     s.message(r, "internal_server_error"),
+		"request_id": requestId,
+	})
+}
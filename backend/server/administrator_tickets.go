@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"conf/ticketing"
+	"conf/user"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func (s *ServerDependency) AdministratorRevokeTicket(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	ticketId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_ticket_id"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	err = s.ticketDomain.RevokeTicket(r.Context(), ticketId)
+	if err != nil {
+		s.writeTicketError(w, r, requestId, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    s.message(r, "ticket_revoked"),
+		"request_id": requestId,
+	})
+	return
+}
+
+func (s *ServerDependency) AdministratorReissueTicket(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	ticketId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_ticket_id"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	sum, err := s.ticketDomain.ReissueTicket(r.Context(), ticketId)
+	if err != nil {
+		s.writeTicketError(w, r, requestId, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    s.message(r, "ticket_reissued"),
+		"sha256sum":  sum,
+		"request_id": requestId,
+	})
+	return
+}
+
+func (s *ServerDependency) AdministratorDownloadTicketPdf(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	if !s.featureFlag.EnableAdministratorMode {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.validateAdministrator(w, r, requestId) {
+		return
+	}
+
+	ticketId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_ticket_id"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	ticket, err := s.ticketDomain.GetIssuedTicket(r.Context(), ticketId)
+	if err != nil {
+		s.writeTicketError(w, r, requestId, err)
+		return
+	}
+
+	// The attendee name is printed if we can find it, the ticket is still valid without it.
+	attendee, err := s.userDomain.GetUserByEmail(r.Context(), ticket.Email)
+	if err != nil && !errors.Is(err, user.ErrUserEmailNotFound) {
+		s.writeTicketError(w, r, requestId, err)
+		return
+	}
+
+	ticketPdf, err := s.ticketDomain.RenderTicketPdf(ticket, attendee.Name)
+	if err != nil {
+		s.writeTicketError(w, r, requestId, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ticketing.TicketPdfContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"ticket-%d.pdf\"", ticket.Id))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(ticketPdf)
+	return
+}
+
+func (s *ServerDependency) writeTicketError(w http.ResponseWriter, r *http.Request, requestId string, err error) {
+	if errors.Is(err, ticketing.ErrInvalidTicket) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "ticket_not_found"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	sentry.GetHubFromContext(r.Context()).CaptureException(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    s.message(r, "internal_server_error"),
+		"errors":     s.message(r, "internal_server_error"),
+		"request_id": requestId,
+	})
+}
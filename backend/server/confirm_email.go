@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"conf/user"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (s *ServerDependency) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	requestBody := ConfirmEmailRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "invalid_request_body"),
+			"errors":     err.Error(),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	s.confirmEmail(w, r, requestBody.Token, "")
+}
+
+// ConfirmEmailLink is the link on the confirmation mail, the token is on its `token` query parameter. It
+// redirects to the emailConfirmedUrl once the email is confirmed, errors are responded the same way as
+// ConfirmEmail.
+func (s *ServerDependency) ConfirmEmailLink(w http.ResponseWriter, r *http.Request) {
+	requestId := middleware.GetReqID(r.Context())
+	sentry.GetHubFromContext(r.Context()).Scope().SetTag("request-id", requestId)
+
+	s.confirmEmail(w, r, r.URL.Query().Get("token"), s.emailConfirmedUrl)
+}
+
+// confirmEmail confirms the token, then either redirects to redirectUrl or responds with a JSON message if
+// redirectUrl is empty.
+func (s *ServerDependency) confirmEmail(w http.ResponseWriter, r *http.Request, token string, redirectUrl string) {
+	requestId := middleware.GetReqID(r.Context())
+
+	_, err := s.userDomain.ConfirmEmail(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidConfirmationToken) || errors.Is(err, user.ErrUserEmailNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"message":    s.message(r, "invalid_confirmation_token"),
+				"errors":     err.Error(),
+				"request_id": requestId,
+			})
+			return
+		}
+
+		sentry.GetHubFromContext(r.Context()).CaptureException(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	if redirectUrl != "" {
+		http.Redirect(w, r, redirectUrl, http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    s.message(r, "email_confirmed"),
+		"request_id": requestId,
+	})
+}
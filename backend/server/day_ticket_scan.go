@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"conf/ticketing"
+	"conf/user"
 	"github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi/v5/middleware"
 	"golang.org/x/crypto/bcrypt"
@@ -15,6 +16,9 @@ import (
 type DayTicketScanRequest struct {
 	Code string `json:"code"`
 	Key  string `json:"key"`
+	// Checkpoint is the ID of the checkpoint the gate is guarding, e.g. "day-1" or "workshop-a".
+	// It can be left empty for general admission.
+	Checkpoint string `json:"checkpoint"`
 }
 
 func (s *ServerDependency) DayTicketScan(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +30,7 @@ func (s *ServerDependency) DayTicketScan(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Invalid request body",
+			"message":    s.message(r, "invalid_request_body"),
 			"errors":     err.Error(),
 			"request_id": requestId,
 		})
@@ -40,8 +44,8 @@ func (s *ServerDependency) DayTicketScan(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
 			"request_id": requestId,
 		})
 		return
@@ -52,7 +56,7 @@ func (s *ServerDependency) DayTicketScan(w http.ResponseWriter, r *http.Request)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusForbidden)
 			_ = json.NewEncoder(w).Encode(map[string]string{
-				"message":    "Wrong passphrase",
+				"message":    s.message(r, "wrong_passphrase"),
 				"errors":     "",
 				"request_id": requestId,
 			})
@@ -63,32 +67,43 @@ func (s *ServerDependency) DayTicketScan(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
 			"request_id": requestId,
 		})
 		return
 	}
 
-	verifiedTicket, err := s.ticketDomain.VerifyTicket(r.Context(), []byte(requestBody.Code))
+	verifiedTicket, err := s.ticketDomain.VerifyTicket(r.Context(), []byte(requestBody.Code), requestBody.Checkpoint)
 	if err != nil {
-		var validationError *ticketing.ValidationError
+		var validationError ticketing.ValidationError
 		if errors.As(err, &validationError) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			_ = json.NewEncoder(w).Encode(map[string]string{
-				"message":    "Validation error",
+				"message":    s.message(r, "validation_error"),
 				"errors":     validationError.Error(),
 				"request_id": requestId,
 			})
 			return
 		}
 
+		if errors.Is(err, ticketing.ErrRevokedTicket) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotAcceptable)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"message":    s.message(r, "revoked_ticket"),
+				"errors":     err.Error(),
+				"request_id": requestId,
+			})
+			return
+		}
+
 		if errors.Is(err, ticketing.ErrInvalidTicket) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotAcceptable)
 			_ = json.NewEncoder(w).Encode(map[string]string{
-				"message":    "Invalid ticket",
+				"message":    s.message(r, "invalid_ticket"),
 				"errors":     err.Error(),
 				"request_id": requestId,
 			})
@@ -99,34 +114,36 @@ func (s *ServerDependency) DayTicketScan(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
 			"request_id": requestId,
 		})
 		return
 	}
 
+	// The ticket is already redeemed at this point, failing the scan would leave it consumed and the rescan
+	// would report it as used. The attendee's details are only informational, so fall back to the ticket's.
 	userEntry, err := s.userDomain.GetUserByEmail(r.Context(), verifiedTicket.Email)
 	if err != nil {
-		sentry.GetHubFromContext(r.Context()).CaptureException(err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
-			"request_id": requestId,
-		})
-		return
+		if !errors.Is(err, user.ErrUserEmailNotFound) {
+			if hub := sentry.GetHubFromContext(r.Context()); hub != nil {
+				hub.CaptureException(err)
+			}
+		}
+
+		userEntry = user.User{Email: verifiedTicket.Email}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"message": "Ticket confirmed",
-		"student": verifiedTicket.Student,
-		"name":    userEntry.Name,
-		"type":    userEntry.Type,
-		"email":   verifiedTicket.Email,
+		"message":      s.message(r, "ticket_confirmed"),
+		"student":      verifiedTicket.Student,
+		"name":         userEntry.Name,
+		"type":         userEntry.Type,
+		"email":        verifiedTicket.Email,
+		"checkpoint":   requestBody.Checkpoint,
+		"entitlements": verifiedTicket.Entitlements,
 	})
 	return
 }
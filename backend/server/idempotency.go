@@ -0,0 +1,213 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// idempotencyKeyTtl is how long the response of an Idempotency-Key is replayed. It only has to outlive the
+// retries of a client whose response got lost, not a later attempt to register again.
+const idempotencyKeyTtl = 10 * time.Minute
+
+// idempotencyKeyMaxLength guards the memory of the cache against absurdly long keys.
+const idempotencyKeyMaxLength = 255
+
+// idempotencyMaxEntries bounds the number of cached responses, the oldest one is evicted to make room for a
+// new key.
+const idempotencyMaxEntries = 10_000
+
+// idempotencyMaxBodySize bounds the request body that is read into memory to fingerprint it.
+const idempotencyMaxBodySize = 64 << 10
+
+// idempotencyCache replays the response of a request that carries the same Idempotency-Key header as an
+// earlier one, instead of handling it again, so clients can safely retry a request whose response got lost.
+// The key is scoped to the method and the path, and is bound to the request body: reusing it with another
+// body is rejected. Server errors are not cached, the request can be retried with the same key.
+//
+// The responses are kept in memory, they don't survive a restart and are not shared between instances.
+type idempotencyCache struct {
+	mutex   sync.Mutex
+	entries map[string]*idempotentResponse
+	// order holds the cache keys from the oldest to the newest entry.
+	order      *list.List
+	ttl        time.Duration
+	maxEntries int
+	// message translates the catalog key for the request, see ServerDependency.message.
+	message func(r *http.Request, key string, args ...any) string
+}
+
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	// done is closed once the first request is handled.
+	done      chan struct{}
+	status    int
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+	element   *list.Element
+}
+
+func newIdempotencyCache(ttl time.Duration, maxEntries int, message func(r *http.Request, key string, args ...any) string) *idempotencyCache {
+	return &idempotencyCache{
+		entries:    make(map[string]*idempotentResponse),
+		order:      list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		message:    message,
+	}
+}
+
+// Middleware handles the Idempotency-Key header, requests without it are passed through.
+func (c *idempotencyCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestId := middleware.GetReqID(r.Context())
+		if len(key) > idempotencyKeyMaxLength {
+			c.writeError(w, http.StatusBadRequest, c.message(r, "idempotency_key_invalid"), requestId)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyMaxBodySize))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				c.writeError(w, http.StatusRequestEntityTooLarge, c.message(r, "request_body_too_large"), requestId)
+				return
+			}
+
+			c.writeError(w, http.StatusBadRequest, c.message(r, "invalid_request_body"), requestId)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.Sum256(body)
+		cacheKey := r.Method + " " + r.URL.Path + " " + key
+		now := time.Now()
+
+		c.mutex.Lock()
+		entry, ok := c.entries[cacheKey]
+		if ok && now.After(entry.expiresAt) {
+			ok = false
+		}
+
+		if ok {
+			c.mutex.Unlock()
+
+			if entry.fingerprint != fingerprint {
+				c.writeError(w, http.StatusUnprocessableEntity, c.message(r, "idempotency_key_reused"), requestId)
+				return
+			}
+
+			select {
+			case <-entry.done:
+			default:
+				c.writeError(w, http.StatusConflict, c.message(r, "idempotency_key_in_progress"), requestId)
+				return
+			}
+
+			for name, values := range entry.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(entry.status)
+			_, _ = w.Write(entry.body)
+			return
+		}
+
+		// Every entry lives as long, the expired ones are always the oldest.
+		for element := c.order.Front(); element != nil; element = c.order.Front() {
+			oldestKey := element.Value.(string)
+			if c.order.Len() < c.maxEntries && !now.After(c.entries[oldestKey].expiresAt) {
+				break
+			}
+
+			c.remove(oldestKey)
+		}
+
+		// An expired entry of the same key might still be there if the cache is not full.
+		if _, ok := c.entries[cacheKey]; ok {
+			c.remove(cacheKey)
+		}
+
+		entry = &idempotentResponse{fingerprint: fingerprint, done: make(chan struct{}), expiresAt: now.Add(c.ttl)}
+		entry.element = c.order.PushBack(cacheKey)
+		c.entries[cacheKey] = entry
+		c.mutex.Unlock()
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			c.mutex.Lock()
+			// A server error or a panic is not the outcome of the request, let the client retry it. The entry
+			// might have been evicted, and the key taken by a newer request, in the meantime.
+			if !completed || recorder.status >= http.StatusInternalServerError {
+				if c.entries[cacheKey] == entry {
+					c.remove(cacheKey)
+				}
+			} else {
+				entry.status = recorder.status
+				entry.header = w.Header().Clone()
+				entry.body = recorder.body.Bytes()
+			}
+			close(entry.done)
+			c.mutex.Unlock()
+		}()
+
+		next.ServeHTTP(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		completed = true
+	})
+}
+
+// remove deletes the entry of the key, the mutex must be held.
+func (c *idempotencyCache) remove(cacheKey string) {
+	c.order.Remove(c.entries[cacheKey].element)
+	delete(c.entries, cacheKey)
+}
+
+func (c *idempotencyCache) writeError(w http.ResponseWriter, status int, message string, requestId string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":    message,
+		"request_id": requestId,
+	})
+}
+
+// responseRecorder writes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyCache_Middleware(t *testing.T) {
+	message := func(r *http.Request, key string, args ...any) string {
+		return key
+	}
+
+	var calls atomic.Int64
+	status := http.StatusCreated
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"call":` + strconv.FormatInt(calls.Load(), 10) + `}`))
+	})
+
+	cache := newIdempotencyCache(time.Hour, 100, message)
+	server := cache.Middleware(handler)
+
+	send := func(key string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/public/register-user", strings.NewReader(body))
+		if key != "" {
+			request.Header.Set("Idempotency-Key", key)
+		}
+
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("Without a key", func(t *testing.T) {
+		calls.Store(0)
+		send("", `{}`)
+		send("", `{}`)
+		if calls.Load() != 2 {
+			t.Errorf("expecting 2 calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Replays the response", func(t *testing.T) {
+		calls.Store(0)
+		first := send("replay", `{"email":"johndoe@example.com"}`)
+		second := send("replay", `{"email":"johndoe@example.com"}`)
+
+		if calls.Load() != 1 {
+			t.Errorf("expecting 1 call, got %d", calls.Load())
+		}
+
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("expecting the first response %d %s, got %d %s", first.Code, first.Body.String(), second.Code, second.Body.String())
+		}
+
+		if second.Header().Get("Content-Type") != "application/json" || second.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("unexpected replayed headers %v", second.Header())
+		}
+	})
+
+	t.Run("Rejects a reused key", func(t *testing.T) {
+		send("reused", `{"email":"johndoe@example.com"}`)
+		response := send("reused", `{"email":"janedoe@example.com"}`)
+		if response.Code != http.StatusUnprocessableEntity || !strings.Contains(response.Body.String(), "idempotency_key_reused") {
+			t.Errorf("expecting 422, got %d %s", response.Code, response.Body.String())
+		}
+	})
+
+	t.Run("Rejects a long key", func(t *testing.T) {
+		response := send(strings.Repeat("k", idempotencyKeyMaxLength+1), `{}`)
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("expecting 400, got %d", response.Code)
+		}
+	})
+
+	t.Run("Does not cache server errors", func(t *testing.T) {
+		calls.Store(0)
+		status = http.StatusInternalServerError
+		send("error", `{}`)
+		status = http.StatusCreated
+		response := send("error", `{}`)
+
+		if calls.Load() != 2 || response.Code != http.StatusCreated {
+			t.Errorf("expecting the retry to be handled, got %d calls and %d", calls.Load(), response.Code)
+		}
+	})
+
+	t.Run("Rejects a concurrent request", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		blocking := newIdempotencyCache(time.Hour, 100, message).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			request := httptest.NewRequest(http.MethodPost, "/api/public/register-user", strings.NewReader(`{}`))
+			request.Header.Set("Idempotency-Key", "concurrent")
+			blocking.ServeHTTP(httptest.NewRecorder(), request)
+		}()
+		<-started
+
+		request := httptest.NewRequest(http.MethodPost, "/api/public/register-user", strings.NewReader(`{}`))
+		request.Header.Set("Idempotency-Key", "concurrent")
+		recorder := httptest.NewRecorder()
+		blocking.ServeHTTP(recorder, request)
+		close(release)
+		<-done
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("expecting 409, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		calls.Store(0)
+		expiring := newIdempotencyCache(time.Millisecond, 100, message).Middleware(handler)
+		for i := 0; i < 2; i++ {
+			request := httptest.NewRequest(http.MethodPost, "/api/public/register-user", strings.NewReader(`{}`))
+			request.Header.Set("Idempotency-Key", "expiring")
+			expiring.ServeHTTP(httptest.NewRecorder(), request)
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		if calls.Load() != 2 {
+			t.Errorf("expecting 2 calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Rejects a large body", func(t *testing.T) {
+		calls.Store(0)
+		response := send("large", strings.Repeat("a", idempotencyMaxBodySize+1))
+		if response.Code != http.StatusRequestEntityTooLarge || calls.Load() != 0 {
+			t.Errorf("expecting 413 without calling the handler, got %d and %d calls", response.Code, calls.Load())
+		}
+	})
+
+	t.Run("Evicts the oldest entry", func(t *testing.T) {
+		calls.Store(0)
+		bounded := newIdempotencyCache(time.Hour, 2, message)
+		boundedServer := bounded.Middleware(handler)
+		for _, key := range []string{"first", "second", "third", "first"} {
+			request := httptest.NewRequest(http.MethodPost, "/api/public/register-user", strings.NewReader(`{}`))
+			request.Header.Set("Idempotency-Key", key)
+			boundedServer.ServeHTTP(httptest.NewRecorder(), request)
+		}
+
+		// The first key is evicted by the third one, so its retry is handled again.
+		if calls.Load() != 4 {
+			t.Errorf("expecting 4 calls, got %d", calls.Load())
+		}
+
+		if len(bounded.entries) != 2 || bounded.order.Len() != 2 {
+			t.Errorf("expecting 2 entries, got %d entries and %d ordered keys", len(bounded.entries), bounded.order.Len())
+		}
+	})
+}
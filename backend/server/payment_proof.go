@@ -28,7 +28,7 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Parsing error",
+			"message":    s.message(r, "parsing_error"),
 			"errors":     err.Error(),
 			"request_id": requestId,
 		})
@@ -40,8 +40,8 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Validation error",
-			"errors":     "Email field is required",
+			"message":    s.message(r, "validation_error"),
+			"errors":     s.message(r, "email_field_required"),
 			"request_id": requestId,
 		})
 		return
@@ -53,8 +53,8 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			_ = json.NewEncoder(w).Encode(map[string]string{
-				"message":    "Validation error",
-				"errors":     "Photo field is required",
+				"message":    s.message(r, "validation_error"),
+				"errors":     s.message(r, "photo_field_required"),
 				"request_id": requestId,
 			})
 			return
@@ -64,7 +64,7 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Reading form file",
+			"message":    s.message(r, "reading_form_file"),
 			"errors":     err.Error(),
 			"request_id": requestId,
 		})
@@ -83,8 +83,8 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Unknown photo file type",
-			"errors":     "Unknown photo file type",
+			"message":    s.message(r, "unknown_photo_file_type"),
+			"errors":     s.message(r, "unknown_photo_file_type"),
 			"request_id": requestId,
 		})
 		return
@@ -98,7 +98,7 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusPreconditionFailed)
 			_ = json.NewEncoder(w).Encode(map[string]string{
-				"message":    "User not found",
+				"message":    s.message(r, "user_not_found"),
 				"errors":     err.Error(),
 				"request_id": requestId,
 			})
@@ -109,8 +109,19 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
+			"request_id": requestId,
+		})
+		return
+	}
+
+	if !userEntry.EmailConfirmed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message":    s.message(r, "email_not_confirmed"),
+			"errors":     s.message(r, "email_not_confirmed"),
 			"request_id": requestId,
 		})
 		return
@@ -123,7 +134,7 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			_ = json.NewEncoder(w).Encode(map[string]string{
-				"message":    "Validation error",
+				"message":    s.message(r, "validation_error"),
 				"errors":     validationError.Error(),
 				"request_id": requestId,
 			})
@@ -134,8 +145,8 @@ func (s *ServerDependency) UploadPaymentProof(w http.ResponseWriter, r *http.Req
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
 			"request_id": requestId,
 		})
 		return
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 
+	"conf/i18n"
 	"conf/user"
 	"github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi/v5/middleware"
@@ -13,6 +14,9 @@ import (
 type RegisterUserRequest struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	// Locale is the language the user wants to be contacted in, such as "en" or "id". It's taken from the
+	// Accept-Language header if it's empty or not supported.
+	Locale string `json:"locale"`
 }
 
 func (s *ServerDependency) RegisterUser(w http.ResponseWriter, r *http.Request) {
@@ -23,7 +27,7 @@ func (s *ServerDependency) RegisterUser(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotAcceptable)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Registration is closed",
+			"message":    s.message(r, "registration_closed"),
 			"request_id": requestId,
 		})
 		return
@@ -34,18 +38,24 @@ func (s *ServerDependency) RegisterUser(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Invalid request body",
+			"message":    s.message(r, "invalid_request_body"),
 			"errors":     err.Error(),
 			"request_id": requestId,
 		})
 		return
 	}
 
-	err := s.userDomain.CreateParticipant(
+	locale, ok := i18n.Parse(requestBody.Locale)
+	if !ok {
+		locale = i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	registration, err := s.userDomain.CreateParticipant(
 		r.Context(),
 		user.CreateParticipantRequest{
-			Name:  requestBody.Name,
-			Email: requestBody.Email,
+			Name:   requestBody.Name,
+			Email:  requestBody.Email,
+			Locale: locale,
 		},
 	)
 	if err != nil {
@@ -54,7 +64,7 @@ func (s *ServerDependency) RegisterUser(w http.ResponseWriter, r *http.Request)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			_ = json.NewEncoder(w).Encode(map[string]any{
-				"message":    "Validation error",
+				"message":    s.message(r, "validation_error"),
 				"errors":     validationError.Errors,
 				"request_id": requestId,
 			})
@@ -65,13 +75,26 @@ func (s *ServerDependency) RegisterUser(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"message":    "Internal server error",
-			"errors":     "Internal server error",
+			"message":    s.message(r, "internal_server_error"),
+			"errors":     s.message(r, "internal_server_error"),
 			"request_id": requestId,
 		})
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	return
+	// Registering again returns the existing registration, so a retried request looks the same to the client
+	// besides the status code.
+	status, message := http.StatusCreated, s.message(r, "registered")
+	if !registration.Created {
+		status, message = http.StatusOK, s.message(r, "already_registered")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"message":         message,
+		"email":           registration.User.Email,
+		"email_confirmed": registration.User.EmailConfirmed,
+		"request_id":      requestId,
+	})
 }
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"conf/administrator"
+	"conf/blast"
 	"conf/features"
+	"conf/i18n"
 	"conf/mailer"
 	"conf/ticketing"
 	"conf/user"
@@ -22,11 +24,16 @@ type ServerConfig struct {
 	TicketDomain        *ticketing.TicketDomain
 	AdministratorDomain *administrator.AdministratorDomain
 	FeatureFlag         *features.FeatureFlag
-	MailSender          *mailer.Mailer
-	Environment         string
-	ValidateTicketKey   string
-	Hostname            string
-	Port                string
+	BulkMailSender      *mailer.BulkSender
+	SegmentResolver     *blast.SegmentResolver
+	MessageCatalog      *i18n.Catalog
+	// EmailConfirmedUrl is where the confirmation link redirects to once the email is confirmed. The link
+	// responds with a JSON message instead if it's empty.
+	EmailConfirmedUrl string
+	Environment       string
+	ValidateTicketKey string
+	Hostname          string
+	Port              string
 }
 
 type ServerDependency struct {
@@ -34,7 +41,10 @@ type ServerDependency struct {
 	ticketDomain        *ticketing.TicketDomain
 	administratorDomain *administrator.AdministratorDomain
 	featureFlag         *features.FeatureFlag
-	mailSender          *mailer.Mailer
+	bulkMailSender      *mailer.BulkSender
+	segmentResolver     *blast.SegmentResolver
+	messageCatalog      *i18n.Catalog
+	emailConfirmedUrl   string
 	validateTicketKey   string
 }
 
@@ -55,8 +65,16 @@ func NewServer(config *ServerConfig) (*http.Server, error) {
 		return nil, fmt.Errorf("nil FeatureFlag")
 	}
 
-	if config.MailSender == nil {
-		return nil, fmt.Errorf("nil MailSender")
+	if config.BulkMailSender == nil {
+		return nil, fmt.Errorf("nil BulkMailSender")
+	}
+
+	if config.SegmentResolver == nil {
+		return nil, fmt.Errorf("nil SegmentResolver")
+	}
+
+	if config.MessageCatalog == nil {
+		return nil, fmt.Errorf("nil MessageCatalog")
 	}
 
 	if config.ValidateTicketKey == "" {
@@ -68,7 +86,10 @@ func NewServer(config *ServerConfig) (*http.Server, error) {
 		ticketDomain:        config.TicketDomain,
 		administratorDomain: config.AdministratorDomain,
 		featureFlag:         config.FeatureFlag,
-		mailSender:          config.MailSender,
+		bulkMailSender:      config.BulkMailSender,
+		segmentResolver:     config.SegmentResolver,
+		messageCatalog:      config.MessageCatalog,
+		emailConfirmedUrl:   config.EmailConfirmedUrl,
 		validateTicketKey:   config.ValidateTicketKey,
 	}
 
@@ -84,19 +105,33 @@ func NewServer(config *ServerConfig) (*http.Server, error) {
 	}
 	r.Use(cors.New(cors.Options{
 		AllowedOrigins:   corsAllowedOrigins,
-		AllowedMethods:   []string{http.MethodPost},
-		AllowedHeaders:   []string{"Authorization"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization", "Idempotency-Key"},
 		AllowCredentials: true,
 		MaxAge:           3600, // 1 day
 	}).Handler)
 
 	r.Use(middleware.Heartbeat("/api/public/ping"))
 
-	r.Post("/api/public/register-user", dependencies.RegisterUser)
+	// Registering is idempotent on its own, the Idempotency-Key additionally replays the exact response and
+	// keeps a retry from mailing another confirmation link.
+	idempotency := newIdempotencyCache(idempotencyKeyTtl, idempotencyMaxEntries, dependencies.message)
+	r.With(idempotency.Middleware).Post("/api/public/register-user", dependencies.RegisterUser)
+	r.Get("/api/public/confirm-email", dependencies.ConfirmEmailLink)
+	r.Post("/api/public/confirm-email", dependencies.ConfirmEmail)
 	r.Post("/api/public/upload-payment-proof", dependencies.UploadPaymentProof)
 	r.Post("/api/public/scan-ticket", dependencies.DayTicketScan)
 
 	r.Post("/api/administrator/login", dependencies.AdministratorLogin)
+	r.Post("/api/administrator/mail-blast", dependencies.AdministratorMailBlast)
+	r.Get("/api/administrator/payments", dependencies.AdministratorListPayments)
+	r.Post("/api/administrator/payments/{id}/approve", dependencies.AdministratorApprovePayment)
+	r.Post("/api/administrator/payments/{id}/reject", dependencies.AdministratorRejectPayment)
+	r.Post("/api/administrator/tickets/{id}/revoke", dependencies.AdministratorRevokeTicket)
+	r.Post("/api/administrator/tickets/{id}/reissue", dependencies.AdministratorReissueTicket)
+	r.Get("/api/administrator/tickets/{id}/pdf", dependencies.AdministratorDownloadTicketPdf)
+	r.Get("/api/administrator/gate-bundle", dependencies.AdministratorExportGateBundle)
+	r.Post("/api/administrator/gate-bundle/redemptions", dependencies.AdministratorUploadRedemptions)
 
 	return &http.Server{
 		Addr:              net.JoinHostPort(config.Hostname, config.Port),
@@ -107,3 +142,8 @@ func NewServer(config *ServerConfig) (*http.Server, error) {
 		IdleTimeout:       time.Hour,
 	}, nil
 }
+
+// message translates the catalog key to the best locale of the request's Accept-Language header.
+func (s *ServerDependency) message(r *http.Request, key string, args ...any) string {
+	return s.messageCatalog.Message(i18n.FromAcceptLanguage(r.Header.Get("Accept-Language")), key, args...)
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,8 +10,8 @@ import (
 	"time"
 
 	"conf/administrator"
+	"conf/blast"
 	"conf/mailer"
-	"conf/nocodb"
 	"conf/server"
 	"conf/ticketing"
 	"conf/user"
@@ -56,15 +55,16 @@ func ServerHandlerAction(ctx *cli.Context) error {
 	}
 	defer sentry.Flush(time.Minute)
 
-	database, err := nocodb.NewClient(nocodb.ClientOptions{
-		ApiToken:   config.Database.NocoDbApiKey,
-		BaseUrl:    config.Database.NocoDbBaseUrl,
-		HttpClient: &http.Client{Transport: NewSentryRoundTripper(http.DefaultTransport, nil)},
-		Logger:     log.Logger,
-	})
+	repositories, err := NewRepositories(ctx.Context, config)
 	if err != nil {
-		return fmt.Errorf("creating database client instance: %w", err)
+		return fmt.Errorf("creating repositories: %w", err)
 	}
+	defer func() {
+		err := repositories.Close()
+		if err != nil {
+			log.Warn().Err(err).Msg("Closing database")
+		}
+	}()
 
 	bucket, err := blob.OpenBucket(context.Background(), config.BlobUrl)
 	if err != nil {
@@ -77,44 +77,97 @@ func ServerHandlerAction(ctx *cli.Context) error {
 		}
 	}()
 
-	signaturePrivateKey, err := hex.DecodeString(config.Signature.PrivateKey)
+	signatureKeyring, err := config.SignatureKeyring()
 	if err != nil {
-		return fmt.Errorf("invalid signature private key: %w", err)
+		return fmt.Errorf("creating signature keyring: %w", err)
 	}
 
-	signaturePublicKey, err := hex.DecodeString(config.Signature.PublicKey)
+	walletIssuer, err := config.WalletIssuer()
 	if err != nil {
-		return fmt.Errorf("invalid signature public key: %w", err)
+		return fmt.Errorf("creating wallet issuer: %w", err)
 	}
 
-	mailSender := mailer.NewMailSender(&mailer.MailConfiguration{
-		SmtpHostname: config.Mailer.Hostname,
-		SmtpPort:     config.Mailer.Port,
-		SmtpFrom:     config.Mailer.From,
-		SmtpPassword: config.Mailer.Password,
+	mailTransport, err := config.MailTransport()
+	if err != nil {
+		return fmt.Errorf("creating mail transport: %w", err)
+	}
+
+	// Mails are queued on the outbox and delivered in the background, so a hiccup on the SMTP server does
+	// not fail the request that sends them.
+	mailOutbox, err := mailer.NewOutbox(repositories.MailOutbox, mailer.NewMailSenderWithTransport(mailTransport, config.MailFrom()), mailer.OutboxOptions{
+		Workers:     config.Mailer.Outbox.Workers,
+		MaxAttempts: config.Mailer.Outbox.MaxAttempts,
+		BaseBackoff: config.Mailer.Outbox.BaseBackoff,
 	})
+	if err != nil {
+		return fmt.Errorf("creating mail outbox: %w", err)
+	}
 
-	ticketDomain, err := ticketing.NewTicketDomain(database, bucket, signaturePrivateKey, signaturePublicKey, mailSender, config.Database.TicketingTableId)
+	outboxCtx, stopOutbox := context.WithCancel(context.Background())
+	outboxDone := make(chan struct{})
+	go func() {
+		defer close(outboxDone)
+		_ = mailOutbox.Run(outboxCtx)
+	}()
+	defer func() {
+		stopOutbox()
+		<-outboxDone
+	}()
+
+	mailTemplates, err := config.MailTemplates()
 	if err != nil {
-		return fmt.Errorf("creating ticket domain: %w", err)
+		return err
 	}
 
-	userDomain, err := user.NewUserDomain(database, config.Database.UserTableId)
+	userDomainOptions, err := config.UserDomainOptions(mailOutbox, mailTemplates)
+	if err != nil {
+		return err
+	}
+
+	userDomain, err := user.NewUserDomain(repositories.User, userDomainOptions)
 	if err != nil {
 		return fmt.Errorf("creating user domain: %w", err)
 	}
 
+	ticketDomainOptions, err := config.TicketDomainOptions(walletIssuer, userDomain)
+	if err != nil {
+		return err
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(repositories.Ticketing, bucket, signatureKeyring, mailOutbox, mailTemplates, ticketDomainOptions)
+	if err != nil {
+		return fmt.Errorf("creating ticket domain: %w", err)
+	}
+
 	administratorDomain, err := administrator.NewAdministratorDomain(config.AdministratorUserMapping)
 	if err != nil {
 		return fmt.Errorf("creating administrator domain: %w", err)
 	}
 
+	bulkMailSender, err := config.BulkMailSender()
+	if err != nil {
+		return fmt.Errorf("creating bulk mail sender: %w", err)
+	}
+
+	segmentResolver, err := blast.NewSegmentResolver(userDomain, repositories.Ticketing)
+	if err != nil {
+		return fmt.Errorf("creating segment resolver: %w", err)
+	}
+
+	messageCatalog, err := config.MessageCatalog()
+	if err != nil {
+		return err
+	}
+
 	httpServer, err := server.NewServer(&server.ServerConfig{
 		UserDomain:          userDomain,
 		TicketDomain:        ticketDomain,
 		AdministratorDomain: administratorDomain,
 		FeatureFlag:         &config.FeatureFlags,
-		MailSender:          mailSender,
+		BulkMailSender:      bulkMailSender,
+		SegmentResolver:     segmentResolver,
+		MessageCatalog:      messageCatalog,
+		EmailConfirmedUrl:   config.EmailConfirmation.RedirectUrl,
 		Environment:         config.Environment,
 		ValidateTicketKey:   config.ValidateTicketKey,
 		Hostname:            "",
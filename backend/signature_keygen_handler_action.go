@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"conf/ticketing"
+	"github.com/urfave/cli/v2"
+)
+
+func SignatureKeygenHandlerAction(cCtx *cli.Context) error {
+	keyId := cCtx.String("id")
+	if keyId == "" {
+		keyId = time.Now().UTC().Format("20060102")
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generating ed25519 key: %w", err)
+	}
+
+	// Validate the key ID the same way the server does on startup
+	_, err = ticketing.NewKeyring(keyId, []ticketing.SigningKey{{Id: keyId, PublicKey: publicKey, PrivateKey: privateKey}})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(cCtx.App.Writer, `# Add the key to the existing keys, then point active_key_id to it.
+# Keep the previous keys on the list, so tickets issued with them are still valid.
+signature:
+  active_key_id: %[1]s
+  keys:
+    - id: %[1]s
+      public_key: %[2]s
+      private_key: %[3]s
+`, keyId, hex.EncodeToString(publicKey), hex.EncodeToString(privateKey))
+	return err
+}
@@ -0,0 +1,64 @@
+package ticketing
+
+import (
+	"errors"
+	"strings"
+)
+
+// base45Alphabet is the QR code alphanumeric mode character set, as defined on RFC 9285.
+const base45Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+var errInvalidBase45 = errors.New("invalid base45 string")
+
+// encodeBase45 encodes every 2 bytes into 3 characters, and the odd byte at the end into 2 characters.
+func encodeBase45(src []byte) []byte {
+	dst := make([]byte, 0, len(src)/2*3+2)
+	for i := 0; i+1 < len(src); i += 2 {
+		n := int(src[i])<<8 | int(src[i+1])
+		dst = append(dst, base45Alphabet[n%45], base45Alphabet[n/45%45], base45Alphabet[n/2025])
+	}
+
+	if len(src)%2 == 1 {
+		n := int(src[len(src)-1])
+		dst = append(dst, base45Alphabet[n%45], base45Alphabet[n/45])
+	}
+
+	return dst
+}
+
+func decodeBase45(src []byte) ([]byte, error) {
+	if len(src)%3 == 1 {
+		return nil, errInvalidBase45
+	}
+
+	values := make([]int, len(src))
+	for i, c := range src {
+		value := strings.IndexByte(base45Alphabet, c)
+		if value < 0 {
+			return nil, errInvalidBase45
+		}
+
+		values[i] = value
+	}
+
+	dst := make([]byte, 0, len(src)/3*2+1)
+	for i := 0; i+2 < len(values); i += 3 {
+		n := values[i] + values[i+1]*45 + values[i+2]*2025
+		if n > 0xffff {
+			return nil, errInvalidBase45
+		}
+
+		dst = append(dst, byte(n>>8), byte(n))
+	}
+
+	if len(values)%3 == 2 {
+		n := values[len(values)-2] + values[len(values)-1]*45
+		if n > 0xff {
+			return nil, errInvalidBase45
+		}
+
+		dst = append(dst, byte(n))
+	}
+
+	return dst, nil
+}
@@ -14,3 +14,5 @@ func (v ValidationError) Error() string {
 }
 
 var ErrInvalidTicket = errors.New("invalid ticket")
+var ErrRevokedTicket = errors.New("revoked ticket")
+var ErrPaymentAlreadyReviewed = errors.New("payment already reviewed")
@@ -0,0 +1,377 @@
+package ticketing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// GateBundle holds everything a ticket scanner needs to verify tickets without reaching the database.
+// It is exported by ExportGateBundle and loaded on the gates with OpenGateBundle.
+type GateBundle struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// PublicKeys holds the ed25519 public keys that sign the QR code payloads, keyed by their IDs.
+	PublicKeys map[string]ed25519.PublicKey `json:"public_keys"`
+	Tickets    []GateTicket                 `json:"tickets"`
+}
+
+// GateTicket is a valid ticket on the GateBundle. The email is only stored as its SHA-384 hash, the same
+// one that is embedded on the QR code payload.
+type GateTicket struct {
+	Id           int64    `json:"id"`
+	HashedEmail  []byte   `json:"hashed_email"`
+	Entitlements []string `json:"entitlements,omitempty"`
+	Version      int64    `json:"version,omitempty"`
+	Revoked      bool     `json:"revoked,omitempty"`
+	// RedeemedCheckpoints lists the checkpoints the ticket has been redeemed on by the time the bundle is
+	// generated. It holds an empty string if a general admission ticket has been used.
+	RedeemedCheckpoints []string `json:"redeemed_checkpoints,omitempty"`
+}
+
+// signedGateBundle is the wire format of the GateBundle. The signature covers the exact bytes of the
+// bundle, so it's kept raw instead of being re-encoded.
+type signedGateBundle struct {
+	Bundle    json.RawMessage `json:"bundle"`
+	KeyId     string          `json:"key_id"`
+	Signature []byte          `json:"signature"`
+}
+
+// ExportGateBundle collects every paid ticket into a GateBundle, then signs it with the active key.
+func (t *TicketDomain) ExportGateBundle(ctx context.Context) ([]byte, error) {
+	span := sentry.StartSpan(ctx, "ticketing.export_gate_bundle", sentry.WithTransactionName("ExportGateBundle"))
+	defer span.Finish()
+
+	bundle := GateBundle{
+		GeneratedAt: time.Now().UTC(),
+		PublicKeys:  t.keyring.PublicKeys(),
+		Tickets:     []GateTicket{},
+	}
+
+	var offset int64
+	for {
+		tickets, isLastPage, err := t.repository.ListTickets(ctx, TicketQuery{Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("listing tickets: %w", err)
+		}
+
+		offset += int64(len(tickets))
+
+		for _, ticket := range tickets {
+			if !ticket.Paid || ticket.Rejected {
+				continue
+			}
+
+			payload := newTicketPayload(ticket)
+			gateTicket := GateTicket{
+				Id:           ticket.Id,
+				HashedEmail:  payload.HashedEmail,
+				Entitlements: payload.Entitlements,
+				Version:      ticket.Version,
+				Revoked:      ticket.Revoked,
+			}
+
+			if len(payload.Entitlements) == 0 {
+				if ticket.Used {
+					gateTicket.RedeemedCheckpoints = []string{""}
+				}
+			} else {
+				gateTicket.RedeemedCheckpoints = splitList(ticket.RedeemedCheckpoints)
+			}
+
+			bundle.Tickets = append(bundle.Tickets, gateTicket)
+		}
+
+		if isLastPage || len(tickets) == 0 {
+			break
+		}
+	}
+
+	sort.Slice(bundle.Tickets, func(i, j int) bool {
+		return bundle.Tickets[i].Id < bundle.Tickets[j].Id
+	})
+
+	rawBundle, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling bundle: %w", err)
+	}
+
+	activeKey, ok := t.keyring.ActiveKey()
+	if !ok {
+		return nil, fmt.Errorf("keyring does not have an active key")
+	}
+
+	signed, err := json.Marshal(signedGateBundle{
+		Bundle:    rawBundle,
+		KeyId:     activeKey.Id,
+		Signature: ed25519.Sign(activeKey.PrivateKey, rawBundle),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling signed bundle: %w", err)
+	}
+
+	return signed, nil
+}
+
+// ErrInvalidGateBundle is returned by OpenGateBundle if the bundle is malformed, or it's not signed by any
+// of the trusted keys.
+var ErrInvalidGateBundle = errors.New("invalid gate bundle")
+
+// OfflineRedemption is a ticket redemption done by a gate while it's offline. It's queued on the gate, then
+// uploaded to ReconcileRedemptions once the gate is back online.
+type OfflineRedemption struct {
+	TicketId int64 `json:"ticket_id"`
+	// Checkpoint is the checkpoint the ticket is redeemed on. It's empty for general admission tickets.
+	Checkpoint string `json:"checkpoint"`
+	// Version is the version of the scanned QR code, see TicketPayload.
+	Version    int64     `json:"version,omitempty"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
+
+type redemptionKey struct {
+	ticketId   int64
+	checkpoint string
+}
+
+// OfflineVerifier verifies QR code payloads against a GateBundle. It is safe for concurrent use, so
+// multiple scanners on the same gate can share one.
+type OfflineVerifier struct {
+	mu          sync.Mutex
+	keyring     *Keyring
+	generatedAt time.Time
+	tickets     map[int64]GateTicket
+	redeemed    map[redemptionKey]struct{}
+	queue       []OfflineRedemption
+}
+
+// OpenGateBundle validates the signed bundle against the trusted keyring, which should be configured on the
+// gate beforehand rather than taken from the bundle itself. Once the bundle is validated, the public keys
+// on the bundle are used to verify the tickets, so the gate picks up keys that are rotated after it's
+// configured.
+func OpenGateBundle(signed []byte, trustedKeyring *Keyring) (*OfflineVerifier, error) {
+	if trustedKeyring == nil {
+		return nil, fmt.Errorf("trusted keyring is nil")
+	}
+
+	var envelope signedGateBundle
+	if err := json.Unmarshal(signed, &envelope); err != nil {
+		return nil, fmt.Errorf("%w (decoding envelope): %s", ErrInvalidGateBundle, err.Error())
+	}
+
+	trustedPublicKey, ok := trustedKeyring.PublicKey(envelope.KeyId)
+	if !ok {
+		return nil, fmt.Errorf("%w (unknown key %q)", ErrInvalidGateBundle, envelope.KeyId)
+	}
+
+	if !ed25519.Verify(trustedPublicKey, envelope.Bundle, envelope.Signature) {
+		return nil, fmt.Errorf("%w (verifying signature)", ErrInvalidGateBundle)
+	}
+
+	var bundle GateBundle
+	if err := json.Unmarshal(envelope.Bundle, &bundle); err != nil {
+		return nil, fmt.Errorf("%w (decoding bundle): %s", ErrInvalidGateBundle, err.Error())
+	}
+
+	keyring, err := NewVerificationKeyring(bundle.PublicKeys)
+	if err != nil {
+		return nil, fmt.Errorf("%w (creating keyring): %s", ErrInvalidGateBundle, err.Error())
+	}
+
+	verifier := &OfflineVerifier{
+		keyring:     keyring,
+		generatedAt: bundle.GeneratedAt,
+		tickets:     make(map[int64]GateTicket, len(bundle.Tickets)),
+		redeemed:    make(map[redemptionKey]struct{}),
+	}
+
+	for _, ticket := range bundle.Tickets {
+		verifier.tickets[ticket.Id] = ticket
+		for _, checkpoint := range ticket.RedeemedCheckpoints {
+			verifier.redeemed[redemptionKey{ticketId: ticket.Id, checkpoint: checkpoint}] = struct{}{}
+		}
+	}
+
+	return verifier, nil
+}
+
+// GeneratedAt returns the time the bundle is exported. Redemptions done on other gates after that time
+// are unknown to this verifier.
+func (v *OfflineVerifier) GeneratedAt() time.Time {
+	return v.generatedAt
+}
+
+// Verify validates the QR code payload on the checkpoint the same way TicketDomain.VerifyTicket does, but
+// against the bundle. A successful verification is queued to be uploaded later.
+//
+// Each gate only knows its own redemptions, so a ticket used on two gates at the same time is only
+// detected as a conflict by ReconcileRedemptions.
+func (v *OfflineVerifier) Verify(payload []byte, checkpoint string) (OfflineRedemption, error) {
+	if checkpoint != "" {
+		if err := validateCheckpoints([]string{checkpoint}); err != nil {
+			return OfflineRedemption{}, err
+		}
+	}
+
+	ticketPayload, err := ParseTicketPayload(v.keyring, payload)
+	if err != nil {
+		return OfflineRedemption{}, err
+	}
+
+	if !ticketPayload.Entitles(checkpoint) {
+		return OfflineRedemption{}, fmt.Errorf("%w (not entitled to %s)", ErrInvalidTicket, checkpoint)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	ticket, ok := v.tickets[ticketPayload.TicketId]
+	if !ok {
+		return OfflineRedemption{}, fmt.Errorf("%w: not exists", ErrInvalidTicket)
+	}
+
+	if !matchHashedEmail(ticket.HashedEmail, ticketPayload.HashedEmail) {
+		return OfflineRedemption{}, fmt.Errorf("%w (mismatched email)", ErrInvalidTicket)
+	}
+
+	if ticket.Revoked || ticketPayload.Version < ticket.Version {
+		return OfflineRedemption{}, ErrRevokedTicket
+	}
+
+	redeemOn := checkpoint
+	if len(ticketPayload.Entitlements) == 0 {
+		redeemOn = ""
+	}
+
+	key := redemptionKey{ticketId: ticket.Id, checkpoint: redeemOn}
+	if _, ok := v.redeemed[key]; ok {
+		return OfflineRedemption{}, fmt.Errorf("%w: already used", ErrInvalidTicket)
+	}
+
+	v.redeemed[key] = struct{}{}
+
+	redemption := OfflineRedemption{
+		TicketId:   ticket.Id,
+		Checkpoint: redeemOn,
+		Version:    ticketPayload.Version,
+		RedeemedAt: time.Now().UTC(),
+	}
+	v.queue = append(v.queue, redemption)
+
+	return redemption, nil
+}
+
+// Flush returns the queued redemptions and empties the queue. Put them back with Requeue if the upload fails.
+func (v *OfflineVerifier) Flush() []OfflineRedemption {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	queue := v.queue
+	v.queue = nil
+
+	return queue
+}
+
+// Requeue puts the redemptions back to the queue, in front of the ones queued after Flush.
+func (v *OfflineVerifier) Requeue(redemptions []OfflineRedemption) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.queue = append(slices.Clone(redemptions), v.queue...)
+}
+
+// RedemptionConflict is an uploaded redemption that can not be recorded, most likely because the same
+// ticket is used on another gate.
+type RedemptionConflict struct {
+	OfflineRedemption
+	Reason string `json:"reason"`
+}
+
+// ReconcileReport summarizes the result of ReconcileRedemptions.
+type ReconcileReport struct {
+	Accepted  int                  `json:"accepted"`
+	Conflicts []RedemptionConflict `json:"conflicts"`
+}
+
+// ReconcileRedemptions records redemptions uploaded by the gates. The redemptions are applied from the
+// earliest one, so the first entry wins if the same ticket is used on multiple gates. Every redemption that
+// can't be recorded is reported as a conflict instead of failing the whole upload, including the ones of a
+// ticket that is not paid, is rejected, or is not entitled to the checkpoint.
+func (t *TicketDomain) ReconcileRedemptions(ctx context.Context, redemptions []OfflineRedemption) (ReconcileReport, error) {
+	span := sentry.StartSpan(ctx, "ticketing.reconcile_redemptions", sentry.WithTransactionName("ReconcileRedemptions"))
+	defer span.Finish()
+
+	redemptions = slices.Clone(redemptions)
+	sort.SliceStable(redemptions, func(i, j int) bool {
+		return redemptions[i].RedeemedAt.Before(redemptions[j].RedeemedAt)
+	})
+
+	report := ReconcileReport{Conflicts: []RedemptionConflict{}}
+	for _, redemption := range redemptions {
+		if redemption.Checkpoint != "" {
+			if err := validateCheckpoints([]string{redemption.Checkpoint}); err != nil {
+				report.Conflicts = append(report.Conflicts, RedemptionConflict{OfflineRedemption: redemption, Reason: err.Error()})
+				continue
+			}
+		}
+
+		tickets, _, err := t.repository.ListTickets(ctx, TicketQuery{
+			Id:    sql.NullInt64{Int64: redemption.TicketId, Valid: true},
+			Limit: 1,
+		})
+		if err != nil {
+			return ReconcileReport{}, fmt.Errorf("acquiring records: %w", err)
+		}
+
+		if len(tickets) == 0 {
+			report.Conflicts = append(report.Conflicts, RedemptionConflict{OfflineRedemption: redemption, Reason: "ticket not exists"})
+			continue
+		}
+
+		// The uploads are not trusted, the ticket has to be valid for the gate the same way as VerifyTicket and
+		// ExportGateBundle see it.
+		if !tickets[0].Paid || tickets[0].Rejected {
+			report.Conflicts = append(report.Conflicts, RedemptionConflict{OfflineRedemption: redemption, Reason: "ticket is not paid"})
+			continue
+		}
+
+		// The ticket might be revoked or reissued after the bundle is exported
+		if tickets[0].Revoked || redemption.Version < tickets[0].Version {
+			report.Conflicts = append(report.Conflicts, RedemptionConflict{OfflineRedemption: redemption, Reason: ErrRevokedTicket.Error()})
+			continue
+		}
+
+		payload := newTicketPayload(tickets[0])
+		if !payload.Entitles(redemption.Checkpoint) {
+			report.Conflicts = append(report.Conflicts, RedemptionConflict{OfflineRedemption: redemption, Reason: "not entitled to " + redemption.Checkpoint})
+			continue
+		}
+
+		// General admission tickets are redeemed as a whole, regardless of the checkpoint it's scanned on.
+		redeemOn := redemption.Checkpoint
+		if len(payload.Entitlements) == 0 {
+			redeemOn = ""
+		}
+
+		err = t.repository.RedeemTicket(ctx, redemption.TicketId, redeemOn, redemption.RedeemedAt)
+		if err != nil {
+			if errors.Is(err, ErrInvalidTicket) {
+				report.Conflicts = append(report.Conflicts, RedemptionConflict{OfflineRedemption: redemption, Reason: err.Error()})
+				continue
+			}
+
+			return ReconcileReport{}, fmt.Errorf("redeeming ticket: %w", err)
+		}
+
+		report.Accepted++
+	}
+
+	return report, nil
+}
@@ -0,0 +1,243 @@
+package ticketing_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"conf/ticketing"
+	"conf/user"
+)
+
+func TestTicketDomain_GateBundle(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+		return
+	}
+
+	signingKey := ticketing.SigningKey{PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating a ticket domain instance: %s", err.Error())
+	}
+
+	// issueTicket stores a payment receipt for the email, optionally approves it, then builds the QR code payload.
+	issueTicket := func(t *testing.T, ctx context.Context, email string, paid bool) []byte {
+		err := ticketDomain.StorePaymentReceipt(ctx, user.User{Email: email}, strings.NewReader("Hello world! This is not a photo. Yet this will be a text file."), "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if paid {
+			_, err = ticketDomain.ValidatePaymentReceipt(ctx, user.User{Email: email})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: email, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(tickets) == 0 {
+			t.Fatalf("expecting ticket for %s to exists, got none", email)
+		}
+
+		hashedEmail := sha512.Sum384([]byte(email))
+		return mustSign(t, ticketing.TicketPayload{TicketId: tickets[0].Id, HashedEmail: hashedEmail[:]}, signingKey)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	paidPayload := issueTicket(t, ctx, "johndoe+gate-paid@example.com", true)
+	unpaidPayload := issueTicket(t, ctx, "johndoe+gate-unpaid@example.com", false)
+
+	bundle, err := ticketDomain.ExportGateBundle(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	t.Run("Untrusted key", func(t *testing.T) {
+		otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generating new ed25519 key: %s", err.Error())
+		}
+
+		otherKeyring, err := ticketing.NewVerificationKeyring(map[string]ed25519.PublicKey{"": otherPublicKey})
+		if err != nil {
+			t.Fatalf("creating a keyring: %s", err.Error())
+		}
+
+		_, err = ticketing.OpenGateBundle(bundle, otherKeyring)
+		if !errors.Is(err, ticketing.ErrInvalidGateBundle) {
+			t.Errorf("expecting an error of ErrInvalidGateBundle, instead got %v", err)
+		}
+	})
+
+	t.Run("Tampered bundle", func(t *testing.T) {
+		tampered := bytes.Replace(bundle, []byte(`"generated_at"`), []byte(`"generated_aT"`), 1)
+
+		_, err := ticketing.OpenGateBundle(tampered, keyring)
+		if !errors.Is(err, ticketing.ErrInvalidGateBundle) {
+			t.Errorf("expecting an error of ErrInvalidGateBundle, instead got %v", err)
+		}
+	})
+
+	t.Run("Verify offline", func(t *testing.T) {
+		verifier, err := ticketing.OpenGateBundle(bundle, keyring)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = verifier.Verify(unpaidPayload, "")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket for unpaid ticket, instead got %v", err)
+		}
+
+		_, err = verifier.Verify(paidPayload, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = verifier.Verify(paidPayload, "")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket for used ticket, instead got %v", err)
+		}
+
+		if redemptions := verifier.Flush(); len(redemptions) != 1 {
+			t.Errorf("expecting 1 queued redemption, got %d", len(redemptions))
+		}
+
+		if redemptions := verifier.Flush(); len(redemptions) != 0 {
+			t.Errorf("expecting queue to be empty after flush, got %d", len(redemptions))
+		}
+	})
+
+	t.Run("Reconcile double use across gates", func(t *testing.T) {
+		email := "johndoe+gate-double@example.com"
+		payload := issueTicket(t, ctx, email, true)
+
+		bundle, err := ticketDomain.ExportGateBundle(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		var redemptions []ticketing.OfflineRedemption
+		for i := 0; i < 2; i++ {
+			verifier, err := ticketing.OpenGateBundle(bundle, keyring)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			_, err = verifier.Verify(payload, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			redemptions = append(redemptions, verifier.Flush()...)
+		}
+
+		redemptions = append(redemptions, ticketing.OfflineRedemption{TicketId: 987654321, RedeemedAt: time.Now()})
+
+		report, err := ticketDomain.ReconcileRedemptions(ctx, redemptions)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if report.Accepted != 1 {
+			t.Errorf("expecting 1 accepted redemption, got %d", report.Accepted)
+		}
+
+		if len(report.Conflicts) != 2 {
+			t.Errorf("expecting 2 conflicts, got %d", len(report.Conflicts))
+		}
+
+		_, err = ticketDomain.VerifyTicket(ctx, payload, "")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket after reconciliation, instead got %v", err)
+		}
+	})
+
+	t.Run("Reconcile ineligible redemptions", func(t *testing.T) {
+		// storeReceipt stores a payment receipt for the email, then returns its ticket.
+		storeReceipt := func(t *testing.T, email string) ticketing.Ticketing {
+			err := ticketDomain.StorePaymentReceipt(ctx, user.User{Email: email}, strings.NewReader("Hello world! This is not a photo. Yet this will be a text file."), "text/plain")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: email, Limit: 1})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(tickets) == 0 {
+				t.Fatalf("expecting ticket for %s to exists, got none", email)
+			}
+
+			return tickets[0]
+		}
+
+		unpaid := storeReceipt(t, "johndoe+reconcile-unpaid@example.com")
+
+		rejected := storeReceipt(t, "johndoe+reconcile-rejected@example.com")
+		if err := ticketDomain.RejectPaymentReceipt(ctx, rejected.Id, "Blurry photo"); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		entitled := storeReceipt(t, "johndoe+reconcile-entitled@example.com")
+		if _, err := ticketDomain.ApprovePaymentReceipt(ctx, entitled.Id, []string{"day-1"}); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		generalAdmission := storeReceipt(t, "johndoe+reconcile-general@example.com")
+		if _, err := ticketDomain.ApprovePaymentReceipt(ctx, generalAdmission.Id, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		now := time.Now()
+		testCases := []struct {
+			name       string
+			redemption ticketing.OfflineRedemption
+			accepted   bool
+		}{
+			{name: "unpaid", redemption: ticketing.OfflineRedemption{TicketId: unpaid.Id, RedeemedAt: now}},
+			{name: "rejected", redemption: ticketing.OfflineRedemption{TicketId: rejected.Id, RedeemedAt: now}},
+			{name: "not entitled checkpoint", redemption: ticketing.OfflineRedemption{TicketId: entitled.Id, Checkpoint: "day-2", RedeemedAt: now}},
+			{name: "general admission on an entitled ticket", redemption: ticketing.OfflineRedemption{TicketId: entitled.Id, RedeemedAt: now}},
+			{name: "entitled checkpoint", redemption: ticketing.OfflineRedemption{TicketId: entitled.Id, Checkpoint: "day-1", RedeemedAt: now}, accepted: true},
+			{name: "general admission", redemption: ticketing.OfflineRedemption{TicketId: generalAdmission.Id, RedeemedAt: now}, accepted: true},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				report, err := ticketDomain.ReconcileRedemptions(ctx, []ticketing.OfflineRedemption{testCase.redemption})
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+
+				if testCase.accepted && (report.Accepted != 1 || len(report.Conflicts) != 0) {
+					t.Errorf("expecting the redemption to be accepted, got %+v", report)
+				}
+
+				if !testCase.accepted && (report.Accepted != 0 || len(report.Conflicts) != 1) {
+					t.Errorf("expecting the redemption to be a conflict, got %+v", report)
+				}
+			})
+		}
+	})
+}
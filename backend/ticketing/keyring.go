@@ -0,0 +1,124 @@
+package ticketing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"regexp"
+)
+
+// SigningKey is an ed25519 keypair on the Keyring. A key without the private key can only be used to
+// verify tickets that have been issued with it.
+type SigningKey struct {
+	// Id is embedded on every QR code payload signed by this key. An empty Id is reserved for the key
+	// that signs tickets issued before key IDs exist.
+	Id         string
+	PublicKey  ed25519.PublicKey
+	PrivateKey ed25519.PrivateKey
+}
+
+// keyIdPattern keeps key IDs safe to be embedded on the QR code payload.
+var keyIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Keyring holds the keys that sign and verify the QR code payloads. New tickets are signed with the active
+// key, while the rest are kept to verify tickets that have been issued before the key is rotated.
+type Keyring struct {
+	activeKeyId string
+	canSign     bool
+	keys        map[string]SigningKey
+}
+
+// NewKeyring creates a keyring that signs new tickets with the key identified by activeKeyId.
+func NewKeyring(activeKeyId string, keys []SigningKey) (*Keyring, error) {
+	keyring, err := newKeyring(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	activeKey, ok := keyring.keys[activeKeyId]
+	if !ok {
+		return nil, fmt.Errorf("active key %q is not on the keyring", activeKeyId)
+	}
+
+	if activeKey.PrivateKey == nil {
+		return nil, fmt.Errorf("active key %q does not have a private key", activeKeyId)
+	}
+
+	keyring.activeKeyId = activeKeyId
+	keyring.canSign = true
+	return keyring, nil
+}
+
+// NewVerificationKeyring creates a keyring that can only verify tickets, for example on the gates.
+func NewVerificationKeyring(publicKeys map[string]ed25519.PublicKey) (*Keyring, error) {
+	keys := make([]SigningKey, 0, len(publicKeys))
+	for id, publicKey := range publicKeys {
+		keys = append(keys, SigningKey{Id: id, PublicKey: publicKey})
+	}
+
+	return newKeyring(keys)
+}
+
+func newKeyring(keys []SigningKey) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("keyring is empty")
+	}
+
+	keyring := &Keyring{keys: make(map[string]SigningKey, len(keys))}
+	for _, key := range keys {
+		if key.Id != "" && !keyIdPattern.MatchString(key.Id) {
+			return nil, fmt.Errorf("invalid key id %q", key.Id)
+		}
+
+		if _, ok := keyring.keys[key.Id]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.Id)
+		}
+
+		if len(key.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key length for key %q", key.Id)
+		}
+
+		if key.PrivateKey != nil {
+			if len(key.PrivateKey) != ed25519.PrivateKeySize {
+				return nil, fmt.Errorf("invalid private key length for key %q", key.Id)
+			}
+
+			if !bytes.Equal(key.PrivateKey.Public().(ed25519.PublicKey), key.PublicKey) {
+				return nil, fmt.Errorf("mismatched private key and public key for key %q", key.Id)
+			}
+		}
+
+		keyring.keys[key.Id] = key
+	}
+
+	return keyring, nil
+}
+
+// ActiveKey returns the key that signs new tickets. It returns false on a verification only keyring.
+func (k *Keyring) ActiveKey() (SigningKey, bool) {
+	if !k.canSign {
+		return SigningKey{}, false
+	}
+
+	return k.keys[k.activeKeyId], true
+}
+
+// PublicKey returns the public key identified by the id.
+func (k *Keyring) PublicKey(id string) (ed25519.PublicKey, bool) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, false
+	}
+
+	return key.PublicKey, true
+}
+
+// PublicKeys returns every public key on the keyring, keyed by their IDs.
+func (k *Keyring) PublicKeys() map[string]ed25519.PublicKey {
+	publicKeys := make(map[string]ed25519.PublicKey, len(k.keys))
+	for id, key := range k.keys {
+		publicKeys[id] = key.PublicKey
+	}
+
+	return publicKeys
+}
@@ -0,0 +1,155 @@
+package ticketing_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"testing"
+
+	"conf/ticketing"
+)
+
+func TestNewKeyring(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+
+	otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+
+	tests := []struct {
+		name        string
+		activeKeyId string
+		keys        []ticketing.SigningKey
+	}{
+		{
+			name:        "empty keyring",
+			activeKeyId: "",
+			keys:        nil,
+		},
+		{
+			name:        "invalid key id",
+			activeKeyId: "2024.01",
+			keys:        []ticketing.SigningKey{{Id: "2024.01", PublicKey: publicKey, PrivateKey: privateKey}},
+		},
+		{
+			name:        "duplicate key id",
+			activeKeyId: "2024",
+			keys: []ticketing.SigningKey{
+				{Id: "2024", PublicKey: publicKey, PrivateKey: privateKey},
+				{Id: "2024", PublicKey: otherPublicKey},
+			},
+		},
+		{
+			name:        "mismatched keypair",
+			activeKeyId: "2024",
+			keys:        []ticketing.SigningKey{{Id: "2024", PublicKey: otherPublicKey, PrivateKey: privateKey}},
+		},
+		{
+			name:        "active key not exists",
+			activeKeyId: "2025",
+			keys:        []ticketing.SigningKey{{Id: "2024", PublicKey: publicKey, PrivateKey: privateKey}},
+		},
+		{
+			name:        "active key without private key",
+			activeKeyId: "2024",
+			keys:        []ticketing.SigningKey{{Id: "2024", PublicKey: publicKey}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyring, err := ticketing.NewKeyring(test.activeKeyId, test.keys)
+			if err == nil {
+				t.Error("expecting an error, got nil")
+			}
+
+			if keyring != nil {
+				t.Error("expecting keyring to be nil")
+			}
+		})
+	}
+}
+
+func TestParseTicketPayload_KeyRotation(t *testing.T) {
+	var keys []ticketing.SigningKey
+	for _, id := range []string{"", "2023", "2024"} {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generating new ed25519 key: %s", err.Error())
+		}
+
+		keys = append(keys, ticketing.SigningKey{Id: id, PublicKey: publicKey, PrivateKey: privateKey})
+	}
+	legacyKey, previousKey, activeKey := keys[0], keys[1], keys[2]
+
+	// Only the active key keeps its private key, the rest are kept for verification.
+	keyring, err := ticketing.NewKeyring("2024", []ticketing.SigningKey{
+		{Id: legacyKey.Id, PublicKey: legacyKey.PublicKey},
+		{Id: previousKey.Id, PublicKey: previousKey.PublicKey},
+		activeKey,
+	})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	hashedEmail := sha512.Sum384([]byte("johndoe@example.com"))
+	ticketPayload := ticketing.TicketPayload{TicketId: 1, HashedEmail: hashedEmail[:]}
+
+	t.Run("Signed by the active key", func(t *testing.T) {
+		parsed, err := ticketing.ParseTicketPayload(keyring, mustSign(t, ticketPayload, activeKey))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if parsed.KeyId != "2024" {
+			t.Errorf("expecting key id to be 2024, got %q", parsed.KeyId)
+		}
+	})
+
+	t.Run("Signed by a previous key", func(t *testing.T) {
+		parsed, err := ticketing.ParseTicketPayload(keyring, mustSign(t, ticketPayload, previousKey))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if parsed.KeyId != "2023" {
+			t.Errorf("expecting key id to be 2023, got %q", parsed.KeyId)
+		}
+	})
+
+	t.Run("Signed before key IDs exist", func(t *testing.T) {
+		parsed, err := ticketing.ParseTicketPayload(keyring, mustSign(t, ticketPayload, legacyKey))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if parsed.KeyId != "" {
+			t.Errorf("expecting key id to be empty, got %q", parsed.KeyId)
+		}
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		unknownKey := activeKey
+		unknownKey.Id = "2025"
+
+		_, err := ticketing.ParseTicketPayload(keyring, mustSign(t, ticketPayload, unknownKey))
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+	})
+
+	t.Run("Signed by another key under the active key ID", func(t *testing.T) {
+		forgedKey := previousKey
+		forgedKey.Id = "2024"
+
+		_, err := ticketing.ParseTicketPayload(keyring, mustSign(t, ticketPayload, forgedKey))
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package ticketing
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Repository is the storage layer that TicketDomain depends on. Two implementations are available:
+// NocoDBRepository and PostgresRepository, pick one based on the configured database driver.
+type Repository interface {
+	// InsertTicket stores a single new ticketing entry.
+	InsertTicket(ctx context.Context, ticket Ticketing) error
+	// ListTickets returns a single page of ticketing entries that matches the query, sorted by the newest
+	// CreatedAt first. isLastPage will be true if there are no more entries after the returned page.
+	ListTickets(ctx context.Context, query TicketQuery) (tickets []Ticketing, isLastPage bool, err error)
+	// UpdateTicket partially updates a ticketing entry identified by ticket.Id. Only valid fields are written.
+	UpdateTicket(ctx context.Context, ticket NullTicketing) error
+	// ReviewTicket is UpdateTicket on an entry that has been neither approved nor rejected, in a compare-and-set
+	// manner. Out of many concurrent reviews of the same entry, exactly one succeeds, the rest returns
+	// ErrPaymentAlreadyReviewed.
+	ReviewTicket(ctx context.Context, ticket NullTicketing) error
+	// RedeemTicket records a redemption of the ticket on the checkpoint in a compare-and-set manner. Out of
+	// many concurrent calls for the same ticket and checkpoint, exactly one succeeds, the rest returns
+	// ErrInvalidTicket. An empty checkpoint redeems the ticket as a whole, which can only be done once.
+	RedeemTicket(ctx context.Context, id int64, checkpoint string, redeemedAt time.Time) error
+}
+
+// TicketQuery narrows down the entries returned by Repository.ListTickets. Zero-valued fields are not filtered.
+type TicketQuery struct {
+	Id     sql.NullInt64
+	Email  string
+	Used   sql.NullBool
+	Offset int64
+	// Limit defaults to the storage's page size if it's zero.
+	Limit int64
+}
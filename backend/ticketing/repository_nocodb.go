@@ -0,0 +1,199 @@
+package ticketing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"conf/nocodb"
+)
+
+// nocodbPageSize is NocoDB's page size when a request does not set a limit.
+const nocodbPageSize = 25
+
+// NocoDBRepository implements Repository on top of a NocoDB table.
+type NocoDBRepository struct {
+	tickets *nocodb.Table[Ticketing]
+	// updates shares the table with tickets, NullTicketing only sends the fields that are set.
+	updates *nocodb.Table[NullTicketing]
+	// ticketLocks serializes RedeemTicket and ReviewTicket calls per ticket, striped by the ticket id. NocoDB
+	// doesn't provide conditional updates, so this only protects against concurrent scans and reviews within a
+	// single process. Use PostgresRepository if you are running more than one instance.
+	ticketLocks [64]sync.Mutex
+}
+
+func NewNocoDBRepository(db *nocodb.Client, tableId string) (*NocoDBRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	if tableId == "" {
+		return nil, fmt.Errorf("tableId is empty")
+	}
+
+	tickets, err := nocodb.NewTable[Ticketing](db, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := nocodb.NewTable[NullTicketing](db, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NocoDBRepository{tickets: tickets, updates: updates}, nil
+}
+
+func (n *NocoDBRepository) InsertTicket(ctx context.Context, ticket Ticketing) error {
+	_, err := n.tickets.Create(ctx, ticket)
+	if err != nil {
+		return fmt.Errorf("creating table records: %w", err)
+	}
+
+	return nil
+}
+
+func (n *NocoDBRepository) ListTickets(ctx context.Context, query TicketQuery) ([]Ticketing, bool, error) {
+	var conditions []nocodb.Filter
+	if query.Id.Valid {
+		conditions = append(conditions, nocodb.Eq("Id", query.Id.Int64))
+	}
+
+	if query.Email != "" {
+		conditions = append(conditions, nocodb.Like("Email", query.Email))
+	}
+
+	if query.Used.Valid {
+		conditions = append(conditions, nocodb.Eq("Used", query.Used.Bool))
+	}
+
+	options := nocodb.ListTableRecordOptions{
+		Where:  nocodb.And(conditions...).String(),
+		Sort:   []nocodb.Sort{nocodb.SortDescending("CreatedAt")},
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	}
+	if query.Email != "" {
+		return n.listTicketsByEmail(ctx, query.Email, options)
+	}
+
+	tickets, pageInfo, err := n.tickets.List(ctx, options)
+	if err != nil {
+		return nil, false, fmt.Errorf("list table records: %w", err)
+	}
+
+	return tickets, pageInfo.IsLastPage, nil
+}
+
+// listTicketsByEmail matches the email case-insensitively, the same way as the Postgres repository. NocoDB's
+// like is case-insensitive but also matches a substring, so every candidate is read and the page is cut from
+// the exact matches. An email only has a handful of tickets.
+func (n *NocoDBRepository) listTicketsByEmail(ctx context.Context, email string, options nocodb.ListTableRecordOptions) ([]Ticketing, bool, error) {
+	offset, limit := options.Offset, options.Limit
+	if limit <= 0 {
+		limit = nocodbPageSize
+	}
+
+	options.Offset = 0
+	options.Limit = 0
+
+	var matches []Ticketing
+	for ticket, err := range n.tickets.All(ctx, options) {
+		if err != nil {
+			return nil, false, fmt.Errorf("list table records: %w", err)
+		}
+
+		if strings.EqualFold(ticket.Email, email) {
+			matches = append(matches, ticket)
+		}
+	}
+
+	if offset >= int64(len(matches)) {
+		return nil, true, nil
+	}
+
+	end := min(offset+limit, int64(len(matches)))
+	return matches[offset:end], end == int64(len(matches)), nil
+}
+
+func (n *NocoDBRepository) UpdateTicket(ctx context.Context, ticket NullTicketing) error {
+	err := n.updates.Update(ctx, ticket)
+	if err != nil {
+		return fmt.Errorf("updating table records: %w", err)
+	}
+
+	return nil
+}
+
+func (n *NocoDBRepository) ReviewTicket(ctx context.Context, ticket NullTicketing) error {
+	if !ticket.Id.Valid {
+		return fmt.Errorf("ticket id is required")
+	}
+
+	lock := &n.ticketLocks[uint64(ticket.Id.Int64)%uint64(len(n.ticketLocks))]
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Re-check the entry while holding the lock, another review might have finished in the meantime.
+	current, err := n.tickets.Get(ctx, ticket.Id.Int64, nocodb.ReadTableRecordsOptions{
+		Fields: []string{"Id", "Paid", "Rejected"},
+	})
+	if err != nil {
+		return fmt.Errorf("reading table records: %w", err)
+	}
+
+	if current.Paid || current.Rejected {
+		return ErrPaymentAlreadyReviewed
+	}
+
+	err = n.updates.Update(ctx, ticket)
+	if err != nil {
+		return fmt.Errorf("updating table records: %w", err)
+	}
+
+	return nil
+}
+
+func (n *NocoDBRepository) RedeemTicket(ctx context.Context, id int64, checkpoint string, redeemedAt time.Time) error {
+	lock := &n.ticketLocks[uint64(id)%uint64(len(n.ticketLocks))]
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Re-check the entry while holding the lock, another scan might have redeemed it in the meantime.
+	ticket, err := n.tickets.Get(ctx, id, nocodb.ReadTableRecordsOptions{
+		Fields: []string{"Id", "Used", "RedeemedCheckpoints"},
+	})
+	if err != nil {
+		return fmt.Errorf("reading table records: %w", err)
+	}
+
+	update := NullTicketing{
+		Id:        sql.NullInt64{Int64: id, Valid: true},
+		Used:      sql.NullBool{Bool: true, Valid: true},
+		UpdatedAt: sql.NullTime{Time: redeemedAt, Valid: true},
+	}
+
+	if checkpoint == "" {
+		if ticket.Used {
+			return fmt.Errorf("%w: already used", ErrInvalidTicket)
+		}
+	} else {
+		redeemedCheckpoints := splitList(ticket.RedeemedCheckpoints)
+		if slices.Contains(redeemedCheckpoints, checkpoint) {
+			return fmt.Errorf("%w: already used on %s", ErrInvalidTicket, checkpoint)
+		}
+
+		update.RedeemedCheckpoints = sql.NullString{String: strings.Join(append(redeemedCheckpoints, checkpoint), ","), Valid: true}
+	}
+
+	err = n.updates.Update(ctx, update)
+	if err != nil {
+		return fmt.Errorf("updating table records: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+package ticketing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"conf/i18n"
+)
+
+// postgresPageSize mimics NocoDB's default page size, so both repositories behave the same way.
+const postgresPageSize = 25
+
+// PostgresRepository implements Repository on top of the `ticketing` table. The schema lives on the
+// migrations directory, make sure it's been migrated before using this repository.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) (*PostgresRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &PostgresRepository{db: db}, nil
+}
+
+func (p *PostgresRepository) InsertTicket(ctx context.Context, ticket Ticketing) error {
+	_, err := p.db.ExecContext(
+		ctx,
+		`INSERT INTO ticketing (email, receipt_photo_path, paid, student, sha256sum, used, entitlements, version, revoked, rejected, rejection_reason, locale, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		ticket.Email,
+		ticket.ReceiptPhotoPath,
+		ticket.Paid,
+		ticket.Student,
+		ticket.SHA256Sum,
+		ticket.Used,
+		ticket.Entitlements,
+		ticket.Version,
+		ticket.Revoked,
+		ticket.Rejected,
+		ticket.RejectionReason,
+		localeOrDefault(ticket.Locale),
+		ticket.CreatedAt,
+		ticket.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting ticketing: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PostgresRepository) ListTickets(ctx context.Context, query TicketQuery) ([]Ticketing, bool, error) {
+	var conditions []string
+	var args []any
+	if query.Id.Valid {
+		args = append(args, query.Id.Int64)
+		conditions = append(conditions, "id = $"+strconv.Itoa(len(args)))
+	}
+
+	if query.Email != "" {
+		args = append(args, query.Email)
+		conditions = append(conditions, "lower(email) = lower($"+strconv.Itoa(len(args))+")")
+	}
+
+	if query.Used.Valid {
+		args = append(args, query.Used.Bool)
+		conditions = append(conditions, "COALESCE(used, FALSE) = $"+strconv.Itoa(len(args)))
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = postgresPageSize
+	}
+
+	statement := `SELECT
+			id,
+			email,
+			receipt_photo_path,
+			COALESCE(paid, FALSE),
+			COALESCE(student, FALSE),
+			COALESCE(sha256sum, ''),
+			COALESCE(used, FALSE),
+			entitlements,
+			COALESCE(
+				(SELECT string_agg(checkpoint, ',' ORDER BY redeemed_at) FROM ticket_redemptions WHERE ticket_id = ticketing.id),
+				''
+			),
+			version,
+			revoked,
+			COALESCE(rejected, FALSE),
+			COALESCE(rejection_reason, ''),
+			locale,
+			created_at,
+			updated_at
+		FROM ticketing`
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// Fetch one more row than requested to know whether there is another page after this one.
+	args = append(args, limit+1, query.Offset)
+	statement += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := p.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("querying ticketing: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var tickets []Ticketing
+	for rows.Next() {
+		var ticket Ticketing
+		err := rows.Scan(
+			&ticket.Id,
+			&ticket.Email,
+			&ticket.ReceiptPhotoPath,
+			&ticket.Paid,
+			&ticket.Student,
+			&ticket.SHA256Sum,
+			&ticket.Used,
+			&ticket.Entitlements,
+			&ticket.RedeemedCheckpoints,
+			&ticket.Version,
+			&ticket.Revoked,
+			&ticket.Rejected,
+			&ticket.RejectionReason,
+			&ticket.Locale,
+			&ticket.CreatedAt,
+			&ticket.UpdatedAt,
+		)
+		if err != nil {
+			return nil, false, fmt.Errorf("scanning row: %w", err)
+		}
+
+		tickets = append(tickets, ticket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	if int64(len(tickets)) > limit {
+		return tickets[:limit], false, nil
+	}
+
+	return tickets, true, nil
+}
+
+// UpdateTicket partially updates a ticketing entry. RedeemedCheckpoints is ignored, as redemptions are
+// stored on their own table by RedeemTicket.
+func (p *PostgresRepository) UpdateTicket(ctx context.Context, ticket NullTicketing) error {
+	_, err := p.updateTicket(ctx, ticket, "")
+	return err
+}
+
+// ReviewTicket updates the entry in the same statement that checks it's still pending. The row lock acquired by
+// UPDATE makes a concurrent review wait, then re-evaluate the condition against the committed value.
+func (p *PostgresRepository) ReviewTicket(ctx context.Context, ticket NullTicketing) error {
+	affected, err := p.updateTicket(ctx, ticket, "NOT COALESCE(paid, FALSE) AND NOT COALESCE(rejected, FALSE)")
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrPaymentAlreadyReviewed
+	}
+
+	return nil
+}
+
+// updateTicket writes the valid fields of the ticket, on the condition if it's not empty. It returns the number
+// of affected rows.
+func (p *PostgresRepository) updateTicket(ctx context.Context, ticket NullTicketing, condition string) (int64, error) {
+	if !ticket.Id.Valid {
+		return 0, fmt.Errorf("ticket id is required")
+	}
+
+	var assignments []string
+	var args []any
+	set := func(column string, value any) {
+		args = append(args, value)
+		assignments = append(assignments, column+" = $"+strconv.Itoa(len(args)))
+	}
+
+	if ticket.Email.Valid {
+		set("email", ticket.Email.String)
+	}
+
+	if ticket.ReceiptPhotoPath.Valid {
+		set("receipt_photo_path", ticket.ReceiptPhotoPath.String)
+	}
+
+	if ticket.Paid.Valid {
+		set("paid", ticket.Paid.Bool)
+	}
+
+	if ticket.Student.Valid {
+		set("student", ticket.Student.Bool)
+	}
+
+	if ticket.SHA256Sum.Valid {
+		set("sha256sum", ticket.SHA256Sum.String)
+	}
+
+	if ticket.Used.Valid {
+		set("used", ticket.Used.Bool)
+	}
+
+	if ticket.Entitlements.Valid {
+		set("entitlements", ticket.Entitlements.String)
+	}
+
+	if ticket.Version.Valid {
+		set("version", ticket.Version.Int64)
+	}
+
+	if ticket.Revoked.Valid {
+		set("revoked", ticket.Revoked.Bool)
+	}
+
+	if ticket.Rejected.Valid {
+		set("rejected", ticket.Rejected.Bool)
+	}
+
+	if ticket.RejectionReason.Valid {
+		set("rejection_reason", ticket.RejectionReason.String)
+	}
+
+	if ticket.Locale.Valid {
+		set("locale", localeOrDefault(i18n.Locale(ticket.Locale.String)))
+	}
+
+	if ticket.CreatedAt.Valid {
+		set("created_at", ticket.CreatedAt.Time)
+	}
+
+	if ticket.UpdatedAt.Valid {
+		set("updated_at", ticket.UpdatedAt.Time)
+	}
+
+	if len(assignments) == 0 {
+		return 0, nil
+	}
+
+	args = append(args, ticket.Id.Int64)
+	statement := "UPDATE ticketing SET " + strings.Join(assignments, ", ") + " WHERE id = $" + strconv.Itoa(len(args))
+	if condition != "" {
+		statement += " AND " + condition
+	}
+
+	result, err := p.db.ExecContext(ctx, statement, args...)
+	if err != nil {
+		return 0, fmt.Errorf("updating ticketing: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("acquiring affected rows: %w", err)
+	}
+
+	return affected, nil
+}
+
+// localeOrDefault keeps the NOT NULL locale column valid for tickets created before the attendee had a locale.
+func localeOrDefault(locale i18n.Locale) string {
+	if locale == "" {
+		return string(i18n.Default)
+	}
+
+	return string(locale)
+}
+
+func (p *PostgresRepository) RedeemTicket(ctx context.Context, id int64, checkpoint string, redeemedAt time.Time) error {
+	if checkpoint == "" {
+		// The row lock acquired by UPDATE makes concurrent redemptions wait, then re-evaluate the
+		// WHERE clause against the committed value. Only the first one affects the row.
+		result, err := p.db.ExecContext(
+			ctx,
+			`UPDATE ticketing SET used = TRUE, updated_at = $2 WHERE id = $1 AND COALESCE(used, FALSE) = FALSE`,
+			id,
+			redeemedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("updating ticketing: %w", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("acquiring affected rows: %w", err)
+		}
+
+		if affected == 0 {
+			return fmt.Errorf("%w: already used", ErrInvalidTicket)
+		}
+
+		return nil
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// The primary key of (ticket_id, checkpoint) only allows a single redemption per checkpoint.
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO ticket_redemptions (ticket_id, checkpoint, redeemed_at) VALUES ($1, $2, $3)
+		ON CONFLICT (ticket_id, checkpoint) DO NOTHING`,
+		id,
+		checkpoint,
+		redeemedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting ticket redemption: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("acquiring affected rows: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: already used on %s", ErrInvalidTicket, checkpoint)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE ticketing SET used = TRUE, updated_at = $2 WHERE id = $1`, id, redeemedAt)
+	if err != nil {
+		return fmt.Errorf("updating ticketing: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package ticketing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"conf/mailer"
+	"conf/mailtemplate"
+	"conf/user"
+	"github.com/getsentry/sentry-go"
+	"gocloud.dev/blob"
+)
+
+// PendingPayment is a payment receipt that has not been approved nor rejected yet.
+type PendingPayment struct {
+	Ticketing
+	// ReceiptUrl is a short-lived signed URL to the receipt photo on the blob bucket.
+	ReceiptUrl string
+}
+
+// ListPendingPayments lists the latest payment receipt of every email that has not been reviewed yet,
+// oldest first, so the earliest uploader gets reviewed first. Each entry comes with a signed URL to the
+// receipt photo that expires after receiptUrlExpiry.
+func (t *TicketDomain) ListPendingPayments(ctx context.Context, receiptUrlExpiry time.Duration) ([]PendingPayment, error) {
+	span := sentry.StartSpan(ctx, "ticketing.list_pending_payments", sentry.WithTransactionName("ListPendingPayments"))
+	defer span.Finish()
+
+	// Only the latest receipt of an email matters, older ones are superseded by the newer upload.
+	seenEmails := make(map[string]struct{})
+	var pendingPayments []PendingPayment
+	var offset int64
+	for {
+		tickets, isLastPage, err := t.repository.ListTickets(ctx, TicketQuery{Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("listing tickets: %w", err)
+		}
+
+		offset += int64(len(tickets))
+
+		for _, ticket := range tickets {
+			if _, ok := seenEmails[ticket.Email]; ok {
+				continue
+			}
+			seenEmails[ticket.Email] = struct{}{}
+
+			if ticket.Paid || ticket.Rejected || ticket.ReceiptPhotoPath == "" {
+				continue
+			}
+
+			receiptUrl, err := t.bucket.SignedURL(ctx, ticket.ReceiptPhotoPath, &blob.SignedURLOptions{
+				Expiry: receiptUrlExpiry,
+				Method: http.MethodGet,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("signing receipt url: %w", err)
+			}
+
+			pendingPayments = append(pendingPayments, PendingPayment{Ticketing: ticket, ReceiptUrl: receiptUrl})
+		}
+
+		if isLastPage || len(tickets) == 0 {
+			break
+		}
+	}
+
+	// Tickets are listed newest first, reverse it.
+	for i, j := 0, len(pendingPayments)-1; i < j; i, j = i+1, j-1 {
+		pendingPayments[i], pendingPayments[j] = pendingPayments[j], pendingPayments[i]
+	}
+
+	return pendingPayments, nil
+}
+
+// ApprovePaymentReceipt approves the pending payment receipt identified by the ticket id, then sends the ticket
+// to the attendee. Only the reviewed entry is marked as paid, even if the attendee uploaded a newer receipt
+// since. The entitlements lists the checkpoint IDs the ticket can be redeemed on, leave it empty to issue a
+// general admission ticket.
+//
+// It will return ErrInvalidTicket if the ticket does not exist, and ErrPaymentAlreadyReviewed if it's been
+// approved or rejected before, including by a concurrent review.
+func (t *TicketDomain) ApprovePaymentReceipt(ctx context.Context, id int64, entitlements []string) (string, error) {
+	span := sentry.StartSpan(ctx, "ticketing.approve_payment_receipt", sentry.WithTransactionName("ApprovePaymentReceipt"))
+	defer span.Finish()
+
+	if err := validateCheckpoints(entitlements); err != nil {
+		return "", err
+	}
+
+	ticketing, err := t.getReviewableTicket(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	review := NullTicketing{
+		Id:        sql.NullInt64{Int64: ticketing.Id, Valid: true},
+		Paid:      sql.NullBool{Bool: true, Valid: true},
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	if len(entitlements) > 0 {
+		entitlements = slices.Clone(entitlements)
+		slices.Sort(entitlements)
+		entitlements = slices.Compact(entitlements)
+
+		ticketing.Entitlements = strings.Join(entitlements, ",")
+		review.Entitlements = sql.NullString{String: ticketing.Entitlements, Valid: true}
+	}
+
+	payload, qrImage, sha256Sum, err := renderTicketQrCode(ticketing, t.keyring)
+	if err != nil {
+		return "", err
+	}
+
+	// The payment is persisted before the mail goes out, the same way as validateTicket. Only one of the
+	// concurrent reviews gets past this, so the ticket is never mailed twice.
+	review.SHA256Sum = sql.NullString{String: hex.EncodeToString(sha256Sum), Valid: true}
+	err = t.repository.ReviewTicket(ctx, review)
+	if err != nil {
+		if errors.Is(err, ErrPaymentAlreadyReviewed) {
+			return "", err
+		}
+
+		return "", fmt.Errorf("updating ticket: %w", err)
+	}
+
+	attendee := t.attendee(ctx, ticketing)
+	if attendee.Locale != "" {
+		ticketing.Locale = attendee.Locale
+	}
+
+	err = t.sendTicketMail(ctx, ticketing, attendee.Name, payload, qrImage, sha256Sum)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(sha256Sum), nil
+}
+
+// RejectPaymentReceipt rejects a pending payment receipt identified by the ticket id, then emails the
+// attendee with the reason so they can upload a new one.
+//
+// It will return ErrInvalidTicket if the ticket does not exist, and ErrPaymentAlreadyReviewed if it's been
+// approved or rejected before, including by a concurrent review.
+func (t *TicketDomain) RejectPaymentReceipt(ctx context.Context, id int64, reason string) error {
+	span := sentry.StartSpan(ctx, "ticketing.reject_payment_receipt", sentry.WithTransactionName("RejectPaymentReceipt"))
+	defer span.Finish()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ValidationError{Errors: []string{"reason is empty"}}
+	}
+
+	ticketing, err := t.getReviewableTicket(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = t.repository.ReviewTicket(ctx, NullTicketing{
+		Id:              sql.NullInt64{Int64: ticketing.Id, Valid: true},
+		Rejected:        sql.NullBool{Bool: true, Valid: true},
+		RejectionReason: sql.NullString{String: reason, Valid: true},
+		UpdatedAt:       sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, ErrPaymentAlreadyReviewed) {
+			return err
+		}
+
+		return fmt.Errorf("updating ticket: %w", err)
+	}
+
+	rendered, err := t.templates.Render(mailtemplate.PaymentRejected, ticketing.Locale, map[string]any{
+		"email":  ticketing.Email,
+		"reason": reason,
+	})
+	if err != nil {
+		return fmt.Errorf("rendering payment rejected mail: %w", err)
+	}
+
+	err = t.mailer.Send(ctx, &mailer.Mail{
+		RecipientName:  "",
+		RecipientEmail: ticketing.Email,
+		Subject:        rendered.Subject,
+		PlainTextBody:  rendered.PlainTextBody,
+		HtmlBody:       rendered.HtmlBody,
+	})
+	if err != nil {
+		return fmt.Errorf("sending mail: %w", err)
+	}
+
+	return nil
+}
+
+func (t *TicketDomain) getReviewableTicket(ctx context.Context, id int64) (Ticketing, error) {
+	tickets, _, err := t.repository.ListTickets(ctx, TicketQuery{
+		Id:    sql.NullInt64{Int64: id, Valid: true},
+		Limit: 1,
+	})
+	if err != nil {
+		return Ticketing{}, fmt.Errorf("acquiring records: %w", err)
+	}
+
+	if len(tickets) == 0 {
+		return Ticketing{}, fmt.Errorf("%w: not exists", ErrInvalidTicket)
+	}
+
+	if tickets[0].Paid || tickets[0].Rejected {
+		return Ticketing{}, ErrPaymentAlreadyReviewed
+	}
+
+	return tickets[0], nil
+}
+
+// attendee looks up the registration of the ticket's email. The ticket is still sent if the lookup fails, only
+// without the attendee's name.
+func (t *TicketDomain) attendee(ctx context.Context, ticketing Ticketing) user.User {
+	if t.users == nil {
+		return user.User{Email: ticketing.Email}
+	}
+
+	attendee, err := t.users.GetUserByEmail(ctx, ticketing.Email)
+	if err != nil {
+		if !errors.Is(err, user.ErrUserEmailNotFound) {
+			if hub := sentry.GetHubFromContext(ctx); hub != nil {
+				hub.CaptureException(err)
+			}
+		}
+
+		return user.User{Email: ticketing.Email}
+	}
+
+	return attendee
+}
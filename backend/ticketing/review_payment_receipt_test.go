@@ -0,0 +1,286 @@
+package ticketing_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"conf/mailer"
+	"conf/ticketing"
+	"conf/user"
+)
+
+func TestTicketDomain_ReviewPaymentReceipt(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+		return
+	}
+
+	signingKey := ticketing.SigningKey{PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating a ticket domain instance: %s", err.Error())
+	}
+
+	// findPendingPayment stores a payment receipt for the email, then looks for it on the review queue.
+	findPendingPayment := func(t *testing.T, ctx context.Context, email string) ticketing.PendingPayment {
+		err := ticketDomain.StorePaymentReceipt(ctx, user.User{Email: email}, strings.NewReader("Hello world! This is not a photo. Yet this will be a text file."), "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		pendingPayments, err := ticketDomain.ListPendingPayments(ctx, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		for _, pendingPayment := range pendingPayments {
+			if pendingPayment.Email == email {
+				if pendingPayment.ReceiptUrl == "" {
+					t.Error("expecting receipt url to have value, got empty string")
+				}
+
+				return pendingPayment
+			}
+		}
+
+		t.Fatalf("expecting %s to be on the review queue, got none", email)
+		return ticketing.PendingPayment{}
+	}
+
+	isPending := func(t *testing.T, ctx context.Context, email string) bool {
+		pendingPayments, err := ticketDomain.ListPendingPayments(ctx, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		for _, pendingPayment := range pendingPayments {
+			if pendingPayment.Email == email {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	t.Run("Approve", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		email := "johndoe+approve@example.com"
+		pendingPayment := findPendingPayment(t, ctx, email)
+
+		sum, err := ticketDomain.ApprovePaymentReceipt(ctx, pendingPayment.Id, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if sum == "" {
+			t.Error("expecting sum to have value, got empty string")
+		}
+
+		if isPending(t, ctx, email) {
+			t.Errorf("expecting %s to be removed from the review queue", email)
+		}
+
+		_, err = ticketDomain.ApprovePaymentReceipt(ctx, pendingPayment.Id, nil)
+		if !errors.Is(err, ticketing.ErrPaymentAlreadyReviewed) {
+			t.Errorf("expecting an error of ErrPaymentAlreadyReviewed, instead got %v", err)
+		}
+	})
+
+	t.Run("Approve with entitlements", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		email := "johndoe+entitlements@example.com"
+		pendingPayment := findPendingPayment(t, ctx, email)
+
+		_, err := ticketDomain.ApprovePaymentReceipt(ctx, pendingPayment.Id, []string{"Day 1"})
+		var validationError ticketing.ValidationError
+		if !errors.As(err, &validationError) {
+			t.Errorf("expecting a validation error, instead got %v", err)
+		}
+
+		_, err = ticketDomain.ApprovePaymentReceipt(ctx, pendingPayment.Id, []string{"workshop-a", "day-1", "day-1"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: email, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(tickets) == 0 {
+			t.Fatalf("expecting ticket for %s to exists, got none", email)
+		}
+
+		if tickets[0].Entitlements != "day-1,workshop-a" {
+			t.Errorf("expecting entitlements to be day-1,workshop-a, got %s", tickets[0].Entitlements)
+		}
+	})
+
+	t.Run("Approve the reviewed receipt only", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		email := "johndoe+newer-upload@example.com"
+		pendingPayment := findPendingPayment(t, ctx, email)
+
+		// The attendee uploads another receipt while the first one is being reviewed.
+		newerPayment := findPendingPayment(t, ctx, email)
+		if newerPayment.Id == pendingPayment.Id {
+			t.Fatalf("expecting the newer receipt to be another entry, got %d", newerPayment.Id)
+		}
+
+		_, err := ticketDomain.ApprovePaymentReceipt(ctx, pendingPayment.Id, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		for id, expectPaid := range map[int64]bool{pendingPayment.Id: true, newerPayment.Id: false} {
+			tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Id: sql.NullInt64{Int64: id, Valid: true}, Limit: 1})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(tickets) != 1 || tickets[0].Paid != expectPaid {
+				t.Errorf("expecting ticket %d to have paid %t, got %+v", id, expectPaid, tickets)
+			}
+		}
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		email := "johndoe+reject@example.com"
+		pendingPayment := findPendingPayment(t, ctx, email)
+
+		err := ticketDomain.RejectPaymentReceipt(ctx, pendingPayment.Id, "")
+		var validationError ticketing.ValidationError
+		if !errors.As(err, &validationError) {
+			t.Errorf("expecting a validation error, instead got %v", err)
+		}
+
+		err = ticketDomain.RejectPaymentReceipt(ctx, pendingPayment.Id, "The transfer amount does not match")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if isPending(t, ctx, email) {
+			t.Errorf("expecting %s to be removed from the review queue", email)
+		}
+
+		_, err = ticketDomain.ApprovePaymentReceipt(ctx, pendingPayment.Id, nil)
+		if !errors.Is(err, ticketing.ErrPaymentAlreadyReviewed) {
+			t.Errorf("expecting an error of ErrPaymentAlreadyReviewed, instead got %v", err)
+		}
+
+		// Uploading a new receipt puts the email back on the queue
+		findPendingPayment(t, ctx, email)
+	})
+
+	t.Run("Not exists", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := ticketDomain.ApprovePaymentReceipt(ctx, 987654321, nil)
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+	})
+
+	t.Run("Concurrent reviews of the same receipt", func(t *testing.T) {
+		for _, testCase := range []struct {
+			name  string
+			email string
+			// reject makes every other reviewer reject the receipt instead of approving it.
+			reject bool
+		}{
+			{name: "approve and approve", email: "johndoe+concurrent-approve@example.com"},
+			{name: "approve and reject", email: "johndoe+concurrent-reject@example.com", reject: true},
+		} {
+			t.Run(testCase.name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+
+				mailTransport := mailer.NewMemoryTransport()
+				ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailer.NewMailSenderWithTransport(mailTransport, mailer.DefaultFrom), mailTemplates, ticketing.TicketDomainOptions{})
+				if err != nil {
+					t.Fatalf("creating a ticket domain instance: %s", err.Error())
+				}
+
+				pendingPayment := findPendingPayment(t, ctx, testCase.email)
+				delivered := len(mailTransport.Messages())
+
+				const reviewers = 50
+				var wg sync.WaitGroup
+				var mu sync.Mutex
+				var succeeded int
+				var unexpectedErrors []error
+				start := make(chan struct{})
+				for i := 0; i < reviewers; i++ {
+					wg.Add(1)
+					go func(reject bool) {
+						defer wg.Done()
+						<-start
+
+						var err error
+						if reject {
+							err = ticketDomain.RejectPaymentReceipt(ctx, pendingPayment.Id, "Blurry photo")
+						} else {
+							_, err = ticketDomain.ApprovePaymentReceipt(ctx, pendingPayment.Id, nil)
+						}
+
+						mu.Lock()
+						defer mu.Unlock()
+						switch {
+						case err == nil:
+							succeeded++
+						case !errors.Is(err, ticketing.ErrPaymentAlreadyReviewed):
+							unexpectedErrors = append(unexpectedErrors, err)
+						}
+					}(testCase.reject && i%2 == 1)
+				}
+
+				close(start)
+				wg.Wait()
+
+				for _, err := range unexpectedErrors {
+					t.Errorf("unexpected error: %s", err.Error())
+				}
+
+				if succeeded != 1 {
+					t.Errorf("expecting exactly one review to succeed, got %d", succeeded)
+				}
+
+				if mails := len(mailTransport.Messages()) - delivered; mails != 1 {
+					t.Errorf("expecting exactly one mail to be sent, got %d", mails)
+				}
+
+				tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Id: sql.NullInt64{Int64: pendingPayment.Id, Valid: true}})
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+
+				if len(tickets) != 1 || tickets[0].Paid == tickets[0].Rejected {
+					t.Errorf("expecting the ticket to be either paid or rejected, got %+v", tickets)
+				}
+			})
+		}
+	})
+}
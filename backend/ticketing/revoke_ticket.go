@@ -0,0 +1,108 @@
+package ticketing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// RevokeTicket invalidates every QR code issued for the ticket identified by the id. The ticket can be
+// brought back with ReissueTicket, which sends a new QR code to the attendee.
+//
+// It will return ErrInvalidTicket if the ticket does not exist or has not been issued yet.
+func (t *TicketDomain) RevokeTicket(ctx context.Context, id int64) error {
+	span := sentry.StartSpan(ctx, "ticketing.revoke_ticket", sentry.WithTransactionName("RevokeTicket"))
+	defer span.Finish()
+
+	ticketing, err := t.GetIssuedTicket(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = t.repository.UpdateTicket(ctx, NullTicketing{
+		Id:        sql.NullInt64{Int64: ticketing.Id, Valid: true},
+		Revoked:   sql.NullBool{Bool: true, Valid: true},
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("updating ticket: %w", err)
+	}
+
+	return nil
+}
+
+// ReissueTicket mints a new QR code for the ticket identified by the id and sends it to the attendee. The
+// version of the ticket is bumped, so every QR code issued before is rejected by VerifyTicket with
+// ErrRevokedTicket. It lifts the revocation done by RevokeTicket. It returns hex-encoded SHA256SUM of the
+// new QR code.
+//
+// It will return ErrInvalidTicket if the ticket does not exist or has not been issued yet.
+func (t *TicketDomain) ReissueTicket(ctx context.Context, id int64) (string, error) {
+	span := sentry.StartSpan(ctx, "ticketing.reissue_ticket", sentry.WithTransactionName("ReissueTicket"))
+	defer span.Finish()
+
+	ticketing, err := t.GetIssuedTicket(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	ticketing.Version++
+	ticketing.Revoked = false
+
+	attendee := t.attendee(ctx, ticketing)
+	if attendee.Locale != "" {
+		ticketing.Locale = attendee.Locale
+	}
+
+	payload, qrImage, sha256Sum, err := renderTicketQrCode(ticketing, t.keyring)
+	if err != nil {
+		return "", err
+	}
+
+	// Store the new version before sending the mail, so the leaked QR code stops working right away even if
+	// the mail fails to be delivered. Calling it again sends yet another version.
+	err = t.repository.UpdateTicket(ctx, NullTicketing{
+		Id:        sql.NullInt64{Int64: ticketing.Id, Valid: true},
+		SHA256Sum: sql.NullString{String: hex.EncodeToString(sha256Sum), Valid: true},
+		Version:   sql.NullInt64{Int64: ticketing.Version, Valid: true},
+		Revoked:   sql.NullBool{Bool: false, Valid: true},
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return "", fmt.Errorf("updating ticket: %w", err)
+	}
+
+	err = t.sendTicketMail(ctx, ticketing, attendee.Name, payload, qrImage, sha256Sum)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(sha256Sum), nil
+}
+
+// GetIssuedTicket returns the ticket identified by the id, only if its QR code has been issued.
+//
+// It will return ErrInvalidTicket if the ticket does not exist or has not been issued yet.
+func (t *TicketDomain) GetIssuedTicket(ctx context.Context, id int64) (Ticketing, error) {
+	tickets, _, err := t.repository.ListTickets(ctx, TicketQuery{
+		Id:    sql.NullInt64{Int64: id, Valid: true},
+		Limit: 1,
+	})
+	if err != nil {
+		return Ticketing{}, fmt.Errorf("acquiring records: %w", err)
+	}
+
+	if len(tickets) == 0 {
+		return Ticketing{}, fmt.Errorf("%w: not exists", ErrInvalidTicket)
+	}
+
+	if !tickets[0].Paid {
+		return Ticketing{}, fmt.Errorf("%w: not issued yet", ErrInvalidTicket)
+	}
+
+	return tickets[0], nil
+}
@@ -0,0 +1,172 @@
+package ticketing_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"conf/mailer"
+	"conf/ticketing"
+	"conf/user"
+)
+
+func TestTicketDomain_RevokeTicket(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+		return
+	}
+
+	signingKey := ticketing.SigningKey{PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating a ticket domain instance: %s", err.Error())
+	}
+
+	// storeTicket stores a payment receipt for the email, optionally approves it, and returns the ticket.
+	storeTicket := func(t *testing.T, ctx context.Context, email string, paid bool) ticketing.Ticketing {
+		err := ticketDomain.StorePaymentReceipt(ctx, user.User{Email: email}, strings.NewReader("Hello world! This is not a photo. Yet this will be a text file."), "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if paid {
+			_, err = ticketDomain.ValidatePaymentReceipt(ctx, user.User{Email: email})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: email, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(tickets) == 0 {
+			t.Fatalf("expecting ticket for %s to exists, got none", email)
+		}
+
+		return tickets[0]
+	}
+
+	signPayload := func(ticket ticketing.Ticketing, version int64) []byte {
+		hashedEmail := sha512.Sum384([]byte(ticket.Email))
+		return mustSign(t, ticketing.TicketPayload{TicketId: ticket.Id, HashedEmail: hashedEmail[:], Version: version}, signingKey)
+	}
+
+	t.Run("Not issued yet", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		ticket := storeTicket(t, ctx, "johndoe+revoke-unpaid@example.com", false)
+
+		err := ticketDomain.RevokeTicket(ctx, ticket.Id)
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+
+		_, err = ticketDomain.ReissueTicket(ctx, ticket.Id)
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+	})
+
+	t.Run("Not exists", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		err := ticketDomain.RevokeTicket(ctx, 987654321)
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+	})
+
+	t.Run("Reissue carries the attendee's name", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{})
+		if err != nil {
+			t.Fatalf("creating user domain instance: %s", err.Error())
+		}
+
+		mailTransport := mailer.NewMemoryTransport()
+		ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailer.NewMailSenderWithTransport(mailTransport, mailer.DefaultFrom), mailTemplates, ticketing.TicketDomainOptions{Users: userDomain})
+		if err != nil {
+			t.Fatalf("creating a ticket domain instance: %s", err.Error())
+		}
+
+		email := "janedoe+reissue@example.com"
+		_, err = userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "Jane Doe", Email: email})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		ticket := storeTicket(t, ctx, email, true)
+
+		_, err = ticketDomain.ReissueTicket(ctx, ticket.Id)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		messages := mailTransport.Messages()
+		if len(messages) == 0 {
+			t.Fatal("expecting the reissued ticket to be mailed, got none")
+		}
+
+		if message := string(messages[len(messages)-1].Message); !strings.Contains(message, `To: "Jane Doe" <janedoe+reissue@example.com>`) {
+			t.Errorf("expecting the reissued ticket to be addressed to the attendee by name, got %s", message)
+		}
+	})
+
+	t.Run("Revoke then reissue", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		ticket := storeTicket(t, ctx, "johndoe+revoke@example.com", true)
+		leakedPayload := signPayload(ticket, 0)
+
+		err := ticketDomain.RevokeTicket(ctx, ticket.Id)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = ticketDomain.VerifyTicket(ctx, leakedPayload, "")
+		if !errors.Is(err, ticketing.ErrRevokedTicket) {
+			t.Errorf("expecting an error of ErrRevokedTicket, instead got %v", err)
+		}
+
+		sum, err := ticketDomain.ReissueTicket(ctx, ticket.Id)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if sum == "" || sum == ticket.SHA256Sum {
+			t.Errorf("expecting a new sha256sum, got %q", sum)
+		}
+
+		_, err = ticketDomain.VerifyTicket(ctx, leakedPayload, "")
+		if !errors.Is(err, ticketing.ErrRevokedTicket) {
+			t.Errorf("expecting an error of ErrRevokedTicket, instead got %v", err)
+		}
+
+		verifiedTicket, err := ticketDomain.VerifyTicket(ctx, signPayload(ticket, 1), "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if verifiedTicket.Version != 1 {
+			t.Errorf("expecting version to be 1, got %d", verifiedTicket.Version)
+		}
+	})
+}
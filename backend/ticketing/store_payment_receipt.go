@@ -7,13 +7,15 @@ import (
 	"mime"
 	"time"
 
+	"conf/mailer"
+	"conf/mailtemplate"
 	"conf/user"
 	"github.com/getsentry/sentry-go"
 	"gocloud.dev/blob"
 )
 
-// StorePaymentReceipt stores the photo and email combination into our datastore.
-// This will be reviewed manually by the TeknumConf team.
+// StorePaymentReceipt stores the photo and email combination into our datastore, then lets the attendee know
+// that it's been received. This will be reviewed manually by the TeknumConf team.
 func (t *TicketDomain) StorePaymentReceipt(ctx context.Context, user user.User, photo io.Reader, contentType string) error {
 	span := sentry.StartSpan(ctx, "ticketing.store_payment_receipt", sentry.WithTransactionName("StorePaymentReceipt"))
 	defer span.Finish()
@@ -49,18 +51,50 @@ func (t *TicketDomain) StorePaymentReceipt(ctx context.Context, user user.User,
 		return fmt.Errorf("uploading to bucket storage: %w", err)
 	}
 
-	err = t.db.CreateTableRecords(ctx, t.tableId, []any{Ticketing{
+	err = t.repository.InsertTicket(ctx, Ticketing{
 		Email:            user.Email,
 		ReceiptPhotoPath: blobKey,
 		Paid:             false,
 		SHA256Sum:        "",
 		Used:             false,
+		Locale:           user.Locale,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
-	}})
+	})
 	if err != nil {
 		return fmt.Errorf("inserting ticketing entry into database: %w", err)
 	}
 
+	// The receipt is already stored, a failed acknowledgement should not make the attendee upload it again.
+	err = t.sendPaymentReceivedMail(ctx, user)
+	if err != nil {
+		if hub := sentry.GetHubFromContext(ctx); hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	return nil
+}
+
+func (t *TicketDomain) sendPaymentReceivedMail(ctx context.Context, user user.User) error {
+	rendered, err := t.templates.Render(mailtemplate.PaymentReceived, user.Locale, map[string]any{
+		"name":  user.Name,
+		"email": user.Email,
+	})
+	if err != nil {
+		return fmt.Errorf("rendering payment received mail: %w", err)
+	}
+
+	err = t.mailer.Send(ctx, &mailer.Mail{
+		RecipientName:  user.Name,
+		RecipientEmail: user.Email,
+		Subject:        rendered.Subject,
+		PlainTextBody:  rendered.PlainTextBody,
+		HtmlBody:       rendered.HtmlBody,
+	})
+	if err != nil {
+		return fmt.Errorf("sending mail: %w", err)
+	}
+
 	return nil
 }
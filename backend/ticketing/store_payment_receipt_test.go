@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"conf/i18n"
+	"conf/mailer"
 	"conf/ticketing"
 	"conf/user"
 )
@@ -20,12 +22,18 @@ func TestTicketDomain_StorePaymentReceipt(t *testing.T) {
 		return
 	}
 
-	ticketDomain, err := ticketing.NewTicketDomain(database, bucket, privateKey, publicKey, mailSender, tableId)
+	signingKey := ticketing.SigningKey{PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 	if err != nil {
 		t.Fatalf("creating a ticket domain instance: %s", err.Error())
 	}
 
-	userDomain, err := user.NewUserDomain(database, "testing")
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{})
 	if err != nil {
 		t.Fatalf("creating user domain instance: %s", err.Error())
 	}
@@ -49,7 +57,7 @@ func TestTicketDomain_StorePaymentReceipt(t *testing.T) {
 		defer cancel()
 
 		email := "johndoe+happy@example.com"
-		err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{
+		_, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{
 			Name:  "John Doe",
 			Email: email,
 		})
@@ -68,7 +76,7 @@ func TestTicketDomain_StorePaymentReceipt(t *testing.T) {
 		defer cancel()
 
 		email := "johndoe+happy@example.com"
-		err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{
+		_, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{
 			Name:  "John Doe",
 			Email: email,
 		})
@@ -92,4 +100,109 @@ func TestTicketDomain_StorePaymentReceipt(t *testing.T) {
 			t.Errorf("unexpected error: %s", err.Error())
 		}
 	})
+
+	t.Run("Acknowledges the receipt", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+
+		mailTransport := mailer.NewMemoryTransport()
+		ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailer.NewMailSenderWithTransport(mailTransport, mailer.DefaultFrom), mailTemplates, ticketing.TicketDomainOptions{})
+		if err != nil {
+			t.Fatalf("creating a ticket domain instance: %s", err.Error())
+		}
+
+		err = ticketDomain.StorePaymentReceipt(ctx, user.User{Name: "John Doe", Email: "johndoe+ack@example.com"}, strings.NewReader("Hello world!"), "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		messages := mailTransport.Messages()
+		if len(messages) != 1 {
+			t.Fatalf("expecting 1 delivered message, got %d", len(messages))
+		}
+
+		for _, expect := range []string{"Subject: TeknumConf 2023: Bukti Pembayaran Diterima", "Hai, John Doe!"} {
+			if !strings.Contains(string(messages[0].Message), expect) {
+				t.Errorf("expecting the payment received mail to contain %q", expect)
+			}
+		}
+	})
+
+	t.Run("Mails on the attendee's locale", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+
+		mailTransport := mailer.NewMemoryTransport()
+		ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailer.NewMailSenderWithTransport(mailTransport, mailer.DefaultFrom), mailTemplates, ticketing.TicketDomainOptions{})
+		if err != nil {
+			t.Fatalf("creating a ticket domain instance: %s", err.Error())
+		}
+
+		email := "johndoe+locale@example.com"
+		err = ticketDomain.StorePaymentReceipt(ctx, user.User{Name: "John Doe", Email: email, Locale: i18n.English}, strings.NewReader("Hello world!"), "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: email, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(tickets) != 1 || tickets[0].Locale != i18n.English {
+			t.Fatalf("expecting the ticket to store the locale, got %+v", tickets)
+		}
+
+		// The rejection only knows the ticket, the locale comes from it.
+		err = ticketDomain.RejectPaymentReceipt(ctx, tickets[0].Id, "Blurry photo")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		messages := mailTransport.Messages()
+		if len(messages) != 2 {
+			t.Fatalf("expecting 2 delivered messages, got %d", len(messages))
+		}
+
+		for i, expect := range []string{"Subject: TeknumConf 2023: Payment Receipt Received", "Subject: TeknumConf 2023: Your Payment Receipt Was Rejected"} {
+			if !strings.Contains(string(messages[i].Message), expect) {
+				t.Errorf("expecting message %d to contain %q", i, expect)
+			}
+		}
+	})
+
+	t.Run("Lists the tickets of an email case-insensitively", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+
+		// The last email contains the others, it must not show up on their lookup.
+		for _, email := range []string{"JohnDoe+Case@Example.com", "johndoe+case@example.com", "x.johndoe+case@example.com"} {
+			err := ticketDomain.StorePaymentReceipt(ctx, user.User{Email: email}, strings.NewReader("Hello world!"), "text/plain")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		tickets, isLastPage, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: "JOHNDOE+CASE@EXAMPLE.COM", Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(tickets) != 1 || isLastPage {
+			t.Errorf("expecting the first of 2 pages, got %d tickets and last page %t", len(tickets), isLastPage)
+		}
+
+		tickets, isLastPage, err = ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: "johndoe+case@example.com", Offset: 1, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(tickets) != 1 || !isLastPage {
+			t.Errorf("expecting the last page with 1 ticket, got %d tickets and last page %t", len(tickets), isLastPage)
+		}
+
+		if len(tickets) == 1 && !strings.EqualFold(tickets[0].Email, "johndoe+case@example.com") {
+			t.Errorf("expecting the ticket of johndoe+case@example.com, got %s", tickets[0].Email)
+		}
+	})
 }
@@ -0,0 +1,332 @@
+package ticketing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TicketPayload is the signed content of the QR code ticket.
+//
+// Tickets are issued with the version 1 format, which is a compact binary message encoded with base45
+// (RFC 9285), so the QR code can use the alphanumeric mode:
+//
+//	format version  1 byte, always 1
+//	ticket id       uvarint
+//	ticket version  uvarint
+//	key id          1 byte length + key id
+//	hashed email    the first 16 bytes of SHA-384 of the email
+//	entitlements    1 byte count, then 1 byte length + checkpoint id for each entry
+//	signature       64 bytes ed25519 signature of everything above
+//
+// The legacy version 0 format is a text of `keyId.signature;id:hashedEmail:entitlements:version` where the
+// signature is hex encoded, and the email is hashed with SHA-384 and base64 encoded. Tickets issued before
+// key IDs exist does not have the `keyId.` part, tickets issued before entitlements exist does not have the
+// `:entitlements` part, and tickets that have never been reissued does not have the `:version` part. It's
+// only accepted by ParseTicketPayload, so tickets that have been sent out stay valid.
+type TicketPayload struct {
+	// KeyId identifies the key on the Keyring that signs the payload.
+	KeyId    string
+	TicketId int64
+	// HashedEmail is the SHA-384 of the email. It's truncated to hashedEmailLength on the version 1 format.
+	HashedEmail []byte
+	// Entitlements lists the checkpoint IDs that this ticket can be redeemed on, once per checkpoint.
+	// An empty list is a general admission ticket that can be redeemed exactly once on any checkpoint.
+	Entitlements []string
+	// Version is bumped every time the ticket is reissued, older versions are rejected by VerifyTicket.
+	Version int64
+}
+
+const (
+	payloadFormatVersion = 1
+	// hashedEmailLength is the length of the truncated email hash on the version 1 format. It only needs
+	// to tell emails apart, the signature is what keeps the payload from being forged.
+	hashedEmailLength = 16
+)
+
+// checkpointPattern keeps checkpoint IDs safe to be used on the payload and on comma separated columns.
+var checkpointPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+func validateCheckpoints(checkpoints []string) error {
+	var validationError ValidationError
+	for _, checkpoint := range checkpoints {
+		if !checkpointPattern.MatchString(checkpoint) {
+			validationError.Errors = append(validationError.Errors, fmt.Sprintf("invalid checkpoint id %q", checkpoint))
+		}
+	}
+
+	if len(validationError.Errors) > 0 {
+		return validationError
+	}
+
+	return nil
+}
+
+// Entitles returns true if the ticket can be redeemed on the checkpoint.
+func (p TicketPayload) Entitles(checkpoint string) bool {
+	if len(p.Entitlements) == 0 {
+		return true
+	}
+
+	return slices.Contains(p.Entitlements, checkpoint)
+}
+
+// MatchEmail returns true if the payload is issued for the email.
+func (p TicketPayload) MatchEmail(email string) bool {
+	hashedEmail := sha512.Sum384([]byte(email))
+	return matchHashedEmail(hashedEmail[:], p.HashedEmail)
+}
+
+// matchHashedEmail compares the full SHA-384 of an email against the hash on the payload, which might be
+// truncated.
+func matchHashedEmail(hashedEmail []byte, payloadHashedEmail []byte) bool {
+	if len(payloadHashedEmail) < hashedEmailLength {
+		return false
+	}
+
+	return bytes.HasPrefix(hashedEmail, payloadHashedEmail)
+}
+
+func newTicketPayload(ticketing Ticketing) TicketPayload {
+	hashedEmail := sha512.Sum384([]byte(ticketing.Email))
+	return TicketPayload{
+		TicketId:     ticketing.Id,
+		HashedEmail:  hashedEmail[:],
+		Entitlements: splitList(ticketing.Entitlements),
+		Version:      ticketing.Version,
+	}
+}
+
+// Sign creates the QR code content for the payload with the version 1 format, signed by the key. The KeyId
+// of the payload is replaced by the ID of the key. It returns an error if the payload does not fit the format,
+// e.g. the HashedEmail is shorter than hashedEmailLength.
+func (p TicketPayload) Sign(key SigningKey) ([]byte, error) {
+	if len(p.HashedEmail) < hashedEmailLength {
+		return nil, fmt.Errorf("hashed email is %d bytes, expecting at least %d", len(p.HashedEmail), hashedEmailLength)
+	}
+
+	if len(key.Id) > math.MaxUint8 {
+		return nil, fmt.Errorf("key id is longer than %d bytes", math.MaxUint8)
+	}
+
+	if len(p.Entitlements) > math.MaxUint8 {
+		return nil, fmt.Errorf("more than %d entitlements", math.MaxUint8)
+	}
+
+	for _, entitlement := range p.Entitlements {
+		if len(entitlement) > math.MaxUint8 {
+			return nil, fmt.Errorf("entitlement %q is longer than %d bytes", entitlement, math.MaxUint8)
+		}
+	}
+
+	message := []byte{payloadFormatVersion}
+	message = binary.AppendUvarint(message, uint64(p.TicketId))
+	message = binary.AppendUvarint(message, uint64(p.Version))
+	message = append(message, byte(len(key.Id)))
+	message = append(message, key.Id...)
+	message = append(message, p.HashedEmail[:hashedEmailLength]...)
+	message = append(message, byte(len(p.Entitlements)))
+	for _, entitlement := range p.Entitlements {
+		message = append(message, byte(len(entitlement)))
+		message = append(message, entitlement...)
+	}
+
+	message = append(message, ed25519.Sign(key.PrivateKey, message)...)
+	return encodeBase45(message), nil
+}
+
+// ParseTicketPayload disassembles the QR code content and validates its signature against the key on the
+// keyring. Both the version 1 and the legacy version 0 format are accepted. It does not need any access to
+// the database, thus it can be used by the gates to reject invalid tickets offline.
+//
+// If the payload is malformed, the key is unknown, or the signature is invalid, it will return
+// ErrInvalidTicket error.
+func ParseTicketPayload(keyring *Keyring, payload []byte) (TicketPayload, error) {
+	if len(payload) == 0 {
+		return TicketPayload{}, ValidationError{Errors: []string{"payload is empty"}}
+	}
+
+	// The base45 alphabet does not have semicolon, which always separates the signature on version 0
+	if bytes.Contains(payload, []byte(";")) {
+		return parseLegacyTicketPayload(keyring, payload)
+	}
+
+	message, err := decodeBase45(payload)
+	if err != nil {
+		return TicketPayload{}, fmt.Errorf("%w (decoding base45)", ErrInvalidTicket)
+	}
+
+	if len(message) < 1+ed25519.SignatureSize || message[0] != payloadFormatVersion {
+		return TicketPayload{}, fmt.Errorf("%w (unknown format)", ErrInvalidTicket)
+	}
+
+	signed := message[:len(message)-ed25519.SignatureSize]
+	signature := message[len(message)-ed25519.SignatureSize:]
+	reader := payloadReader{data: signed[1:]}
+
+	ticketId := reader.uvarint()
+	version := reader.uvarint()
+	keyId := string(reader.bytes(int(reader.byte())))
+	hashedEmail := reader.bytes(hashedEmailLength)
+	entitlements := make([]string, int(reader.byte()))
+	for i := range entitlements {
+		entitlements[i] = string(reader.bytes(int(reader.byte())))
+	}
+
+	if reader.err || len(reader.data) > 0 || ticketId > 1<<63-1 || version > 1<<63-1 {
+		return TicketPayload{}, fmt.Errorf("%w (malformed payload)", ErrInvalidTicket)
+	}
+
+	if keyId != "" && !keyIdPattern.MatchString(keyId) {
+		return TicketPayload{}, fmt.Errorf("%w (invalid key id)", ErrInvalidTicket)
+	}
+
+	publicKey, ok := keyring.PublicKey(keyId)
+	if !ok {
+		return TicketPayload{}, fmt.Errorf("%w (unknown key %q)", ErrInvalidTicket, keyId)
+	}
+
+	// Validate the signature and its message using ed25519. If it's invalid, return ErrInvalidTicket
+	if !ed25519.Verify(publicKey, signed, signature) {
+		return TicketPayload{}, fmt.Errorf("%w (verifying signature)", ErrInvalidTicket)
+	}
+
+	if len(entitlements) == 0 {
+		entitlements = nil
+	}
+
+	return TicketPayload{
+		KeyId:        keyId,
+		TicketId:     int64(ticketId),
+		HashedEmail:  hashedEmail,
+		Entitlements: entitlements,
+		Version:      int64(version),
+	}, nil
+}
+
+// payloadReader reads the version 1 format. Reading past the end sets err instead of panicking, so the
+// caller only needs to check it once at the end.
+type payloadReader struct {
+	data []byte
+	err  bool
+}
+
+func (r *payloadReader) uvarint() uint64 {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = true
+		r.data = nil
+		return 0
+	}
+
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *payloadReader) byte() byte {
+	value := r.bytes(1)
+	if len(value) == 0 {
+		return 0
+	}
+
+	return value[0]
+}
+
+func (r *payloadReader) bytes(n int) []byte {
+	if len(r.data) < n {
+		r.err = true
+		r.data = nil
+		return nil
+	}
+
+	value := r.data[:n]
+	r.data = r.data[n:]
+	return value
+}
+
+func parseLegacyTicketPayload(keyring *Keyring, payload []byte) (TicketPayload, error) {
+	// Separate the payload into the signature + random id + email + entitlements + version
+	rawSignature, message, found := bytes.Cut(payload, []byte(";"))
+	if !found {
+		return TicketPayload{}, ErrInvalidTicket
+	}
+
+	var keyId string
+	if rawKeyId, rest, found := bytes.Cut(rawSignature, []byte(".")); found {
+		keyId, rawSignature = string(rawKeyId), rest
+		if !keyIdPattern.MatchString(keyId) {
+			return TicketPayload{}, fmt.Errorf("%w (invalid key id)", ErrInvalidTicket)
+		}
+	}
+
+	publicKey, ok := keyring.PublicKey(keyId)
+	if !ok {
+		return TicketPayload{}, fmt.Errorf("%w (unknown key %q)", ErrInvalidTicket, keyId)
+	}
+
+	rawTicketId, rest, found := bytes.Cut(message, []byte(":"))
+	if !found {
+		return TicketPayload{}, ErrInvalidTicket
+	}
+
+	ticketId, err := strconv.ParseInt(string(rawTicketId), 10, 64)
+	if err != nil {
+		return TicketPayload{}, ErrInvalidTicket
+	}
+
+	rawHashedEmail, rest, _ := bytes.Cut(rest, []byte(":"))
+	rawEntitlements, rawVersion, _ := bytes.Cut(rest, []byte(":"))
+
+	var version int64
+	if len(rawVersion) > 0 {
+		version, err = strconv.ParseInt(string(rawVersion), 10, 64)
+		if err != nil || version < 0 {
+			return TicketPayload{}, ErrInvalidTicket
+		}
+	}
+
+	hashedEmail, err := base64.StdEncoding.DecodeString(string(rawHashedEmail))
+	if err != nil {
+		return TicketPayload{}, fmt.Errorf("%w (decoding base64 string for email)", ErrInvalidTicket)
+	}
+
+	signature, err := hex.DecodeString(string(rawSignature))
+	if err != nil {
+		return TicketPayload{}, fmt.Errorf("%w (decoding hex string for signature)", ErrInvalidTicket)
+	}
+
+	// Validate the signature and its message using ed25519. If it's invalid, return ErrInvalidTicket
+	if !ed25519.Verify(publicKey, message, signature) {
+		return TicketPayload{}, fmt.Errorf("%w (verifying signature)", ErrInvalidTicket)
+	}
+
+	return TicketPayload{
+		KeyId:        keyId,
+		TicketId:     ticketId,
+		HashedEmail:  hashedEmail,
+		Entitlements: splitList(string(rawEntitlements)),
+		Version:      version,
+	}, nil
+}
+
+// splitList splits a comma separated column value, ignoring empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			out = append(out, entry)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,160 @@
+package ticketing_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"conf/ticketing"
+)
+
+// mustSign signs the payload, failing the test if it does not fit the format.
+func mustSign(t *testing.T, payload ticketing.TicketPayload, key ticketing.SigningKey) []byte {
+	t.Helper()
+
+	signed, err := payload.Sign(key)
+	if err != nil {
+		t.Fatalf("signing ticket payload: %s", err.Error())
+	}
+
+	return signed
+}
+
+func TestTicketPayload_Sign(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+
+	signingKey := ticketing.SigningKey{Id: "2024", PublicKey: publicKey, PrivateKey: privateKey}
+	hashedEmail := sha512.Sum384([]byte("johndoe@example.com"))
+
+	tests := map[string]ticketing.TicketPayload{
+		"no hashed email":       {TicketId: 1},
+		"short hashed email":    {TicketId: 1, HashedEmail: hashedEmail[:15]},
+		"too many entitlements": {TicketId: 1, HashedEmail: hashedEmail[:], Entitlements: make([]string, 256)},
+		"long entitlement":      {TicketId: 1, HashedEmail: hashedEmail[:], Entitlements: []string{strings.Repeat("a", 256)}},
+	}
+
+	for name, payload := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := payload.Sign(signingKey)
+			if err == nil {
+				t.Error("expecting an error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseTicketPayload(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+
+	signingKey := ticketing.SigningKey{Id: "2024", PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("2024", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	hashedEmail := sha512.Sum384([]byte("johndoe@example.com"))
+
+	t.Run("Round trip", func(t *testing.T) {
+		ticketPayload := ticketing.TicketPayload{
+			TicketId:     1234567,
+			HashedEmail:  hashedEmail[:],
+			Entitlements: []string{"day-1", "workshop-a"},
+			Version:      3,
+		}
+
+		payload := mustSign(t, ticketPayload, signingKey)
+
+		// Every character must be on the QR code alphanumeric mode character set
+		for _, c := range string(payload) {
+			if !strings.ContainsRune("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:", c) {
+				t.Fatalf("unexpected character %q on payload %s", c, payload)
+			}
+		}
+
+		parsed, err := ticketing.ParseTicketPayload(keyring, payload)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if parsed.KeyId != "2024" || parsed.TicketId != 1234567 || parsed.Version != 3 {
+			t.Errorf("unexpected payload: %+v", parsed)
+		}
+
+		if !reflect.DeepEqual(parsed.Entitlements, []string{"day-1", "workshop-a"}) {
+			t.Errorf("expecting entitlements to be [day-1 workshop-a], got %v", parsed.Entitlements)
+		}
+
+		if !parsed.MatchEmail("johndoe@example.com") {
+			t.Error("expecting payload to match the email")
+		}
+
+		if parsed.MatchEmail("janedoe@example.com") {
+			t.Error("expecting payload to not match another email")
+		}
+	})
+
+	t.Run("Smaller than legacy", func(t *testing.T) {
+		ticketPayload := ticketing.TicketPayload{TicketId: 1234567, HashedEmail: hashedEmail[:]}
+		legacyMessage := fmt.Sprintf("%d:%s", ticketPayload.TicketId, base64.StdEncoding.EncodeToString(hashedEmail[:]))
+		legacyPayload := hex.EncodeToString(ed25519.Sign(privateKey, []byte(legacyMessage))) + ";" + legacyMessage
+
+		payload := mustSign(t, ticketPayload, signingKey)
+		if len(payload) >= len(legacyPayload) {
+			t.Errorf("expecting payload to be smaller than %d characters, got %d", len(legacyPayload), len(payload))
+		}
+	})
+
+	t.Run("Legacy format", func(t *testing.T) {
+		legacyMessage := fmt.Sprintf("42:%s:day-1,day-2:2", base64.StdEncoding.EncodeToString(hashedEmail[:]))
+		legacyPayload := "2024." + hex.EncodeToString(ed25519.Sign(privateKey, []byte(legacyMessage))) + ";" + legacyMessage
+
+		parsed, err := ticketing.ParseTicketPayload(keyring, []byte(legacyPayload))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if parsed.TicketId != 42 || parsed.Version != 2 || !reflect.DeepEqual(parsed.Entitlements, []string{"day-1", "day-2"}) {
+			t.Errorf("unexpected payload: %+v", parsed)
+		}
+
+		if !parsed.MatchEmail("johndoe@example.com") {
+			t.Error("expecting payload to match the email")
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		payload := string(mustSign(t, ticketing.TicketPayload{TicketId: 42, HashedEmail: hashedEmail[:]}, signingKey))
+
+		tests := map[string]string{
+			"lowercase":        strings.ToLower(payload),
+			"truncated":        payload[:len(payload)-3],
+			"trailing data":    payload + "000",
+			"dangling char":    payload + "0",
+			"overflowing char": "::: " + payload,
+			"garbage":          "hello world",
+			"legacy garbage":   "a;b",
+		}
+
+		for name, malformed := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := ticketing.ParseTicketPayload(keyring, []byte(malformed))
+				if !errors.Is(err, ticketing.ErrInvalidTicket) {
+					t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+				}
+			})
+		}
+	})
+}
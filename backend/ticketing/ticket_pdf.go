@@ -0,0 +1,207 @@
+package ticketing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// TicketPdfContentType is the media type of the rendered PDF ticket.
+const TicketPdfContentType = "application/pdf"
+
+// RenderTicketPdf renders the PDF ticket with the attendee details, event info, and the QR code of the
+// current ticket version, on the ticket's locale. The attendee name is optional, the email is printed either way.
+//
+// Unlike the inline QR code image, the PDF is sent as a regular attachment, so it stays readable on mail
+// clients that block `cid:` images.
+func (t *TicketDomain) RenderTicketPdf(ticketing Ticketing, attendeeName string) ([]byte, error) {
+	activeKey, ok := t.keyring.ActiveKey()
+	if !ok {
+		return nil, fmt.Errorf("keyring does not have an active key")
+	}
+
+	payload, err := newTicketPayload(ticketing).Sign(activeKey)
+	if err != nil {
+		return nil, fmt.Errorf("signing ticket payload: %w", err)
+	}
+
+	return t.renderTicketPdf(ticketing, attendeeName, payload)
+}
+
+func (t *TicketDomain) renderTicketPdf(ticketing Ticketing, attendeeName string, payload []byte) ([]byte, error) {
+	qr, err := qrcode.New(string(payload), qrcode.High)
+	if err != nil {
+		return nil, fmt.Errorf("generating qr code: %w", err)
+	}
+
+	// One pixel per module, the PDF viewer scales it up without interpolation so it stays sharp.
+	bitmap := qr.Bitmap()
+	var pixels bytes.Buffer
+	for _, row := range bitmap {
+		for _, dark := range row {
+			if dark {
+				pixels.WriteByte(0x00)
+			} else {
+				pixels.WriteByte(0xff)
+			}
+		}
+	}
+
+	var compressedPixels bytes.Buffer
+	zlibWriter := zlib.NewWriter(&compressedPixels)
+	if _, err := zlibWriter.Write(pixels.Bytes()); err != nil {
+		return nil, fmt.Errorf("compressing qr code: %w", err)
+	}
+
+	if err := zlibWriter.Close(); err != nil {
+		return nil, fmt.Errorf("compressing qr code: %w", err)
+	}
+
+	message := func(key string, args ...any) string {
+		return t.catalog.Message(ticketing.Locale, key, args...)
+	}
+
+	ticketType := message("ticket_pdf_type_general")
+	if ticketing.Student {
+		ticketType = message("ticket_pdf_type_student")
+	}
+
+	access := message("ticket_pdf_access_all")
+	if entitlements := splitList(ticketing.Entitlements); len(entitlements) > 0 {
+		access = strings.Join(entitlements, ", ")
+	}
+
+	// The page is A4 in points, with the origin on the bottom left corner.
+	var content pdfContent
+	content.text("F2", 28, 56, 770, t.event.Name)
+	content.text("F1", 14, 56, 745, message("ticket_pdf_heading"))
+
+	y := 715
+	if t.event.Schedule != "" {
+		content.text("F1", 11, 56, y, message("ticket_pdf_schedule", "schedule", t.event.Schedule))
+		y -= 16
+	}
+
+	if t.event.Venue != "" {
+		content.text("F1", 11, 56, y, message("ticket_pdf_venue", "venue", t.event.Venue))
+	}
+
+	y = 660
+	for _, field := range [][2]string{
+		{message("ticket_pdf_name"), attendeeName},
+		{message("ticket_pdf_email"), ticketing.Email},
+		{message("ticket_pdf_type"), ticketType},
+		{message("ticket_pdf_access"), access},
+		{message("ticket_pdf_number"), fmt.Sprintf("%d-%d", ticketing.Id, ticketing.Version)},
+	} {
+		if field[1] == "" {
+			continue
+		}
+
+		content.text("F2", 11, 56, y, field[0])
+		content.text("F1", 11, 160, y, field[1])
+		y -= 18
+	}
+
+	const qrSize = 320
+	content.image("QR", (595-qrSize)/2, y-20-qrSize, qrSize)
+
+	if ticketing.Student {
+		content.text("F1", 10, 56, 110, message("ticket_pdf_student_notice"))
+	}
+	content.text("F1", 10, 56, 94, message("ticket_pdf_instructions"))
+
+	var document pdfDocument
+	document.object("<< /Type /Catalog /Pages 2 0 R >>")
+	document.object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	document.object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] " +
+		"/Resources << /Font << /F1 4 0 R /F2 5 0 R >> /XObject << /QR 6 0 R >> >> /Contents 7 0 R >>")
+	document.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	document.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	document.stream(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray "+
+		"/BitsPerComponent 8 /Interpolate false /Filter /FlateDecode /Length %d >>", len(bitmap), len(bitmap), compressedPixels.Len()),
+		compressedPixels.Bytes())
+	document.stream(fmt.Sprintf("<< /Length %d >>", content.Len()), content.Bytes())
+	document.object("<< /Title " + pdfString(message("ticket_pdf_title", "conferenceName", t.event.Name)) + " /Producer (TeknumConf) >>")
+
+	return document.bytes(1, 8), nil
+}
+
+// pdfContent is a page content stream.
+type pdfContent struct {
+	bytes.Buffer
+}
+
+func (c *pdfContent) text(font string, size int, x int, y int, text string) {
+	fmt.Fprintf(c, "BT /%s %d Tf %d %d Td %s Tj ET\n", font, size, x, y, pdfString(text))
+}
+
+func (c *pdfContent) image(name string, x int, y int, size int) {
+	fmt.Fprintf(c, "q %d 0 0 %d %d %d cm /%s Do Q\n", size, size, x, y, name)
+}
+
+// pdfDocument writes numbered objects and keeps their offsets for the cross-reference table.
+type pdfDocument struct {
+	body    bytes.Buffer
+	offsets []int
+}
+
+func (d *pdfDocument) begin() {
+	if d.body.Len() == 0 {
+		// The binary comment tells transfer programs that the file is not plain text.
+		d.body.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	}
+
+	d.offsets = append(d.offsets, d.body.Len())
+	fmt.Fprintf(&d.body, "%d 0 obj\n", len(d.offsets))
+}
+
+func (d *pdfDocument) object(dictionary string) {
+	d.begin()
+	d.body.WriteString(dictionary)
+	d.body.WriteString("\nendobj\n")
+}
+
+func (d *pdfDocument) stream(dictionary string, data []byte) {
+	d.begin()
+	d.body.WriteString(dictionary)
+	d.body.WriteString("\nstream\n")
+	d.body.Write(data)
+	d.body.WriteString("\nendstream\nendobj\n")
+}
+
+func (d *pdfDocument) bytes(root int, info int) []byte {
+	xrefOffset := d.body.Len()
+	fmt.Fprintf(&d.body, "xref\n0 %d\n0000000000 65535 f \n", len(d.offsets)+1)
+	for _, offset := range d.offsets {
+		fmt.Fprintf(&d.body, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&d.body, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.offsets)+1, root, info, xrefOffset)
+	return d.body.Bytes()
+}
+
+// pdfString encodes the text as a PDF literal string. The standard fonts use WinAnsiEncoding, which matches
+// Latin-1 for the characters we care about, anything outside of it is replaced with a question mark.
+func pdfString(text string) string {
+	var out strings.Builder
+	out.WriteByte('(')
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff:
+			out.WriteByte('?')
+		case r >= 0xa0:
+			out.WriteByte(byte(r))
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte(')')
+	return out.String()
+}
@@ -0,0 +1,136 @@
+package ticketing_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"conf/i18n"
+	"conf/ticketing"
+)
+
+func TestTicketDomain_RenderTicketPdf(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{{PublicKey: publicKey, PrivateKey: privateKey}})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{
+		Event: ticketing.Event{Name: "TeknumConf 2024", Venue: "Jakarta"},
+	})
+	if err != nil {
+		t.Fatalf("creating a ticket domain instance: %s", err.Error())
+	}
+
+	ticketPdf, err := ticketDomain.RenderTicketPdf(ticketing.Ticketing{
+		Id:           42,
+		Email:        "johndoe@example.com",
+		Paid:         true,
+		Student:      true,
+		Entitlements: "main-hall,workshop-a",
+		Version:      2,
+	}, "John (Johnny) Doe")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if !bytes.HasPrefix(ticketPdf, []byte("%PDF-1.4\n")) {
+		t.Errorf("expecting a PDF header, got %q", ticketPdf[:16])
+	}
+
+	if !bytes.HasSuffix(ticketPdf, []byte("%%EOF\n")) {
+		t.Error("expecting the PDF to end with the EOF marker")
+	}
+
+	for _, text := range []string{
+		`(John \(Johnny\) Doe) Tj`,
+		`(johndoe@example.com) Tj`,
+		`(Pelajar / Mahasiswa) Tj`,
+		`(TeknumConf 2024) Tj`,
+		`(Tempat: Jakarta) Tj`,
+		`(main-hall, workshop-a) Tj`,
+		`(42-2) Tj`,
+		`/QR Do`,
+	} {
+		if !bytes.Contains(ticketPdf, []byte(text)) {
+			t.Errorf("expecting the PDF to contain %q", text)
+		}
+	}
+
+	// Every entry on the cross-reference table must point to the start of its object.
+	startXref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(ticketPdf)
+	if startXref == nil {
+		t.Fatal("expecting startxref")
+	}
+
+	xrefOffset, _ := strconv.Atoi(string(startXref[1]))
+	if !bytes.HasPrefix(ticketPdf[xrefOffset:], []byte("xref\n")) {
+		t.Fatalf("expecting startxref to point to the xref table, got %q", ticketPdf[xrefOffset:xrefOffset+8])
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(ticketPdf[xrefOffset:], -1)
+	if len(entries) != 8 {
+		t.Errorf("expecting 8 objects, got %d", len(entries))
+	}
+
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		expect := strconv.Itoa(i+1) + " 0 obj\n"
+		if !bytes.HasPrefix(ticketPdf[offset:], []byte(expect)) {
+			t.Errorf("expecting object %d at offset %d", i+1, offset)
+		}
+	}
+}
+
+func TestTicketDomain_RenderTicketPdf_Locale(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{{PublicKey: publicKey, PrivateKey: privateKey}})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{
+		Event: ticketing.Event{Name: "TeknumConf 2024", Schedule: "1 June 2024"},
+	})
+	if err != nil {
+		t.Fatalf("creating a ticket domain instance: %s", err.Error())
+	}
+
+	ticketPdf, err := ticketDomain.RenderTicketPdf(ticketing.Ticketing{
+		Id:      42,
+		Email:   "johndoe@example.com",
+		Paid:    true,
+		Version: 1,
+		Locale:  i18n.English,
+	}, "John Doe")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for _, text := range []string{
+		`(Admission Ticket) Tj`,
+		`(Date & Time: 1 June 2024) Tj`,
+		`(General) Tj`,
+		`(TeknumConf 2024 - Ticket)`,
+	} {
+		if !bytes.Contains(ticketPdf, []byte(text)) {
+			t.Errorf("expecting the PDF to contain %q", text)
+		}
+	}
+
+	if bytes.Contains(ticketPdf, []byte("Venue:")) {
+		t.Error("expecting the empty venue to be left out")
+	}
+}
@@ -1,7 +1,7 @@
 package ticketing
 
 import (
-	"crypto/ed25519"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
@@ -9,78 +9,145 @@ import (
 	"reflect"
 	"time"
 
+	"conf/i18n"
 	"conf/mailer"
-	"conf/nocodb"
+	"conf/mailtemplate"
+	"conf/user"
+	"conf/wallet"
 
 	"gocloud.dev/blob"
 )
 
 type TicketDomain struct {
-	db         *nocodb.Client
-	tableId    string
+	repository Repository
 	bucket     *blob.Bucket
-	privateKey *ed25519.PrivateKey
-	publicKey  *ed25519.PublicKey
-	mailer     *mailer.Mailer
+	keyring    *Keyring
+	mailer     mailer.Sender
+	templates  *mailtemplate.Registry
+	wallet     *wallet.Wallet
+	users      UserFinder
+	event      Event
+	catalog    *i18n.Catalog
 }
 
-func NewTicketDomain(db *nocodb.Client, bucket *blob.Bucket, privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey, mailer *mailer.Mailer, tableId string) (*TicketDomain, error) {
-	if db == nil {
-		return nil, fmt.Errorf("db is nil")
+// Event describes the event the tickets are issued for, it's printed on the PDF ticket.
+type Event struct {
+	Name string
+	// Schedule and Venue are printed as they are, e.g. "21 Oktober 2023, 13:00 - 19:00". They are left out if
+	// empty.
+	Schedule string
+	Venue    string
+}
+
+// UserFinder looks up the registration of an email address, *user.UserDomain implements it.
+type UserFinder interface {
+	GetUserByEmail(ctx context.Context, email string) (user.User, error)
+}
+
+// TicketDomainOptions configures the optional dependencies of TicketDomain.
+type TicketDomainOptions struct {
+	// Wallet attaches the Apple Wallet pass and the Google Wallet link to the ticket mail, they are left out if it's
+	// nil.
+	Wallet *wallet.Wallet
+	// Users looks up the attendee of a ticket, so the tickets sent by ApprovePaymentReceipt and ReissueTicket carry
+	// the attendee's name. They are sent without a name if it's nil.
+	Users UserFinder
+	// Event is printed on the PDF ticket.
+	Event Event
+	// Catalog translates the PDF ticket to the ticket's locale, the i18n.Builtin catalog is used if it's nil.
+	Catalog *i18n.Catalog
+}
+
+// NewTicketDomain creates a ticket domain instance. The templates must have the mailtemplate.Ticket,
+// mailtemplate.PaymentReceived, and mailtemplate.PaymentRejected templates.
+func NewTicketDomain(repository Repository, bucket *blob.Bucket, keyring *Keyring, mailer mailer.Sender, templates *mailtemplate.Registry, options TicketDomainOptions) (*TicketDomain, error) {
+	if repository == nil {
+		return nil, fmt.Errorf("repository is nil")
 	}
 
 	if bucket == nil {
 		return nil, fmt.Errorf("bucket is nil")
 	}
 
-	if privateKey == nil {
-		return nil, fmt.Errorf("privateKey is nil")
+	if keyring == nil {
+		return nil, fmt.Errorf("keyring is nil")
 	}
 
-	if publicKey == nil {
-		return nil, fmt.Errorf("publicKey is nil")
+	if _, ok := keyring.ActiveKey(); !ok {
+		return nil, fmt.Errorf("keyring does not have an active key")
 	}
 
 	if mailer == nil {
 		return nil, fmt.Errorf("mailer is nil")
 	}
 
-	if tableId == "" {
-		return nil, fmt.Errorf("tableId is nil")
+	if templates == nil {
+		return nil, fmt.Errorf("templates is nil")
 	}
-	
+
+	for _, name := range []string{mailtemplate.Ticket, mailtemplate.PaymentReceived, mailtemplate.PaymentRejected} {
+		if !templates.Has(name) {
+			return nil, fmt.Errorf("%s template does not exist", name)
+		}
+	}
+
+	if options.Catalog == nil {
+		var err error
+		options.Catalog, err = i18n.LoadCatalog(i18n.Builtin)
+		if err != nil {
+			return nil, fmt.Errorf("loading builtin catalog: %w", err)
+		}
+	}
+
 	return &TicketDomain{
-		db:         db,
+		repository: repository,
 		bucket:     bucket,
-		privateKey: &privateKey,
-		publicKey:  &publicKey,
+		keyring:    keyring,
 		mailer:     mailer,
-		tableId:    tableId,
+		templates:  templates,
+		wallet:     options.Wallet,
+		users:      options.Users,
+		event:      options.Event,
+		catalog:    options.Catalog,
 	}, nil
 }
 
 type Ticketing struct {
-	Id               int64     `json:"Id,omitempty"`
-	Email            string    `json:"Email,omitempty"`
-	ReceiptPhotoPath string    `json:"ReceiptPhotoPath,omitempty"`
-	Paid             bool      `json:"Paid,omitempty"`
-	Student          bool      `json:"Student,omitempty"`
-	SHA256Sum        string    `json:"SHA256Sum,omitempty"`
-	Used             bool      `json:"Used,omitempty"`
-	CreatedAt        time.Time `json:"CreatedAt,omitempty"`
-	UpdatedAt        time.Time `json:"UpdatedAt,omitempty"`
+	Id                  int64       `json:"Id,omitempty"`
+	Email               string      `json:"Email,omitempty"`
+	ReceiptPhotoPath    string      `json:"ReceiptPhotoPath,omitempty"`
+	Paid                bool        `json:"Paid,omitempty"`
+	Student             bool        `json:"Student,omitempty"`
+	SHA256Sum           string      `json:"SHA256Sum,omitempty"`
+	Used                bool        `json:"Used,omitempty"`
+	Entitlements        string      `json:"Entitlements,omitempty"`
+	RedeemedCheckpoints string      `json:"RedeemedCheckpoints,omitempty"`
+	Version             int64       `json:"Version,omitempty"`
+	Revoked             bool        `json:"Revoked,omitempty"`
+	Rejected            bool        `json:"Rejected,omitempty"`
+	RejectionReason     string      `json:"RejectionReason,omitempty"`
+	Locale              i18n.Locale `json:"Locale,omitempty"`
+	CreatedAt           time.Time   `json:"CreatedAt,omitempty"`
+	UpdatedAt           time.Time   `json:"UpdatedAt,omitempty"`
 }
 
 type NullTicketing struct {
-	Id               sql.NullInt64  `json:"Id,omitempty"`
-	Email            sql.NullString `json:"Email,omitempty"`
-	ReceiptPhotoPath sql.NullString `json:"ReceiptPhotoPath,omitempty"`
-	Paid             sql.NullBool   `json:"Paid,omitempty"`
-	Student          sql.NullBool   `json:"Student,omitempty"`
-	SHA256Sum        sql.NullString `json:"SHA256Sum,omitempty"`
-	Used             sql.NullBool   `json:"Used,omitempty"`
-	CreatedAt        sql.NullTime   `json:"CreatedAt,omitempty"`
-	UpdatedAt        sql.NullTime   `json:"UpdatedAt,omitempty"`
+	Id                  sql.NullInt64  `json:"Id,omitempty"`
+	Email               sql.NullString `json:"Email,omitempty"`
+	ReceiptPhotoPath    sql.NullString `json:"ReceiptPhotoPath,omitempty"`
+	Paid                sql.NullBool   `json:"Paid,omitempty"`
+	Student             sql.NullBool   `json:"Student,omitempty"`
+	SHA256Sum           sql.NullString `json:"SHA256Sum,omitempty"`
+	Used                sql.NullBool   `json:"Used,omitempty"`
+	Entitlements        sql.NullString `json:"Entitlements,omitempty"`
+	RedeemedCheckpoints sql.NullString `json:"RedeemedCheckpoints,omitempty"`
+	Version             sql.NullInt64  `json:"Version,omitempty"`
+	Revoked             sql.NullBool   `json:"Revoked,omitempty"`
+	Rejected            sql.NullBool   `json:"Rejected,omitempty"`
+	RejectionReason     sql.NullString `json:"RejectionReason,omitempty"`
+	Locale              sql.NullString `json:"Locale,omitempty"`
+	CreatedAt           sql.NullTime   `json:"CreatedAt,omitempty"`
+	UpdatedAt           sql.NullTime   `json:"UpdatedAt,omitempty"`
 }
 
 func (t NullTicketing) MarshalJSON() ([]byte, error) {
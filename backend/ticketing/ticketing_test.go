@@ -3,25 +3,34 @@ package ticketing_test
 import (
 	"context"
 	"crypto/ed25519"
+	"crypto/rand"
 	"database/sql"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"conf/mailer"
+	"conf/mailtemplate"
+	"conf/migrations"
 	"conf/nocodb"
 	"conf/nocodb/nocodbmock"
 	"conf/ticketing"
+	"conf/user"
 	"github.com/getsentry/sentry-go"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/rs/zerolog/log"
 	"gocloud.dev/blob"
 	_ "gocloud.dev/blob/fileblob"
 )
 
-var database *nocodb.Client
+var ticketingRepository ticketing.Repository
+var userRepository user.Repository
 var bucket *blob.Bucket
 var mailSender *mailer.Mailer
+var mailTemplates *mailtemplate.Registry
 var tableId = "ticketing"
 
 func TestMain(m *testing.M) {
@@ -33,7 +42,15 @@ func TestMain(m *testing.M) {
 
 	blobUrl, ok := os.LookupEnv("BLOB_URL")
 	if !ok {
-		blobUrl = "file://" + tempDir
+		// Signed URLs on fileblob requires a secret key
+		secretKeyPath := filepath.Join(tempDir, "secret.key")
+		err = os.WriteFile(secretKeyPath, []byte("secret"), 0600)
+		if err != nil {
+			log.Fatal().Err(err).Msg("writing secret key")
+			return
+		}
+
+		blobUrl = "file://" + tempDir + "?base_url=http://localhost/&secret_key_path=" + secretKeyPath
 	}
 
 	smtpHostname, ok := os.LookupEnv("SMTP_HOSTNAME")
@@ -61,7 +78,7 @@ func TestMain(m *testing.M) {
 		return
 	}
 
-	database, err = nocodb.NewClient(nocodb.ClientOptions{
+	database, err := nocodb.NewClient(nocodb.ClientOptions{
 		ApiToken:   "testing",
 		BaseUrl:    nocodbMockServer.URL,
 		HttpClient: nocodbMockServer.Client(),
@@ -72,6 +89,46 @@ func TestMain(m *testing.M) {
 		return
 	}
 
+	ticketingRepository, err = ticketing.NewNocoDBRepository(database, tableId)
+	if err != nil {
+		log.Fatal().Err(err).Msg("creating ticketing repository")
+		return
+	}
+
+	userRepository, err = user.NewNocoDBRepository(database, "testing")
+	if err != nil {
+		log.Fatal().Err(err).Msg("creating user repository")
+		return
+	}
+
+	// Run the same test suite against PostgreSQL if it's configured
+	var postgresDatabase *sql.DB
+	if os.Getenv("DATABASE_DRIVER") == "postgres" {
+		postgresDatabase, err = sql.Open("pgx", os.Getenv("DATABASE_URL"))
+		if err != nil {
+			log.Fatal().Err(err).Msg("opening postgres connection")
+			return
+		}
+
+		err = migrations.Up(context.Background(), postgresDatabase)
+		if err != nil {
+			log.Fatal().Err(err).Msg("migrating postgres database")
+			return
+		}
+
+		ticketingRepository, err = ticketing.NewPostgresRepository(postgresDatabase)
+		if err != nil {
+			log.Fatal().Err(err).Msg("creating ticketing repository")
+			return
+		}
+
+		userRepository, err = user.NewPostgresRepository(postgresDatabase)
+		if err != nil {
+			log.Fatal().Err(err).Msg("creating user repository")
+			return
+		}
+	}
+
 	bucket, err = blob.OpenBucket(context.Background(), blobUrl)
 	if err != nil {
 		log.Fatal().Err(err).Msg("creating bucket instance")
@@ -85,26 +142,41 @@ func TestMain(m *testing.M) {
 		SmtpPassword: smtpPassword,
 	})
 
+	mailTemplates, err = mailtemplate.Load(map[string]any{"conferenceName": "TeknumConf 2023"}, nil, mailtemplate.Builtin)
+	if err != nil {
+		log.Fatal().Err(err).Msg("loading mail templates")
+		return
+	}
+
 	exitCode := m.Run()
 
 	_ = os.RemoveAll(tempDir)
 	_ = bucket.Close()
 	nocodbMockServer.Close()
+	if postgresDatabase != nil {
+		_ = postgresDatabase.Close()
+	}
 
 	os.Exit(exitCode)
 }
 
 func TestNewTicketDomain(t *testing.T) {
 	// Create mock dependencies.
-	db := &nocodb.Client{}
+	repository := &ticketing.NocoDBRepository{}
 	bucket := &blob.Bucket{}
-	privateKey := ed25519.PrivateKey{}
-	publicKey := ed25519.PublicKey{}
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{{PublicKey: publicKey, PrivateKey: privateKey}})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
 	mailSender := &mailer.Mailer{}
 
 	// Group the tests with t.Run().
 	t.Run("all dependencies set", func(t *testing.T) {
-		ticketDomain, err := ticketing.NewTicketDomain(db, bucket, privateKey, publicKey, mailSender, "asd")
+		ticketDomain, err := ticketing.NewTicketDomain(repository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 		if err != nil {
 			t.Errorf("NewTicketDomain failed: %v", err)
 		}
@@ -113,18 +185,18 @@ func TestNewTicketDomain(t *testing.T) {
 		}
 	})
 
-	t.Run("nil database", func(t *testing.T) {
-		ticketDomain, err := ticketing.NewTicketDomain(nil, bucket, privateKey, publicKey, mailSender, "")
+	t.Run("nil repository", func(t *testing.T) {
+		ticketDomain, err := ticketing.NewTicketDomain(nil, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 		if err == nil {
-			t.Error("NewTicketDomain did not return error with nil database")
+			t.Error("NewTicketDomain did not return error with nil repository")
 		}
 		if ticketDomain != nil {
-			t.Error("NewTicketDomain returned non-nil ticketDomain with nil database")
+			t.Error("NewTicketDomain returned non-nil ticketDomain with nil repository")
 		}
 	})
 
 	t.Run("nil bucket", func(t *testing.T) {
-		ticketDomain, err := ticketing.NewTicketDomain(db, nil, privateKey, publicKey, mailSender, "")
+		ticketDomain, err := ticketing.NewTicketDomain(repository, nil, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 		if err == nil {
 			t.Error("NewTicketDomain did not return error with nil bucket")
 		}
@@ -133,28 +205,33 @@ func TestNewTicketDomain(t *testing.T) {
 		}
 	})
 
-	t.Run("nil private key", func(t *testing.T) {
-		ticketDomain, err := ticketing.NewTicketDomain(db, bucket, nil, publicKey, mailSender, "")
+	t.Run("nil keyring", func(t *testing.T) {
+		ticketDomain, err := ticketing.NewTicketDomain(repository, bucket, nil, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 		if err == nil {
-			t.Error("NewTicketDomain did not return error with nil private key")
+			t.Error("NewTicketDomain did not return error with nil keyring")
 		}
 		if ticketDomain != nil {
-			t.Error("NewTicketDomain returned non-nil ticketDomain with nil private key")
+			t.Error("NewTicketDomain returned non-nil ticketDomain with nil keyring")
 		}
 	})
 
-	t.Run("nil public key", func(t *testing.T) {
-		ticketDomain, err := ticketing.NewTicketDomain(db, bucket, privateKey, nil, mailSender, "")
+	t.Run("verification only keyring", func(t *testing.T) {
+		verificationKeyring, err := ticketing.NewVerificationKeyring(map[string]ed25519.PublicKey{"": publicKey})
+		if err != nil {
+			t.Fatalf("creating a keyring: %s", err.Error())
+		}
+
+		ticketDomain, err := ticketing.NewTicketDomain(repository, bucket, verificationKeyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 		if err == nil {
-			t.Error("NewTicketDomain did not return error with nil public key")
+			t.Error("NewTicketDomain did not return error with verification only keyring")
 		}
 		if ticketDomain != nil {
-			t.Error("NewTicketDomain returned non-nil ticketDomain with nil public key")
+			t.Error("NewTicketDomain returned non-nil ticketDomain with verification only keyring")
 		}
 	})
 
 	t.Run("nil mailSender", func(t *testing.T) {
-		ticketDomain, err := ticketing.NewTicketDomain(db, bucket, privateKey, publicKey, nil, "")
+		ticketDomain, err := ticketing.NewTicketDomain(repository, bucket, keyring, nil, mailTemplates, ticketing.TicketDomainOptions{})
 		if err == nil {
 			t.Error("NewTicketDomain did not return error with nil mailSender")
 		}
@@ -162,6 +239,31 @@ func TestNewTicketDomain(t *testing.T) {
 			t.Error("NewTicketDomain returned non-nil ticketDomain with nil mailSender")
 		}
 	})
+
+	t.Run("nil templates", func(t *testing.T) {
+		ticketDomain, err := ticketing.NewTicketDomain(repository, bucket, keyring, mailSender, nil, ticketing.TicketDomainOptions{})
+		if err == nil {
+			t.Error("NewTicketDomain did not return error with nil templates")
+		}
+		if ticketDomain != nil {
+			t.Error("NewTicketDomain returned non-nil ticketDomain with nil templates")
+		}
+	})
+
+	t.Run("missing template", func(t *testing.T) {
+		templates, err := mailtemplate.Load(nil, nil, fstest.MapFS{"ticket.html": {Data: []byte("<p>Ticket</p>")}})
+		if err != nil {
+			t.Fatalf("loading templates: %s", err.Error())
+		}
+
+		ticketDomain, err := ticketing.NewTicketDomain(repository, bucket, keyring, mailSender, templates, ticketing.TicketDomainOptions{})
+		if err == nil {
+			t.Error("NewTicketDomain did not return error with missing templates")
+		}
+		if ticketDomain != nil {
+			t.Error("NewTicketDomain returned non-nil ticketDomain with missing templates")
+		}
+	})
 }
 
 func TestNullTicketing_MarshalJSON(t *testing.T) {
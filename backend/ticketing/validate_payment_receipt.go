@@ -2,28 +2,26 @@ package ticketing
 
 import (
 	"context"
-	"crypto/ed25519"
 	"crypto/sha256"
-	"crypto/sha512"
 	"database/sql"
-	"encoding/base64"
 	"encoding/hex"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
 	"conf/mailer"
-	"conf/nocodb"
+	"conf/mailtemplate"
 	"conf/user"
+	"conf/wallet"
+
 	"github.com/getsentry/sentry-go"
 	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
 )
 
 // ValidatePaymentReceipt marks an email payment status as paid. It will create a signature using Ed25519,
-// encode it to a QRCode image, and send the QRCode to the user's email. It returns hex-encoded SHA256SUM
-// of the QR code.
+// encode it to a QRCode image, and send the QRCode to the user's email through the mail sender, which
+// queues it on the outbox in production. It returns hex-encoded SHA256SUM of the QR code.
 //
 // It will return ErrInvalidTicket if the payment receipt's not uploaded yet.
 func (t *TicketDomain) ValidatePaymentReceipt(ctx context.Context, user user.User) (string, error) {
@@ -31,10 +29,8 @@ func (t *TicketDomain) ValidatePaymentReceipt(ctx context.Context, user user.Use
 	defer span.Finish()
 
 	// Mark payment status as paid on database
-	var rawTicketingResults []Ticketing
-	_, err := t.db.ListTableRecords(ctx, t.tableId, &rawTicketingResults, nocodb.ListTableRecordOptions{
-		Where: fmt.Sprintf("(Email,eq,%s)", user.Email),
-		Sort:  []nocodb.Sort{nocodb.SortDescending("CreatedAt")},
+	rawTicketingResults, _, err := t.repository.ListTickets(ctx, TicketQuery{
+		Email: user.Email,
 		Limit: 1,
 	})
 	if err != nil {
@@ -46,19 +42,60 @@ func (t *TicketDomain) ValidatePaymentReceipt(ctx context.Context, user user.Use
 	}
 
 	var ticketing = rawTicketingResults[0]
+	// The attendee may have changed their locale since the receipt was uploaded.
+	if user.Locale != "" {
+		ticketing.Locale = user.Locale
+	}
 
-	// Create a signature using unique key based on the email and random id combination (possibly using any non-text based encoding)
-	sha384Hasher := sha512.New384()
-	sha384Hasher.Write([]byte(user.Email))
-	hashedEmail := sha384Hasher.Sum(nil)
-	payload := fmt.Sprintf("%s:%s", strconv.FormatInt(ticketing.Id, 10), base64.StdEncoding.EncodeToString(hashedEmail))
+	return t.validateTicket(ctx, ticketing, user.Name)
+}
 
-	signature := ed25519.Sign(*t.privateKey, []byte(payload))
+// validateTicket marks the given ticket as paid and sends its QR code to the attendee. It acts on the ticket as
+// it's given, callers are responsible for picking the right entry.
+func (t *TicketDomain) validateTicket(ctx context.Context, ticketing Ticketing, attendeeName string) (string, error) {
+	payload, qrImage, sha256Sum, err := renderTicketQrCode(ticketing, t.keyring)
+	if err != nil {
+		return "", err
+	}
+
+	// Persist the payment before the mail goes out, so an attendee never holds a ticket that isn't paid on
+	// the database. If queueing the mail fails, the ticket can be sent again with ReissueTicket.
+	err = t.repository.UpdateTicket(ctx, NullTicketing{
+		Id:        sql.NullInt64{Int64: ticketing.Id, Valid: true},
+		Paid:      sql.NullBool{Bool: true, Valid: true},
+		SHA256Sum: sql.NullString{String: hex.EncodeToString(sha256Sum), Valid: true},
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return "", fmt.Errorf("updating ticket: %w", err)
+	}
+
+	err = t.sendTicketMail(ctx, ticketing, attendeeName, payload, qrImage, sha256Sum)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(sha256Sum), nil
+}
+
+// renderTicketQrCode signs the ticket payload with the active key and encodes it to a QR code image.
+// It returns the signed payload and the PNG image along with its SHA256SUM.
+func renderTicketQrCode(ticketing Ticketing, keyring *Keyring) ([]byte, []byte, []byte, error) {
+	activeKey, ok := keyring.ActiveKey()
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("keyring does not have an active key")
+	}
+
+	// Create a signature using unique key based on the email, ticket id and its entitlements
+	payload, err := newTicketPayload(ticketing).Sign(activeKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("signing ticket payload: %w", err)
+	}
 
 	// Generate QR code with https://github.com/skip2/go-qrcode
-	qrImage, err := qrcode.Encode(fmt.Sprintf("%s;%s", hex.EncodeToString(signature), payload), qrcode.High, 1024)
+	qrImage, err := qrcode.Encode(string(payload), qrcode.High, 1024)
 	if err != nil {
-		return "", fmt.Errorf("generating qr code: %w", err)
+		return nil, nil, nil, fmt.Errorf("generating qr code: %w", err)
 	}
 
 	// Create SHA256SUM to the generated QR code
@@ -66,91 +103,112 @@ func (t *TicketDomain) ValidatePaymentReceipt(ctx context.Context, user user.Use
 	sha256Hasher.Write(qrImage)
 	sha256Sum := sha256Hasher.Sum(nil)
 
+	return payload, qrImage, sha256Sum, nil
+}
+
+// sendTicketMail sends the QR code ticket to the attendee on the ticket's locale, along with the wallet passes if
+// it's configured. The passes carry the same signed payload as the QR code image.
+func (t *TicketDomain) sendTicketMail(ctx context.Context, ticketing Ticketing, attendeeName string, payload []byte, qrImage []byte, sha256Sum []byte) error {
 	imageCid, _, _ := strings.Cut(uuid.NewString(), "-")
 
+	ticketPdf, err := t.renderTicketPdf(ticketing, attendeeName, payload)
+	if err != nil {
+		return fmt.Errorf("rendering pdf ticket: %w", err)
+	}
+
+	walletAttachments, googleWalletLink, err := t.buildWalletPasses(ticketing, attendeeName, payload)
+	if err != nil {
+		return err
+	}
+
+	ticketPdfChecksum := sha256.Sum256(ticketPdf)
+
+	rendered, err := t.templates.Render(mailtemplate.Ticket, ticketing.Locale, map[string]any{
+		"name":             attendeeName,
+		"email":            ticketing.Email,
+		"qrCodeContentId":  imageCid,
+		"googleWalletLink": googleWalletLink,
+	})
+	if err != nil {
+		return fmt.Errorf("rendering ticket mail: %w", err)
+	}
+
 	// Send email programmatically
 	err = t.mailer.Send(ctx, &mailer.Mail{
-		RecipientName:  "",
-		RecipientEmail: user.Email,
-		Subject:        "TeknumConf 2023: Tiket Anda!",
-		PlainTextBody: `Hai! Ini dia email yang kamu tunggu-tunggu💃
-        
-Pembayaran kamu telah di konfirmasi! Dibawah ini terdapat QR code sebagai tiket kamu masuk ke TeknumConf 2023.
-Apabila kamu mendapat student discount, pastikan kamu membawa Kartu Mahasiswa atau Kartu Pelajar ya!
-Panitia akan melakukan verifikasi tambahan pada lokasi untuk memastikan kalau kamu betulan pelajar.
-
-Sampai jumpa di TeknumConf 2023!
-
-Email ini hanya tertuju untuk Anda. Apabila Anda merasa tidak mendaftar untuk TeknumConf 2023,
-harap abaikan email ini. Terima kasih!`,
-		HtmlBody: `<!DOCTYPE html>
-<html lang="en" xmlns="http://www.w3.org/1999/xhtml">
-    <head>
-        <meta content="IE=edge" http-equiv="X-UA-Compatible" />
-        <meta content="width=device-width,initial-scale=1 user-scalable=yes" name="viewport" />
-        <meta content="telephone=no, date=no, address=no, email=no, url=no" name="format-detection" />
-        <meta name="x-apple-disable-message-reformatting" />
-        <meta charset="UTF-8" />
-        <!--[if mso]>
-            <noscript>
-                <xml>
-                    <o:OfficeDocumentSettings>
-                        <o:PixelsPerInch>96</o:PixelsPerInch>
-                    </o:OfficeDocumentSettings>
-                </xml>
-            </noscript>
-        <![endif]-->
-
-        <style>
-            * {
-                font-family: 'Rubik', system-ui, -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, 'Open Sans', 'Helvetica Neue', sans-serif;
-            }
-        </style>
-        <title>TeknumConf 2023: Tiket Anda!</title>
-    </head>
-    <body>
-        <h1>Hai! Ini dia email yang kamu tunggu-tunggu💃</h1>
-        <p>
-            Pembayaran kamu telah di konfirmasi! Dibawah ini terdapat QR code sebagai tiket kamu masuk ke TeknumConf 2023.
-            Apabila kamu mendapat <i>student discount</i>, pastikan kamu membawa Kartu Mahasiswa atau Kartu Pelajar ya!
-            Panitia akan melakukan verifikasi tambahan pada lokasi untuk memastikan kalau kamu betulan pelajar.
-        </p>
-        <p><b>Sampai jumpa di TeknumConf 2023!</b></p>
-        <p><img src="cid:` + imageCid + `" style="width: 100%; max-width: 720px;"></p>
-        <p>
-            <small>
-                Email ini hanya tertuju untuk Anda. Apabila Anda merasa tidak mendaftar untuk TeknumConf 2023,
-                harap abaikan email ini. Terima kasih!
-            </small>
-        </p>
-    </body>
-</html>
-`,
-		Attachments: []mailer.Attachment{
+		RecipientName:  attendeeName,
+		RecipientEmail: ticketing.Email,
+		Subject:        rendered.Subject,
+		PlainTextBody:  rendered.PlainTextBody,
+		HtmlBody:       rendered.HtmlBody,
+		Attachments: append([]mailer.Attachment{
 			{
 				Name:               "qrcode_ticket.png",
-				Description:        "QR code ticket TeknumConf 2023",
+				Description:        t.catalog.Message(ticketing.Locale, "mail_ticket_attachment_qr_code", "conferenceName", t.event.Name),
 				ContentType:        "image/png",
 				ContentDisposition: mailer.ContentDispositionInline,
 				ContentId:          imageCid,
 				SHA256Checksum:     sha256Sum,
 				Payload:            qrImage,
 			},
-		},
+			{
+				Name:               "ticket.pdf",
+				Description:        t.catalog.Message(ticketing.Locale, "mail_ticket_attachment_pdf", "conferenceName", t.event.Name),
+				ContentType:        TicketPdfContentType,
+				ContentDisposition: mailer.ContentDispositionAttachment,
+				SHA256Checksum:     ticketPdfChecksum[:],
+				Payload:            ticketPdf,
+			},
+		}, walletAttachments...),
 	})
 	if err != nil {
-		return "", fmt.Errorf("sending mail: %w", err)
+		return fmt.Errorf("sending mail: %w", err)
 	}
 
-	err = t.db.UpdateTableRecords(ctx, t.tableId, []any{NullTicketing{
-		Id:        sql.NullInt64{Int64: ticketing.Id, Valid: true},
-		Paid:      sql.NullBool{Bool: true, Valid: true},
-		SHA256Sum: sql.NullString{String: hex.EncodeToString(sha256Sum), Valid: true},
-		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
-	}})
-	if err != nil {
-		return "", fmt.Errorf("updating table records: %w", err)
+	return nil
+}
+
+// buildWalletPasses creates the Apple Wallet pass attachment and the Google Wallet save link of the ticket.
+// Either of them is empty if it's not configured.
+func (t *TicketDomain) buildWalletPasses(ticketing Ticketing, attendeeName string, payload []byte) ([]mailer.Attachment, string, error) {
+	if t.wallet == nil {
+		return nil, "", nil
 	}
 
-	return hex.EncodeToString(sha256Sum), nil
+	passTicket := wallet.PassTicket{
+		SerialNumber:  fmt.Sprintf("%d-%d", ticketing.Id, ticketing.Version),
+		Barcode:       string(payload),
+		AttendeeName:  attendeeName,
+		AttendeeEmail: ticketing.Email,
+		Entitlements:  splitList(ticketing.Entitlements),
+	}
+
+	var attachments []mailer.Attachment
+	if t.wallet.Apple != nil {
+		pkpass, err := t.wallet.Apple.Build(passTicket)
+		if err != nil {
+			return nil, "", fmt.Errorf("building apple wallet pass: %w", err)
+		}
+
+		checksum := sha256.Sum256(pkpass)
+		attachments = append(attachments, mailer.Attachment{
+			Name:               "ticket.pkpass",
+			Description:        t.catalog.Message(ticketing.Locale, "mail_ticket_attachment_apple_wallet", "conferenceName", t.event.Name),
+			ContentType:        wallet.ApplePassContentType,
+			ContentDisposition: mailer.ContentDispositionAttachment,
+			SHA256Checksum:     checksum[:],
+			Payload:            pkpass,
+		})
+	}
+
+	var googleWalletLink string
+	if t.wallet.Google != nil {
+		link, err := t.wallet.Google.SaveLink(passTicket)
+		if err != nil {
+			return nil, "", fmt.Errorf("creating google wallet link: %w", err)
+		}
+
+		googleWalletLink = link
+	}
+
+	return attachments, googleWalletLink, nil
 }
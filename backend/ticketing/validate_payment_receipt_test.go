@@ -2,15 +2,24 @@ package ticketing_test
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"math/big"
 	"strings"
 	"testing"
 	"time"
 
+	"conf/mailer"
 	"conf/ticketing"
 	"conf/user"
+	"conf/wallet"
 )
 
 func TestTicketDomain_ValidatePaymentReceipt(t *testing.T) {
@@ -20,19 +29,23 @@ func TestTicketDomain_ValidatePaymentReceipt(t *testing.T) {
 		return
 	}
 
-	ticketDomain, err := ticketing.NewTicketDomain(database, bucket, privateKey, publicKey, mailSender, tableId)
+	signingKey := ticketing.SigningKey{PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 	if err != nil {
 		t.Fatalf("creating a ticket domain instance: %s", err.Error())
 	}
 
-	userDomain, err := user.NewUserDomain(database, "testing")
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{})
 	if err != nil {
 		t.Fatalf("creating user domain instance: %s", err.Error())
 	}
 
 	t.Run("Email not found", func(t *testing.T) {
-		t.Skip("We don't do any parameter checking on mock server")
-
 		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 		defer cancel()
 
@@ -51,7 +64,7 @@ func TestTicketDomain_ValidatePaymentReceipt(t *testing.T) {
 		defer cancel()
 
 		email := "johndoe+happy@example.com"
-		err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{
+		_, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{
 			Name:  "John Doe",
 			Email: email,
 		})
@@ -76,3 +89,151 @@ func TestTicketDomain_ValidatePaymentReceipt(t *testing.T) {
 		}
 	})
 }
+
+func TestTicketDomain_ValidatePaymentReceipt_WalletPasses(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+	}
+
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{{PublicKey: publicKey, PrivateKey: privateKey}})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	// A self-signed certificate stands in for both the Pass Type ID and the WWDR certificate.
+	passKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ecdsa key: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.test.teknumconf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, passKey.Public(), passKey)
+	if err != nil {
+		t.Fatalf("creating certificate: %s", err.Error())
+	}
+
+	passKeyDer, err := x509.MarshalPKCS8PrivateKey(passKey)
+	if err != nil {
+		t.Fatalf("marshaling private key: %s", err.Error())
+	}
+
+	certificatePem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+	applePassSigner, err := wallet.NewApplePassSigner(wallet.ApplePassConfig{
+		PassTypeIdentifier:      "pass.test.teknumconf",
+		TeamIdentifier:          "TEAM123456",
+		OrganizationName:        "Teknologi Umum",
+		EventName:               "TeknumConf 2023",
+		Certificate:             certificatePem,
+		PrivateKey:              pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: passKeyDer}),
+		IntermediateCertificate: certificatePem,
+	})
+	if err != nil {
+		t.Fatalf("creating apple pass signer: %s", err.Error())
+	}
+
+	googleKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating rsa key: %s", err.Error())
+	}
+
+	googleWalletIssuer, err := wallet.NewGoogleWalletIssuer(wallet.GoogleWalletConfig{
+		IssuerId:            "3388000000012345678",
+		ClassSuffix:         "teknumconf-2023",
+		ServiceAccountEmail: "wallet@teknumconf.iam.gserviceaccount.com",
+		PrivateKey:          pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(googleKey)}),
+	})
+	if err != nil {
+		t.Fatalf("creating google wallet issuer: %s", err.Error())
+	}
+
+	outboxStore, err := mailer.NewFileOutboxStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating outbox store: %s", err.Error())
+	}
+
+	mailTransport := mailer.NewMemoryTransport()
+	mailOutbox, err := mailer.NewOutbox(outboxStore, mailer.NewMailSenderWithTransport(mailTransport, mailer.DefaultFrom), mailer.OutboxOptions{})
+	if err != nil {
+		t.Fatalf("creating outbox: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailOutbox, mailTemplates, ticketing.TicketDomainOptions{
+		Wallet: &wallet.Wallet{Apple: applePassSigner, Google: googleWalletIssuer},
+		Event:  ticketing.Event{Name: "TeknumConf 2024"},
+	})
+	if err != nil {
+		t.Fatalf("creating a ticket domain instance: %s", err.Error())
+	}
+
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating user domain instance: %s", err.Error())
+	}
+
+	email := "johndoe+wallet@example.com"
+	_, err = userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{
+		Name:  "John Doe",
+		Email: email,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	participant := user.User{Name: "John Doe", Email: email}
+	err = ticketDomain.StorePaymentReceipt(ctx, participant, strings.NewReader("Hello world! This is not a photo. Yet this will be a text file."), "text/plain")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	sum, err := ticketDomain.ValidatePaymentReceipt(ctx, participant)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if sum == "" {
+		t.Error("expecting sum to have value, got empty string")
+	}
+
+	// The mail is only queued, nothing is delivered until the outbox is processed.
+	if len(mailTransport.Messages()) != 0 {
+		t.Errorf("expecting no delivered message before processing the outbox, got %d", len(mailTransport.Messages()))
+	}
+
+	_, err = mailOutbox.ProcessDue(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	// The other one acknowledges the payment receipt.
+	messages := mailTransport.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expecting 2 delivered messages, got %d", len(messages))
+	}
+
+	ticketMessage := string(messages[0].Message)
+	if !strings.Contains(ticketMessage, "Subject: TeknumConf 2023: Tiket Anda!") {
+		ticketMessage = string(messages[1].Message)
+	}
+
+	for _, expect := range []string{
+		"Subject: TeknumConf 2023: Tiket Anda!",
+		"filename=ticket.pdf",
+		"Content-Description: Tiket TeknumConf 2024",
+		"filename=ticket.pkpass",
+		"Content-Description: Apple Wallet pass TeknumConf 2024",
+		"https://pay.google.com/gp/v/save/",
+	} {
+		if !strings.Contains(ticketMessage, expect) {
+			t.Errorf("expecting the ticket mail to contain %s", expect)
+		}
+	}
+}
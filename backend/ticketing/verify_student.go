@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"time"
 
-	"conf/nocodb"
 	"conf/user"
 	"github.com/getsentry/sentry-go"
 )
@@ -15,10 +14,8 @@ func (t *TicketDomain) VerifyIsStudent(ctx context.Context, user user.User) (err
 	span := sentry.StartSpan(ctx, "ticketing.verify_is_student", sentry.WithTransactionName("VerifyIsStudent"))
 	defer span.Finish()
 
-	var rawTicketingResults []Ticketing
-	_, err = t.db.ListTableRecords(ctx, t.tableId, &rawTicketingResults, nocodb.ListTableRecordOptions{
-		Where: fmt.Sprintf("(Email,eq,%s)", user.Email),
-		Sort:  []nocodb.Sort{nocodb.SortDescending("CreatedAt")},
+	rawTicketingResults, _, err := t.repository.ListTickets(ctx, TicketQuery{
+		Email: user.Email,
 		Limit: 1,
 	})
 	if err != nil {
@@ -31,13 +28,13 @@ func (t *TicketDomain) VerifyIsStudent(ctx context.Context, user user.User) (err
 
 	var ticketing = rawTicketingResults[0]
 
-	err = t.db.UpdateTableRecords(ctx, "TODO: Table Id", []any{NullTicketing{
+	err = t.repository.UpdateTicket(ctx, NullTicketing{
 		Id:        sql.NullInt64{Int64: ticketing.Id, Valid: true},
 		Student:   sql.NullBool{Bool: true, Valid: true},
 		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
-	}})
+	})
 	if err != nil {
-		return fmt.Errorf("updating table records: %w", err)
+		return fmt.Errorf("updating ticket: %w", err)
 	}
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,7 +19,13 @@ func TestTicketDomain_VerifyIsStudent(t *testing.T) {
 		return
 	}
 
-	ticketDomain, err := ticketing.NewTicketDomain(database, bucket, privateKey, publicKey, mailSender, tableId)
+	signingKey := ticketing.SigningKey{PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
 	if err != nil {
 		t.Fatalf("creating a ticket domain instance: %s", err.Error())
 	}
@@ -27,7 +34,13 @@ func TestTicketDomain_VerifyIsStudent(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 		defer cancel()
 
-		err := ticketDomain.VerifyIsStudent(ctx, user.User{Email: "aji@test.com"})
+		student := user.User{Email: "aji@test.com"}
+		err := ticketDomain.StorePaymentReceipt(ctx, student, strings.NewReader("Hello world! This is not a photo. Yet this will be a text file."), "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		err = ticketDomain.VerifyIsStudent(ctx, student)
 		if err != nil {
 			t.Errorf("unexpected error: %s", err.Error())
 		}
@@ -1,70 +1,46 @@
 package ticketing
 
 import (
-	"bytes"
 	"context"
-	"crypto/ed25519"
-	"crypto/sha512"
 	"database/sql"
-	"encoding/base64"
-	"encoding/hex"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 
-	"conf/nocodb"
 	"github.com/getsentry/sentry-go"
 )
 
-// VerifyTicket will verify a ticket from the QR code payload. It will disassemble the payload and validate
-// the signature and mark the ticket as used. Each ticket can only be used once.
+// VerifyTicket will verify a ticket from the QR code payload on the checkpoint. It will disassemble the
+// payload, validate the signature and record the redemption of the ticket.
 //
-// If the signature is invalid or the ticket is used, it will return ErrInvalidTicket error.
-func (t *TicketDomain) VerifyTicket(ctx context.Context, payload []byte) (ticketing Ticketing, err error) {
+// A ticket with entitlements can only be used on the checkpoints it's entitled to, once per checkpoint.
+// A ticket without any entitlements is a general admission ticket that can only be used once, on any
+// checkpoint. Either way, it holds even if it's scanned by multiple gates at the same time.
+//
+// If the signature is invalid, the ticket is not entitled to the checkpoint, or it's been used, it will
+// return ErrInvalidTicket error. If the ticket is revoked or the QR code has been superseded by
+// ReissueTicket, it will return ErrRevokedTicket error.
+func (t *TicketDomain) VerifyTicket(ctx context.Context, payload []byte, checkpoint string) (ticketing Ticketing, err error) {
 	span := sentry.StartSpan(ctx, "ticketing.verify_ticket", sentry.WithTransactionName("VerifyTicket"))
 	defer span.Finish()
 
-	if len(payload) == 0 {
-		return Ticketing{}, ValidationError{Errors: []string{"payload is empty"}}
-	}
-
-	// Separate the payload into the signature + email + random id that's generated from ValidatePaymentReceipt
-	rawSignature, payloadAfter, found := bytes.Cut(payload, []byte(";"))
-	if !found {
-		return Ticketing{}, ErrInvalidTicket
-	}
-
-	rawTicketId, rawHashedEmail, found := bytes.Cut(payloadAfter, []byte(":"))
-	if !found {
-		return Ticketing{}, ErrInvalidTicket
+	if checkpoint != "" {
+		if err := validateCheckpoints([]string{checkpoint}); err != nil {
+			return Ticketing{}, err
+		}
 	}
 
-	ticketId, err := strconv.ParseInt(string(rawTicketId), 10, 64)
+	ticketPayload, err := ParseTicketPayload(t.keyring, payload)
 	if err != nil {
-		return Ticketing{}, ErrInvalidTicket
+		return Ticketing{}, err
 	}
 
-	userHashedEmail, err := base64.StdEncoding.DecodeString(string(rawHashedEmail))
-	if err != nil {
-		return Ticketing{}, fmt.Errorf("decoding base64 string for email: %w", err)
-	}
-
-	signature, err := hex.DecodeString(string(rawSignature))
-	if err != nil {
-		return Ticketing{}, fmt.Errorf("decoding hex string for signature: %w", err)
+	if !ticketPayload.Entitles(checkpoint) {
+		return Ticketing{}, fmt.Errorf("%w (not entitled to %s)", ErrInvalidTicket, checkpoint)
 	}
 
-	// Validate the signature and its message using ed25519. If it's invalid, return ErrInvalidTicket
-	signatureValidated := ed25519.Verify(*t.publicKey, payloadAfter, signature)
-	if !signatureValidated {
-		return Ticketing{}, fmt.Errorf("%w (verifying signature)", ErrInvalidTicket)
-	}
-
-	// Check the ticket if it's been used before. If it is, return ErrInvalidTicket. Decorate it a bit.
-	var rawTicketingResults []Ticketing
-	_, err = t.db.ListTableRecords(ctx, t.tableId, &rawTicketingResults, nocodb.ListTableRecordOptions{
-		Where: fmt.Sprintf("(Id,eq,%d)~and(Used,eq,false)", ticketId),
-		Sort:  []nocodb.Sort{nocodb.SortDescending("CreatedAt")},
+	rawTicketingResults, _, err := t.repository.ListTickets(ctx, TicketQuery{
+		Id:    sql.NullInt64{Int64: ticketPayload.TicketId, Valid: true},
 		Limit: 1,
 	})
 	if err != nil {
@@ -77,22 +53,30 @@ func (t *TicketDomain) VerifyTicket(ctx context.Context, payload []byte) (ticket
 
 	ticketing = rawTicketingResults[0]
 
-	// Validate email
-	sha384Hasher := sha512.New384()
-	sha384Hasher.Write([]byte(ticketing.Email))
-	hashedEmail := sha384Hasher.Sum(nil)
-	if !bytes.Equal(hashedEmail, userHashedEmail) {
+	if !ticketPayload.MatchEmail(ticketing.Email) {
 		return Ticketing{}, fmt.Errorf("%w (mismatched email)", ErrInvalidTicket)
 	}
 
-	// Mark the ticket as used
-	err = t.db.UpdateTableRecords(ctx, t.tableId, []any{NullTicketing{
-		Id:        sql.NullInt64{Int64: ticketing.Id, Valid: true},
-		Used:      sql.NullBool{Bool: true, Valid: true},
-		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
-	}})
+	if ticketing.Revoked || ticketPayload.Version < ticketing.Version {
+		return Ticketing{}, ErrRevokedTicket
+	}
+
+	// General admission tickets are redeemed as a whole, regardless of the checkpoint it's scanned on.
+	redeemOn := checkpoint
+	if len(ticketPayload.Entitlements) == 0 {
+		redeemOn = ""
+	}
+
+	// The lookup above might be stale if another gate scans the same ticket at the same time, the
+	// repository makes sure only one of them is able to redeem it.
+	err = t.repository.RedeemTicket(ctx, ticketing.Id, redeemOn, time.Now())
 	if err != nil {
-		return Ticketing{}, fmt.Errorf("updating table records: %w", err)
+		return Ticketing{}, fmt.Errorf("redeeming ticket: %w", err)
+	}
+
+	ticketing.Used = true
+	if redeemOn != "" {
+		ticketing.RedeemedCheckpoints = strings.Join(append(splitList(ticketing.RedeemedCheckpoints), redeemOn), ",")
 	}
 
 	return ticketing, nil
@@ -0,0 +1,227 @@
+package ticketing_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"conf/ticketing"
+	"conf/user"
+)
+
+func TestTicketDomain_VerifyTicket(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating new ed25519 key: %s", err.Error())
+		return
+	}
+
+	signingKey := ticketing.SigningKey{PublicKey: publicKey, PrivateKey: privateKey}
+	keyring, err := ticketing.NewKeyring("", []ticketing.SigningKey{signingKey})
+	if err != nil {
+		t.Fatalf("creating a keyring: %s", err.Error())
+	}
+
+	ticketDomain, err := ticketing.NewTicketDomain(ticketingRepository, bucket, keyring, mailSender, mailTemplates, ticketing.TicketDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating a ticket domain instance: %s", err.Error())
+	}
+
+	// issueTicket stores a payment receipt for the email, then builds the QR code payload
+	// the same way ValidatePaymentReceipt does.
+	issueTicket := func(t *testing.T, ctx context.Context, email string, entitlements ...string) []byte {
+		err := ticketDomain.StorePaymentReceipt(ctx, user.User{Email: email}, strings.NewReader("Hello world! This is not a photo. Yet this will be a text file."), "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: email, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(tickets) == 0 {
+			t.Fatalf("expecting ticket for %s to exists, got none", email)
+		}
+
+		hashedEmail := sha512.Sum384([]byte(email))
+		return mustSign(t, ticketing.TicketPayload{
+			TicketId:     tickets[0].Id,
+			HashedEmail:  hashedEmail[:],
+			Entitlements: entitlements,
+		}, signingKey)
+	}
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		ticketPayload, err := ticketing.ParseTicketPayload(keyring, issueTicket(t, ctx, "johndoe+forged@example.com"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		// Sign the same content with another key under the same key ID
+		_, forgedPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generating new ed25519 key: %s", err.Error())
+		}
+
+		payload := mustSign(t, ticketPayload, ticketing.SigningKey{PublicKey: publicKey, PrivateKey: forgedPrivateKey})
+
+		_, err = ticketDomain.VerifyTicket(ctx, payload, "")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+	})
+
+	t.Run("Ticket can only be used once", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		email := "johndoe+once@example.com"
+		payload := issueTicket(t, ctx, email)
+
+		verifiedTicket, err := ticketDomain.VerifyTicket(ctx, payload, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if verifiedTicket.Email != email {
+			t.Errorf("expecting email to be %s, got %s", email, verifiedTicket.Email)
+		}
+
+		_, err = ticketDomain.VerifyTicket(ctx, payload, "")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+	})
+
+	t.Run("Legacy payload", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		email := "johndoe+legacy@example.com"
+		issueTicket(t, ctx, email)
+
+		tickets, _, err := ticketingRepository.ListTickets(ctx, ticketing.TicketQuery{Email: email, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		// Tickets issued before entitlements exist does not have the entitlements part
+		hashedEmail := sha512.Sum384([]byte(email))
+		message := fmt.Sprintf("%d:%s", tickets[0].Id, base64.StdEncoding.EncodeToString(hashedEmail[:]))
+		payload := hex.EncodeToString(ed25519.Sign(privateKey, []byte(message))) + ";" + message
+
+		_, err = ticketDomain.VerifyTicket(ctx, []byte(payload), "day-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	})
+
+	t.Run("Entitled checkpoints can be used once each", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		payload := issueTicket(t, ctx, "johndoe+multiday@example.com", "day-1", "day-2")
+
+		_, err := ticketDomain.VerifyTicket(ctx, payload, "workshop-a")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+
+		_, err = ticketDomain.VerifyTicket(ctx, payload, "")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+
+		verifiedTicket, err := ticketDomain.VerifyTicket(ctx, payload, "day-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if verifiedTicket.RedeemedCheckpoints != "day-1" {
+			t.Errorf("expecting redeemed checkpoints to be day-1, got %s", verifiedTicket.RedeemedCheckpoints)
+		}
+
+		_, err = ticketDomain.VerifyTicket(ctx, payload, "day-1")
+		if !errors.Is(err, ticketing.ErrInvalidTicket) {
+			t.Errorf("expecting an error of ErrInvalidTicket, instead got %v", err)
+		}
+
+		verifiedTicket, err = ticketDomain.VerifyTicket(ctx, payload, "day-2")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if verifiedTicket.RedeemedCheckpoints != "day-1,day-2" {
+			t.Errorf("expecting redeemed checkpoints to be day-1,day-2, got %s", verifiedTicket.RedeemedCheckpoints)
+		}
+	})
+
+	t.Run("Invalid checkpoint", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		payload := issueTicket(t, ctx, "johndoe+badcheckpoint@example.com", "day-1")
+
+		_, err := ticketDomain.VerifyTicket(ctx, payload, "day 1; DROP")
+		var validationError ticketing.ValidationError
+		if !errors.As(err, &validationError) {
+			t.Errorf("expecting a validation error, instead got %v", err)
+		}
+	})
+
+	t.Run("Concurrent scans of the same ticket", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		payload := issueTicket(t, ctx, "johndoe+concurrent@example.com")
+
+		const scanners = 50
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var succeeded int
+		var unexpectedErrors []error
+		start := make(chan struct{})
+		for i := 0; i < scanners; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+
+				_, err := ticketDomain.VerifyTicket(ctx, payload, "")
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					succeeded++
+				case !errors.Is(err, ticketing.ErrInvalidTicket):
+					unexpectedErrors = append(unexpectedErrors, err)
+				}
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+
+		for _, err := range unexpectedErrors {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		if succeeded != 1 {
+			t.Errorf("expecting exactly one scan to succeed, got %d", succeeded)
+		}
+	})
+}
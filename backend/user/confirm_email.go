@@ -0,0 +1,76 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"conf/mailer"
+	"conf/mailtemplate"
+	"github.com/getsentry/sentry-go"
+)
+
+// ConfirmEmail confirms the email address of the confirmation token sent on registration, which makes the
+// participant eligible for the payment instructions. Confirming an address twice is not an error.
+//
+// It will return ErrInvalidConfirmationToken if the token is malformed, tampered, or expired, and
+// ErrUserEmailNotFound if the participant does not exist anymore.
+func (u *UserDomain) ConfirmEmail(ctx context.Context, token string) (string, error) {
+	span := sentry.StartSpan(ctx, "user.confirm_email", sentry.WithTransactionName("ConfirmEmail"))
+	defer span.Finish()
+
+	if u.confirmationSigner == nil {
+		return "", fmt.Errorf("%w: email confirmation is disabled", ErrInvalidConfirmationToken)
+	}
+
+	email, err := u.confirmationSigner.Verify(token, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	err = u.repository.ConfirmEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserEmailNotFound) {
+			return "", err
+		}
+
+		return "", fmt.Errorf("confirming email: %w", err)
+	}
+
+	return email, nil
+}
+
+func (u *UserDomain) sendConfirmationMail(ctx context.Context, user User) error {
+	link, err := u.confirmationSigner.Link(u.confirmationUrl, user.Email, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rendered, err := u.templates.Render(mailtemplate.EmailConfirmation, user.Locale, map[string]any{
+		"name":             user.Name,
+		"email":            user.Email,
+		"confirmationLink": link,
+	})
+	if err != nil {
+		return fmt.Errorf("rendering email confirmation mail: %w", err)
+	}
+
+	err = u.mailer.Send(ctx, &mailer.Mail{
+		RecipientName:  user.Name,
+		RecipientEmail: user.Email,
+		Subject:        rendered.Subject,
+		PlainTextBody:  rendered.PlainTextBody,
+		HtmlBody:       rendered.HtmlBody,
+	})
+	if err != nil {
+		return fmt.Errorf("sending confirmation mail: %w", err)
+	}
+
+	err = u.repository.MarkConfirmationSent(ctx, user.Email, time.Now())
+	if err != nil {
+		return fmt.Errorf("marking confirmation sent: %w", err)
+	}
+
+	return nil
+}
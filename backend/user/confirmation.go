@@ -0,0 +1,102 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidConfirmationToken is returned for a confirmation token that's malformed, tampered, or expired.
+var ErrInvalidConfirmationToken = errors.New("invalid confirmation token")
+
+// ConfirmationSigner signs the email confirmation tokens of the double opt-in. A token carries the email
+// address and its expiry, authenticated with HMAC-SHA256, so nothing has to be stored until it's confirmed.
+type ConfirmationSigner struct {
+	key    []byte
+	expiry time.Duration
+}
+
+// NewConfirmationSigner creates a signer with a secret key of at least 32 bytes. The tokens are valid for
+// expiry since they are signed.
+func NewConfirmationSigner(key []byte, expiry time.Duration) (*ConfirmationSigner, error) {
+	if len(key) < 32 {
+		return nil, fmt.Errorf("key must be at least 32 bytes, got %d", len(key))
+	}
+
+	if expiry <= 0 {
+		return nil, fmt.Errorf("expiry must be positive")
+	}
+
+	return &ConfirmationSigner{key: key, expiry: expiry}, nil
+}
+
+// Sign creates a URL-safe token of the email address that expires after the signer's expiry.
+func (c *ConfirmationSigner) Sign(email string, now time.Time) string {
+	payload := strconv.FormatInt(now.Add(c.expiry).Unix(), 10) + ":" + email
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(c.mac(payload))
+}
+
+// Verify returns the email address of a token signed by Sign, as long as it has not expired.
+func (c *ConfirmationSigner) Verify(token string, now time.Time) (string, error) {
+	encodedPayload, encodedMac, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", fmt.Errorf("%w: malformed", ErrInvalidConfirmationToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed payload", ErrInvalidConfirmationToken)
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMac)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed signature", ErrInvalidConfirmationToken)
+	}
+
+	if !hmac.Equal(mac, c.mac(string(payload))) {
+		return "", fmt.Errorf("%w: mismatched signature", ErrInvalidConfirmationToken)
+	}
+
+	rawExpiry, email, ok := strings.Cut(string(payload), ":")
+	if !ok {
+		return "", fmt.Errorf("%w: malformed payload", ErrInvalidConfirmationToken)
+	}
+
+	expiry, err := strconv.ParseInt(rawExpiry, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed expiry", ErrInvalidConfirmationToken)
+	}
+
+	if now.After(time.Unix(expiry, 0)) {
+		return "", fmt.Errorf("%w: expired", ErrInvalidConfirmationToken)
+	}
+
+	return email, nil
+}
+
+// Link appends the token of the email address to the confirmation page URL as the `token` query parameter.
+func (c *ConfirmationSigner) Link(pageUrl string, email string, now time.Time) (string, error) {
+	link, err := url.Parse(pageUrl)
+	if err != nil {
+		return "", fmt.Errorf("parsing confirmation url: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("token", c.Sign(email, now))
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+func (c *ConfirmationSigner) mac(payload string) []byte {
+	hash := hmac.New(sha256.New, c.key)
+	hash.Write([]byte("email-confirmation:"))
+	hash.Write([]byte(payload))
+	return hash.Sum(nil)
+}
@@ -0,0 +1,91 @@
+package user_test
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"conf/user"
+)
+
+func TestConfirmationSigner(t *testing.T) {
+	signer, err := user.NewConfirmationSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	if err != nil {
+		t.Fatalf("creating signer: %s", err.Error())
+	}
+
+	now := time.Now()
+	token := signer.Sign("johndoe@example.com", now)
+
+	t.Run("Valid", func(t *testing.T) {
+		email, err := signer.Verify(token, now.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if email != "johndoe@example.com" {
+			t.Errorf("expecting johndoe@example.com, got %s", email)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		_, err := signer.Verify(token, now.Add(time.Hour+time.Second))
+		if !errors.Is(err, user.ErrInvalidConfirmationToken) {
+			t.Errorf("expecting ErrInvalidConfirmationToken, got %v", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		otherSigner, err := user.NewConfirmationSigner(bytes.Repeat([]byte("x"), 32), time.Hour)
+		if err != nil {
+			t.Fatalf("creating signer: %s", err.Error())
+		}
+
+		payload, signature, _ := strings.Cut(token, ".")
+		forgedPayload, _, _ := strings.Cut(signer.Sign("janedoe@example.com", now), ".")
+		for _, tampered := range []string{
+			forgedPayload + "." + signature,
+			payload + "." + signature[:len(signature)-2] + "AA",
+			otherSigner.Sign("johndoe@example.com", now),
+			payload,
+			"",
+			"!!!.!!!",
+		} {
+			_, err := signer.Verify(tampered, now)
+			if !errors.Is(err, user.ErrInvalidConfirmationToken) {
+				t.Errorf("Verify(%q): expecting ErrInvalidConfirmationToken, got %v", tampered, err)
+			}
+		}
+	})
+
+	t.Run("Link", func(t *testing.T) {
+		link, err := signer.Link("https://conference.example.com/confirm-email?utm_source=mail", "johndoe@example.com", now)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		parsed, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("parsing link: %s", err.Error())
+		}
+
+		if parsed.Query().Get("utm_source") != "mail" {
+			t.Errorf("expecting the existing query to be kept, got %s", link)
+		}
+
+		email, err := signer.Verify(parsed.Query().Get("token"), now)
+		if err != nil || email != "johndoe@example.com" {
+			t.Errorf("expecting the link token to be valid, got %q %v", email, err)
+		}
+	})
+
+	t.Run("Short key", func(t *testing.T) {
+		_, err := user.NewConfirmationSigner([]byte("short"), time.Hour)
+		if err == nil {
+			t.Error("expecting an error, got nil")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+)
+
+// ErrInvalidEmail is returned for an email address that can't receive mails.
+var ErrInvalidEmail = errors.New("invalid email")
+
+// NormalizeEmail validates the syntax of a bare email address, such as "johndoe@example.com", and returns it
+// trimmed and lowercased. Display names, comments, and domains without a dot are rejected.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidEmail)
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return "", fmt.Errorf("%w: malformed %q", ErrInvalidEmail, email)
+	}
+
+	_, domain, _ := strings.Cut(email, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", fmt.Errorf("%w: malformed domain %q", ErrInvalidEmail, domain)
+	}
+
+	return email, nil
+}
+
+// MXResolver looks up the mail exchangers of a domain, *net.Resolver satisfies it.
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// checkMailExchanger makes sure the domain of a normalized email address accepts mails. A domain that does
+// not exist, has no MX records, or has a null MX record (RFC 7505) is rejected. Lookup failures other than
+// that, such as a timeout, let the address through, a flaky resolver should not block registrations.
+func checkMailExchanger(ctx context.Context, resolver MXResolver, email string) error {
+	_, domain, _ := strings.Cut(email, "@")
+	records, err := resolver.LookupMX(ctx, domain)
+	if err != nil {
+		var dnsError *net.DNSError
+		if errors.As(err, &dnsError) && dnsError.IsNotFound {
+			return fmt.Errorf("%w: %s does not accept mails", ErrInvalidEmail, domain)
+		}
+
+		return nil
+	}
+
+	for _, record := range records {
+		if record.Host != "." && record.Host != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s does not accept mails", ErrInvalidEmail, domain)
+}
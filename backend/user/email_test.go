@@ -0,0 +1,48 @@
+package user_test
+
+import (
+	"errors"
+	"testing"
+
+	"conf/user"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	testCases := []struct {
+		email    string
+		expected string
+		valid    bool
+	}{
+		{email: "johndoe@example.com", expected: "johndoe@example.com", valid: true},
+		{email: "  JohnDoe+Conf@Example.COM\t", expected: "johndoe+conf@example.com", valid: true},
+		{email: "john.doe@mail.example.co.id", expected: "john.doe@mail.example.co.id", valid: true},
+		{email: "", valid: false},
+		{email: "johndoe", valid: false},
+		{email: "johndoe@", valid: false},
+		{email: "@example.com", valid: false},
+		{email: "johndoe@localhost", valid: false},
+		{email: "johndoe@example..com", valid: false},
+		{email: "johndoe@example.com.", valid: false},
+		{email: "John Doe <johndoe@example.com>", valid: false},
+		{email: "johndoe@example.com, janedoe@example.com", valid: false},
+		{email: "john doe@example.com", valid: false},
+	}
+
+	for _, testCase := range testCases {
+		email, err := user.NormalizeEmail(testCase.email)
+		if testCase.valid {
+			if err != nil {
+				t.Errorf("NormalizeEmail(%q): unexpected error: %s", testCase.email, err.Error())
+			}
+
+			if email != testCase.expected {
+				t.Errorf("NormalizeEmail(%q): expecting %q, got %q", testCase.email, testCase.expected, email)
+			}
+			continue
+		}
+
+		if !errors.Is(err, user.ErrInvalidEmail) {
+			t.Errorf("NormalizeEmail(%q): expecting ErrInvalidEmail, got %v", testCase.email, err)
+		}
+	}
+}
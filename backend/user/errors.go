@@ -14,3 +14,9 @@ func (v ValidationError) Error() string {
 }
 
 var ErrUserEmailNotFound = errors.New("user email not found")
+
+// ErrUserNotFound is returned by Repository.FindOne if no user matches the lookup.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserEmailExists is returned by Repository.InsertUser if the email address is registered already.
+var ErrUserEmailExists = errors.New("user email already exists")
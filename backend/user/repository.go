@@ -0,0 +1,53 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Repository is the storage layer that UserDomain depends on. Two implementations are available:
+// NocoDBRepository and PostgresRepository, pick one based on the configured database driver.
+type Repository interface {
+	// InsertUser stores a single new user entry. It returns ErrUserEmailExists if the storage enforces unique
+	// email addresses and the address is taken.
+	InsertUser(ctx context.Context, user User) error
+	// ListUsers returns a single page of users that matches the query, starting from query.Offset.
+	// isLastPage will be true if there are no more entries after the returned page.
+	ListUsers(ctx context.Context, query UserQuery) (users []User, isLastPage bool, err error)
+	// FindOne returns the earliest user entry that matches the lookup, without paging through the rest. It
+	// returns ErrUserNotFound if there is none.
+	FindOne(ctx context.Context, lookup UserLookup) (User, error)
+	// ConfirmEmail marks every user entry of the email address as confirmed. It returns ErrUserEmailNotFound
+	// if there is none.
+	ConfirmEmail(ctx context.Context, email string) error
+	// MarkConfirmationSent records when the confirmation link was last mailed on every user entry of the email
+	// address.
+	MarkConfirmationSent(ctx context.Context, email string, sentAt time.Time) error
+}
+
+// UserQuery narrows down the users returned by Repository.ListUsers. Zero-valued fields are not filtered.
+type UserQuery struct {
+	Email       string
+	Type        Type
+	IsProcessed sql.NullBool
+	Offset      int64
+	// Limit defaults to the storage's page size if it's zero.
+	Limit int64
+}
+
+// UserLookup identifies a single user for Repository.FindOne. Zero-valued fields are not filtered, but at least
+// one of them must be set.
+type UserLookup struct {
+	Email string
+	Type  Type
+}
+
+func (l UserLookup) validate() error {
+	if l.Email == "" && l.Type == "" {
+		return fmt.Errorf("lookup is empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"conf/nocodb"
+)
+
+// NocoDBRepository implements Repository on top of a NocoDB table.
+type NocoDBRepository struct {
+	users             *nocodb.Table[nocodbUser]
+	confirmations     *nocodb.Table[emailConfirmation]
+	confirmationsSent *nocodb.Table[confirmationSent]
+}
+
+// confirmationSent is the subset of a user entry that MarkConfirmationSent updates.
+type confirmationSent struct {
+	Id                 int64
+	ConfirmationSentAt time.Time
+}
+
+// nocodbUser is a user entry as it's stored on NocoDB. The EmailConfirmed column is added along with the email
+// confirmation, entries made before that don't have it and count as confirmed, since they registered before the
+// confirmation was required. ConfirmationSentAt is empty until the link is mailed.
+type nocodbUser struct {
+	User
+	EmailConfirmed     *bool
+	ConfirmationSentAt *time.Time `json:",omitempty"`
+}
+
+func newNocoDBUser(user User) nocodbUser {
+	entry := nocodbUser{User: user, EmailConfirmed: &user.EmailConfirmed}
+	if !user.ConfirmationSentAt.IsZero() {
+		entry.ConfirmationSentAt = &user.ConfirmationSentAt
+	}
+
+	return entry
+}
+
+func (n nocodbUser) user() User {
+	user := n.User
+	user.EmailConfirmed = n.EmailConfirmed == nil || *n.EmailConfirmed
+	if n.ConfirmationSentAt != nil {
+		user.ConfirmationSentAt = *n.ConfirmationSentAt
+	}
+
+	return user
+}
+
+// emailConfirmation is the subset of a user entry that ConfirmEmail reads and updates.
+type emailConfirmation struct {
+	Id             int64
+	EmailConfirmed bool
+}
+
+func NewNocoDBRepository(db *nocodb.Client, tableId string) (*NocoDBRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	if tableId == "" {
+		return nil, fmt.Errorf("tableId is empty")
+	}
+
+	users, err := nocodb.NewTable[nocodbUser](db, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmations, err := nocodb.NewTable[emailConfirmation](db, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmationsSent, err := nocodb.NewTable[confirmationSent](db, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NocoDBRepository{users: users, confirmations: confirmations, confirmationsSent: confirmationsSent}, nil
+}
+
+func (n *NocoDBRepository) InsertUser(ctx context.Context, user User) error {
+	_, err := n.users.Create(ctx, newNocoDBUser(user))
+	if err != nil {
+		return fmt.Errorf("creating table records: %w", err)
+	}
+
+	return nil
+}
+
+func (n *NocoDBRepository) ListUsers(ctx context.Context, query UserQuery) ([]User, bool, error) {
+	var conditions []nocodb.Filter
+	if query.Email != "" {
+		conditions = append(conditions, nocodb.Eq("Email", query.Email))
+	}
+
+	if query.Type != "" {
+		conditions = append(conditions, nocodb.Eq("Type", string(query.Type)))
+	}
+
+	if query.IsProcessed.Valid {
+		conditions = append(conditions, nocodb.Eq("IsProcessed", query.IsProcessed.Bool))
+	}
+
+	entries, pageInfo, err := n.users.List(ctx, nocodb.ListTableRecordOptions{
+		Where:  nocodb.And(conditions...).String(),
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("list table records: %w", err)
+	}
+
+	users := make([]User, 0, len(entries))
+	for _, entry := range entries {
+		users = append(users, entry.user())
+	}
+
+	return users, pageInfo.IsLastPage, nil
+}
+
+func (n *NocoDBRepository) FindOne(ctx context.Context, lookup UserLookup) (User, error) {
+	if err := lookup.validate(); err != nil {
+		return User{}, err
+	}
+
+	var conditions []nocodb.Filter
+	if lookup.Email != "" {
+		conditions = append(conditions, nocodb.Eq("Email", lookup.Email))
+	}
+
+	if lookup.Type != "" {
+		conditions = append(conditions, nocodb.Eq("Type", string(lookup.Type)))
+	}
+
+	entries, _, err := n.users.List(ctx, nocodb.ListTableRecordOptions{
+		Sort:  []nocodb.Sort{nocodb.SortAscending("Id")},
+		Where: nocodb.And(conditions...).String(),
+		Limit: 1,
+	})
+	if err != nil {
+		return User{}, fmt.Errorf("list table records: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return User{}, ErrUserNotFound
+	}
+
+	return entries[0].user(), nil
+}
+
+func (n *NocoDBRepository) ConfirmEmail(ctx context.Context, email string) error {
+	var entries []emailConfirmation
+	for entry, err := range n.confirmations.All(ctx, nocodb.ListTableRecordOptions{
+		Fields: []string{"Id"},
+		Where:  nocodb.Eq("Email", email).String(),
+	}) {
+		if err != nil {
+			return fmt.Errorf("list table records: %w", err)
+		}
+
+		entry.EmailConfirmed = true
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return ErrUserEmailNotFound
+	}
+
+	err := n.confirmations.Update(ctx, entries...)
+	if err != nil {
+		return fmt.Errorf("updating table records: %w", err)
+	}
+
+	return nil
+}
+
+func (n *NocoDBRepository) MarkConfirmationSent(ctx context.Context, email string, sentAt time.Time) error {
+	var entries []confirmationSent
+	for entry, err := range n.confirmationsSent.All(ctx, nocodb.ListTableRecordOptions{
+		Fields: []string{"Id"},
+		Where:  nocodb.Eq("Email", email).String(),
+	}) {
+		if err != nil {
+			return fmt.Errorf("list table records: %w", err)
+		}
+
+		entry.ConfirmationSentAt = sentAt
+		entries = append(entries, entry)
+	}
+
+	err := n.confirmationsSent.Update(ctx, entries...)
+	if err != nil {
+		return fmt.Errorf("updating table records: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// postgresPageSize mimics NocoDB's default page size, so both repositories behave the same way.
+const postgresPageSize = 25
+
+// PostgresRepository implements Repository on top of the `users` table. The schema lives on the
+// migrations directory, make sure it's been migrated before using this repository.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) (*PostgresRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &PostgresRepository{db: db}, nil
+}
+
+// InsertUser returns ErrUserEmailExists if the email address is taken, case-insensitively, see the
+// users_email_lower_key index.
+func (p *PostgresRepository) InsertUser(ctx context.Context, user User) error {
+	var confirmationSentAt sql.NullTime
+	if !user.ConfirmationSentAt.IsZero() {
+		confirmationSentAt = sql.NullTime{Time: user.ConfirmationSentAt, Valid: true}
+	}
+
+	result, err := p.db.ExecContext(
+		ctx,
+		`INSERT INTO users (name, email, type, is_processed, locale, email_confirmed, confirmation_sent_at, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8) ON CONFLICT DO NOTHING`,
+		user.Name,
+		user.Email,
+		string(user.Type),
+		user.IsProcessed,
+		string(user.Locale),
+		user.EmailConfirmed,
+		confirmationSentAt,
+		user.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting user: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("acquiring affected rows: %w", err)
+	}
+
+	if affected == 0 {
+		return ErrUserEmailExists
+	}
+
+	return nil
+}
+
+// userColumns are the columns scanned by scanUser.
+const userColumns = `name, email, COALESCE(type::TEXT, ''), is_processed, locale, email_confirmed, confirmation_sent_at, created_at`
+
+func scanUser(row interface{ Scan(dest ...any) error }) (User, error) {
+	var user User
+	var confirmationSentAt sql.NullTime
+	err := row.Scan(&user.Name, &user.Email, &user.Type, &user.IsProcessed, &user.Locale, &user.EmailConfirmed, &confirmationSentAt, &user.CreatedAt)
+	if err != nil {
+		return User{}, err
+	}
+
+	user.ConfirmationSentAt = confirmationSentAt.Time
+	return user, nil
+}
+
+func (p *PostgresRepository) ListUsers(ctx context.Context, query UserQuery) ([]User, bool, error) {
+	var conditions []string
+	var args []any
+	if query.Email != "" {
+		args = append(args, query.Email)
+		conditions = append(conditions, "lower(email) = lower($"+strconv.Itoa(len(args))+")")
+	}
+
+	if query.Type != "" {
+		args = append(args, string(query.Type))
+		conditions = append(conditions, "type = $"+strconv.Itoa(len(args)))
+	}
+
+	if query.IsProcessed.Valid {
+		args = append(args, query.IsProcessed.Bool)
+		conditions = append(conditions, "is_processed = $"+strconv.Itoa(len(args)))
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = postgresPageSize
+	}
+
+	statement := `SELECT ` + userColumns + ` FROM users`
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// Fetch one more row than requested to know whether there is another page after this one.
+	args = append(args, limit+1, query.Offset)
+	statement += fmt.Sprintf(" ORDER BY id ASC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := p.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("querying users: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, false, fmt.Errorf("scanning row: %w", err)
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	if int64(len(users)) > limit {
+		return users[:limit], false, nil
+	}
+
+	return users, true, nil
+}
+
+func (p *PostgresRepository) FindOne(ctx context.Context, lookup UserLookup) (User, error) {
+	if err := lookup.validate(); err != nil {
+		return User{}, err
+	}
+
+	var conditions []string
+	var args []any
+	if lookup.Email != "" {
+		args = append(args, lookup.Email)
+		conditions = append(conditions, "lower(email) = lower($"+strconv.Itoa(len(args))+")")
+	}
+
+	if lookup.Type != "" {
+		args = append(args, string(lookup.Type))
+		conditions = append(conditions, "type = $"+strconv.Itoa(len(args)))
+	}
+
+	// users_email_lower_key covers the email lookup, so this does not scan the table.
+	user, err := scanUser(p.db.QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE `+strings.Join(conditions, " AND ")+` ORDER BY id ASC LIMIT 1`,
+		args...,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrUserNotFound
+		}
+
+		return User{}, fmt.Errorf("querying user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (p *PostgresRepository) ConfirmEmail(ctx context.Context, email string) error {
+	result, err := p.db.ExecContext(ctx, `UPDATE users SET email_confirmed = TRUE, updated_at = NOW() WHERE lower(email) = lower($1)`, email)
+	if err != nil {
+		return fmt.Errorf("updating users: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("acquiring affected rows: %w", err)
+	}
+
+	if affected == 0 {
+		return ErrUserEmailNotFound
+	}
+
+	return nil
+}
+
+func (p *PostgresRepository) MarkConfirmationSent(ctx context.Context, email string, sentAt time.Time) error {
+	_, err := p.db.ExecContext(ctx, `UPDATE users SET confirmation_sent_at = $2, updated_at = NOW() WHERE lower(email) = lower($1)`, email, sentAt)
+	if err != nil {
+		return fmt.Errorf("updating users: %w", err)
+	}
+
+	return nil
+}
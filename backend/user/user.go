@@ -2,31 +2,93 @@ package user
 
 import (
 	"context"
+	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"conf/nocodb"
+	"conf/i18n"
+	"conf/mailer"
+	"conf/mailtemplate"
 	"github.com/getsentry/sentry-go"
 )
 
 type UserDomain struct {
-	db      *nocodb.Client
-	tableId string
+	repository         Repository
+	mxResolver         MXResolver
+	confirmationSigner *ConfirmationSigner
+	confirmationUrl    string
+	// confirmationCooldown is how long a repeated registration waits before mailing another link.
+	confirmationCooldown time.Duration
+	mailer               mailer.Sender
+	templates            *mailtemplate.Registry
+	// registrationLocks serializes CreateParticipant calls per email address, striped by its hash, so two
+	// concurrent registrations can't both pass the uniqueness check. This only protects a single process, the
+	// Postgres repository enforces the uniqueness across processes with the users_email_lower_key index.
+	registrationLocks [64]sync.Mutex
 }
 
-func NewUserDomain(db *nocodb.Client, tableId string) (*UserDomain, error) {
-	if db == nil {
-		return nil, fmt.Errorf("db is nil")
+// UserDomainOptions configures the registration checks. The zero value only validates the syntax of the email
+// address, and confirms the participants right away.
+type UserDomainOptions struct {
+	// MXResolver rejects email addresses whose domain does not accept mails, it's skipped if nil.
+	MXResolver MXResolver
+	// ConfirmationSigner enables the double opt-in: new participants are mailed a confirmation link, and they
+	// are not confirmed until it's opened.
+	ConfirmationSigner *ConfirmationSigner
+	// ConfirmationUrl is the link that passes the `token` query parameter to ConfirmEmail.
+	ConfirmationUrl string
+	// ConfirmationCooldown is how long registering again waits before mailing another confirmation link,
+	// defaults to 15 minutes.
+	ConfirmationCooldown time.Duration
+	// Mailer and Templates send the mailtemplate.EmailConfirmation mail, they are required along with the
+	// ConfirmationSigner.
+	Mailer    mailer.Sender
+	Templates *mailtemplate.Registry
+}
+
+func NewUserDomain(repository Repository, options UserDomainOptions) (*UserDomain, error) {
+	if repository == nil {
+		return nil, fmt.Errorf("repository is nil")
 	}
 
-	if tableId == "" {
-		return nil, fmt.Errorf("tableId is empty")
+	if options.ConfirmationSigner != nil {
+		if options.ConfirmationUrl == "" {
+			return nil, fmt.Errorf("confirmation url is empty")
+		}
+
+		if options.Mailer == nil {
+			return nil, fmt.Errorf("mailer is nil")
+		}
+
+		if options.Templates == nil {
+			return nil, fmt.Errorf("templates is nil")
+		}
+
+		if !options.Templates.Has(mailtemplate.EmailConfirmation) {
+			return nil, fmt.Errorf("templates does not have the %s template", mailtemplate.EmailConfirmation)
+		}
+
+		if options.ConfirmationCooldown <= 0 {
+			options.ConfirmationCooldown = 15 * time.Minute
+		}
 	}
 
-	return &UserDomain{db: db, tableId: tableId}, nil
+	return &UserDomain{
+		repository:           repository,
+		mxResolver:           options.MXResolver,
+		confirmationSigner:   options.ConfirmationSigner,
+		confirmationUrl:      options.ConfirmationUrl,
+		confirmationCooldown: options.ConfirmationCooldown,
+		mailer:               options.Mailer,
+		templates:            options.Templates,
+	}, nil
 }
 
 type Type string
@@ -39,6 +101,9 @@ const (
 type CreateParticipantRequest struct {
 	Name  string
 	Email string
+	// Locale is the language the participant is contacted in, an empty or unsupported one is stored as
+	// i18n.Default.
+	Locale i18n.Locale
 }
 
 type User struct {
@@ -46,48 +111,118 @@ type User struct {
 	Email       string
 	Type        Type
 	IsProcessed bool
-	CreatedAt   time.Time
+	Locale      i18n.Locale
+	// EmailConfirmed is false until the participant opens the confirmation link. Only confirmed participants
+	// are eligible for the payment instructions.
+	EmailConfirmed bool
+	// ConfirmationSentAt is the last time the confirmation link was mailed, it's zero if it never was.
+	ConfirmationSentAt time.Time
+	CreatedAt          time.Time
 }
 
 func (c CreateParticipantRequest) validate() (errors []string) {
-	if c.Name == "" {
+	if strings.TrimSpace(c.Name) == "" {
 		errors = append(errors, "Invalid name")
 	}
 
-	if c.Email == "" {
+	if _, err := NormalizeEmail(c.Email); err != nil {
 		errors = append(errors, "Invalid email")
 	}
 
 	return errors
 }
 
-func (u *UserDomain) CreateParticipant(ctx context.Context, req CreateParticipantRequest) error {
+// Registration is the state of a participant's registration.
+type Registration struct {
+	User User
+	// Created is false if the email address was registered before, User is the existing registration then.
+	Created bool
+}
+
+// CreateParticipant registers a participant, unique by the normalized email address. Registering an email
+// address again is not an error, it returns the existing registration as is. The confirmation link is mailed
+// again if the existing registration has not been confirmed yet, at most once every ConfirmationCooldown.
+func (u *UserDomain) CreateParticipant(ctx context.Context, req CreateParticipantRequest) (Registration, error) {
 	span := sentry.StartSpan(ctx, "user.create_participant")
 	defer span.Finish()
 
 	if errors := req.validate(); len(errors) > 0 {
-		return &ValidationError{Errors: errors}
+		return Registration{}, &ValidationError{Errors: errors}
+	}
+
+	email, _ := NormalizeEmail(req.Email)
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(email))
+	lock := &u.registrationLocks[hash.Sum32()%uint32(len(u.registrationLocks))]
+	lock.Lock()
+	defer lock.Unlock()
+
+	existingUser, err := u.repository.FindOne(ctx, UserLookup{Email: email})
+	if err == nil {
+		if !existingUser.EmailConfirmed && u.confirmationSigner != nil && time.Since(existingUser.ConfirmationSentAt) >= u.confirmationCooldown {
+			err = u.sendConfirmationMail(ctx, existingUser)
+			if err != nil {
+				return Registration{}, err
+			}
+		}
+
+		return Registration{User: existingUser, Created: false}, nil
+	}
+
+	if !errors.Is(err, ErrUserNotFound) {
+		return Registration{}, fmt.Errorf("finding user: %w", err)
+	}
+
+	if u.mxResolver != nil {
+		if err := checkMailExchanger(ctx, u.mxResolver, email); err != nil {
+			return Registration{}, &ValidationError{Errors: []string{"Email domain does not accept mails"}}
+		}
 	}
 
 	user := User{
-		Name:        req.Name,
-		Email:       req.Email,
-		Type:        TypeParticipant,
-		IsProcessed: false,
-		CreatedAt:   time.Now(),
+		Name:           strings.TrimSpace(req.Name),
+		Email:          email,
+		Type:           TypeParticipant,
+		IsProcessed:    false,
+		Locale:         i18n.Default,
+		EmailConfirmed: u.confirmationSigner == nil,
+		CreatedAt:      time.Now(),
 	}
 
-	err := u.db.CreateTableRecords(ctx, u.tableId, []any{user})
+	if locale, ok := i18n.Parse(string(req.Locale)); ok {
+		user.Locale = locale
+	}
+
+	err = u.repository.InsertUser(ctx, user)
 	if err != nil {
-		return fmt.Errorf("creating table records: %w", err)
+		// Another instance registered the same address in the meantime, its request mails the link.
+		if errors.Is(err, ErrUserEmailExists) {
+			existingUser, err := u.repository.FindOne(ctx, UserLookup{Email: email})
+			if err != nil {
+				return Registration{}, fmt.Errorf("finding user: %w", err)
+			}
+
+			return Registration{User: existingUser, Created: false}, nil
+		}
+
+		return Registration{}, fmt.Errorf("inserting user: %w", err)
 	}
 
-	return nil
+	if u.confirmationSigner != nil {
+		err = u.sendConfirmationMail(ctx, user)
+		if err != nil {
+			return Registration{}, err
+		}
+	}
+
+	return Registration{User: user, Created: true}, nil
 }
 
+// UserFilterRequest narrows down the users returned by GetUsers. Zero-valued fields are not filtered.
 type UserFilterRequest struct {
 	Type        Type
-	IsProcessed bool
+	IsProcessed sql.NullBool
 }
 
 func (u *UserDomain) GetUsers(ctx context.Context, filter UserFilterRequest) ([]User, error) {
@@ -97,19 +232,19 @@ func (u *UserDomain) GetUsers(ctx context.Context, filter UserFilterRequest) ([]
 	var users []User
 	var offset int64
 	for {
-		var currentUserSets []User
-		pageInfo, err := u.db.ListTableRecords(ctx, u.tableId, &currentUserSets, nocodb.ListTableRecordOptions{
-			Offset: offset,
-			Where:  fmt.Sprintf("(Type,eq,%s)~and(IsProcessed,eq,%s)", filter.Type, strconv.FormatBool(filter.IsProcessed)),
+		currentUserSets, isLastPage, err := u.repository.ListUsers(ctx, UserQuery{
+			Type:        filter.Type,
+			IsProcessed: filter.IsProcessed,
+			Offset:      offset,
 		})
 		if err != nil {
-			return users, fmt.Errorf("list table records: %w", err)
+			return users, fmt.Errorf("listing users: %w", err)
 		}
 
 		offset += int64(len(currentUserSets))
 		users = append(users, currentUserSets...)
 
-		if pageInfo.IsLastPage {
+		if isLastPage {
 			break
 		}
 	}
@@ -121,36 +256,18 @@ func (u *UserDomain) GetUserByEmail(ctx context.Context, email string) (User, er
 	span := sentry.StartSpan(ctx, "user.get_user_by_email", sentry.WithTransactionName("GetUserByEmail"))
 	defer span.Finish()
 
-	var user User
-	var offset int64
-	var found = false
-	for found {
-		var currentUserSets []User
-		pageInfo, err := u.db.ListTableRecords(ctx, u.tableId, &currentUserSets, nocodb.ListTableRecordOptions{
-			Offset: offset,
-			Where:  fmt.Sprintf("(Email,eq,%s)", email),
-		})
-		if err != nil {
-			return user, fmt.Errorf("list table records: %w", err)
-		}
-
-		offset += int64(len(currentUserSets))
-
-		for _, userEntry := range currentUserSets {
-			if userEntry.Email == email {
-				user = userEntry
-				found = true
-				break
-			}
-		}
+	// Emails are normalized on registration, so the lookup has to match.
+	if normalized, err := NormalizeEmail(email); err == nil {
+		email = normalized
+	}
 
-		if pageInfo.IsLastPage {
-			break
+	user, err := u.repository.FindOne(ctx, UserLookup{Email: email})
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return User{}, ErrUserEmailNotFound
 		}
-	}
 
-	if !found {
-		return user, ErrUserEmailNotFound
+		return User{}, fmt.Errorf("finding user: %w", err)
 	}
 
 	return user, nil
@@ -162,7 +279,7 @@ func (u *UserDomain) ExportUnprocessedUsersAsCSV(ctx context.Context) error {
 
 	users, err := u.GetUsers(ctx, UserFilterRequest{
 		Type:        TypeParticipant,
-		IsProcessed: false,
+		IsProcessed: sql.NullBool{Bool: false, Valid: true},
 	})
 	if err != nil {
 		return err
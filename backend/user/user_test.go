@@ -0,0 +1,450 @@
+package user_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"conf/i18n"
+	"conf/mailer"
+	"conf/mailtemplate"
+	"conf/nocodb"
+	"conf/nocodb/nocodbmock"
+	"conf/user"
+	"github.com/rs/zerolog/log"
+)
+
+// fakeResolver answers MX lookups from a map, a missing domain does not exist.
+type fakeResolver map[string][]*net.MX
+
+func (f fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if name == "timeout.example" {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+
+	records, ok := f[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return records, nil
+}
+
+func newUserRepository(t *testing.T) user.Repository {
+	userRepository, err := user.NewNocoDBRepository(newNocoDBClient(t), "users")
+	if err != nil {
+		t.Fatalf("creating user repository: %s", err.Error())
+	}
+
+	return userRepository
+}
+
+func newNocoDBClient(t *testing.T) *nocodb.Client {
+	nocodbMockServer, err := nocodbmock.NewNocoDBMockServer()
+	if err != nil {
+		t.Fatalf("creating nocodb mock server: %s", err.Error())
+	}
+	t.Cleanup(nocodbMockServer.Close)
+
+	database, err := nocodb.NewClient(nocodb.ClientOptions{
+		ApiToken:   "testing",
+		BaseUrl:    nocodbMockServer.URL,
+		HttpClient: nocodbMockServer.Client(),
+		Logger:     log.Logger,
+	})
+	if err != nil {
+		t.Fatalf("creating nocodb client: %s", err.Error())
+	}
+
+	return database
+}
+
+func TestUserDomain_CreateParticipant(t *testing.T) {
+	ctx := context.Background()
+	userRepository := newUserRepository(t)
+
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{
+		MXResolver: fakeResolver{
+			"example.com":     {{Host: "mx.example.com.", Pref: 10}},
+			"null-mx.example": {{Host: ".", Pref: 0}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating user domain: %s", err.Error())
+	}
+
+	t.Run("Normalizes the email", func(t *testing.T) {
+		_, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: " John Doe ", Email: " JohnDoe@Example.com "})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		users, _, err := userRepository.ListUsers(ctx, user.UserQuery{Email: "johndoe@example.com"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(users) != 1 || users[0].Name != "John Doe" {
+			t.Fatalf("expecting the normalized user, got %+v", users)
+		}
+
+		// Without a confirmation signer, the double opt-in is disabled.
+		if !users[0].EmailConfirmed {
+			t.Error("expecting the user to be confirmed")
+		}
+	})
+
+	t.Run("Lookup failures are let through", func(t *testing.T) {
+		_, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "John Doe", Email: "johndoe@timeout.example"})
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+	})
+
+	for _, email := range []string{"johndoe@example", "johndoe@does-not-exist.example", "johndoe@null-mx.example"} {
+		t.Run("Rejects "+email, func(t *testing.T) {
+			_, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "John Doe", Email: email})
+			var validationError *user.ValidationError
+			if !errors.As(err, &validationError) {
+				t.Errorf("expecting a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUserDomain_CreateParticipant_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	userRepository := newUserRepository(t)
+
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating user domain: %s", err.Error())
+	}
+
+	registration, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "John Doe", Email: "johndoe@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if !registration.Created {
+		t.Error("expecting the first registration to be created")
+	}
+
+	// Concurrent registrations with another casing, all of them get the existing registration.
+	var wait sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			registration, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "Johnny", Email: " JOHNDOE@example.com"})
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+				return
+			}
+
+			if registration.Created || registration.User.Name != "John Doe" {
+				t.Errorf("expecting the existing registration, got %+v", registration)
+			}
+		}()
+	}
+	wait.Wait()
+
+	users, _, err := userRepository.ListUsers(ctx, user.UserQuery{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(users) != 1 {
+		t.Errorf("expecting 1 user, got %d", len(users))
+	}
+}
+
+func TestUserDomain_ConfirmEmail(t *testing.T) {
+	ctx := context.Background()
+	userRepository := newUserRepository(t)
+
+	signer, err := user.NewConfirmationSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	if err != nil {
+		t.Fatalf("creating signer: %s", err.Error())
+	}
+
+	templates, err := mailtemplate.Load(map[string]any{"conferenceName": "TeknumConf 2023"}, nil, mailtemplate.Builtin)
+	if err != nil {
+		t.Fatalf("loading templates: %s", err.Error())
+	}
+
+	mailTransport := mailer.NewMemoryTransport()
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{
+		ConfirmationSigner: signer,
+		ConfirmationUrl:    "https://conference.example.com/confirm-email",
+		Mailer:             mailer.NewMailSenderWithTransport(mailTransport, mailer.DefaultFrom),
+		Templates:          templates,
+	})
+	if err != nil {
+		t.Fatalf("creating user domain: %s", err.Error())
+	}
+
+	_, err = userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "John Doe", Email: "johndoe@example.com", Locale: i18n.English})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	users, _, err := userRepository.ListUsers(ctx, user.UserQuery{Email: "johndoe@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(users) != 1 || users[0].EmailConfirmed {
+		t.Fatalf("expecting an unconfirmed user, got %+v", users)
+	}
+
+	messages := mailTransport.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expecting 1 delivered message, got %d", len(messages))
+	}
+
+	message := string(messages[0].Message)
+	if !strings.Contains(message, "Subject: TeknumConf 2023: Confirm Your Email Address") {
+		t.Errorf("expecting the confirmation mail in English, got %s", message)
+	}
+
+	// The link is on the quoted-printable HTML body, where "=" is encoded as "=3D" and long lines are wrapped
+	// with soft line breaks.
+	match := regexp.MustCompile(`token=3D([A-Za-z0-9_.\-]+)"`).FindStringSubmatch(strings.ReplaceAll(message, "=\r\n", ""))
+	if match == nil {
+		t.Fatalf("expecting the confirmation link on the mail, got %s", message)
+	}
+
+	token := match[1]
+
+	// Registering again right away does not mail another link.
+	registration, err := userDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "John Doe", Email: "johndoe@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if registration.Created || len(mailTransport.Messages()) != 1 {
+		t.Errorf("expecting the existing registration without another confirmation mail, got %+v and %d messages", registration, len(mailTransport.Messages()))
+	}
+
+	if registration.User.ConfirmationSentAt.IsZero() {
+		t.Error("expecting the confirmation to be recorded as sent")
+	}
+
+	// Registering again after the cooldown mails another link.
+	impatientUserDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{
+		ConfirmationSigner:   signer,
+		ConfirmationUrl:      "https://conference.example.com/confirm-email",
+		ConfirmationCooldown: time.Nanosecond,
+		Mailer:               mailer.NewMailSenderWithTransport(mailTransport, mailer.DefaultFrom),
+		Templates:            templates,
+	})
+	if err != nil {
+		t.Fatalf("creating user domain: %s", err.Error())
+	}
+
+	registration, err = impatientUserDomain.CreateParticipant(ctx, user.CreateParticipantRequest{Name: "John Doe", Email: "johndoe@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if registration.Created || len(mailTransport.Messages()) != 2 {
+		t.Errorf("expecting the existing registration and another confirmation mail, got %+v and %d messages", registration, len(mailTransport.Messages()))
+	}
+
+	_, err = userDomain.ConfirmEmail(ctx, token+"x")
+	if !errors.Is(err, user.ErrInvalidConfirmationToken) {
+		t.Errorf("expecting ErrInvalidConfirmationToken, got %v", err)
+	}
+
+	email, err := userDomain.ConfirmEmail(ctx, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if email != "johndoe@example.com" {
+		t.Errorf("expecting johndoe@example.com, got %s", email)
+	}
+
+	users, _, err = userRepository.ListUsers(ctx, user.UserQuery{Email: "johndoe@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(users) != 1 || !users[0].EmailConfirmed {
+		t.Errorf("expecting a confirmed user, got %+v", users)
+	}
+
+	// A token of an address that never registered.
+	_, err = userDomain.ConfirmEmail(ctx, signer.Sign("janedoe@example.com", time.Now()))
+	if !errors.Is(err, user.ErrUserEmailNotFound) {
+		t.Errorf("expecting ErrUserEmailNotFound, got %v", err)
+	}
+}
+
+func TestUserDomain_GetUserByEmail(t *testing.T) {
+	ctx := context.Background()
+	userRepository := newUserRepository(t)
+
+	userDomain, err := user.NewUserDomain(userRepository, user.UserDomainOptions{})
+	if err != nil {
+		t.Fatalf("creating user domain: %s", err.Error())
+	}
+
+	// Enough users to span several pages of the default page size of 25.
+	const total = 60
+	for i := 0; i < total; i++ {
+		err := userRepository.InsertUser(ctx, user.User{
+			Name:      "User " + strconv.Itoa(i),
+			Email:     "user" + strconv.Itoa(i) + "@example.com",
+			Type:      user.TypeParticipant,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("inserting user: %s", err.Error())
+		}
+	}
+
+	t.Run("Finds users on every page", func(t *testing.T) {
+		for _, i := range []int{0, 24, 25, 49, total - 1} {
+			foundUser, err := userDomain.GetUserByEmail(ctx, " USER"+strconv.Itoa(i)+"@example.com")
+			if err != nil {
+				t.Fatalf("unexpected error for user %d: %s", i, err.Error())
+			}
+
+			if foundUser.Name != "User "+strconv.Itoa(i) {
+				t.Errorf("expecting User %d, got %+v", i, foundUser)
+			}
+		}
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		_, err := userDomain.GetUserByEmail(ctx, "nobody@example.com")
+		if !errors.Is(err, user.ErrUserEmailNotFound) {
+			t.Errorf("expecting ErrUserEmailNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Returns the earliest registration", func(t *testing.T) {
+		err := userRepository.InsertUser(ctx, user.User{Name: "Duplicate", Email: "user7@example.com", Type: user.TypeParticipant, CreatedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("inserting user: %s", err.Error())
+		}
+
+		foundUser, err := userDomain.GetUserByEmail(ctx, "user7@example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if foundUser.Name != "User 7" {
+			t.Errorf("expecting User 7, got %+v", foundUser)
+		}
+	})
+
+	t.Run("Lists every page", func(t *testing.T) {
+		users, err := userDomain.GetUsers(ctx, user.UserFilterRequest{Type: user.TypeParticipant})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(users) != total+1 {
+			t.Errorf("expecting %d users, got %d", total+1, len(users))
+		}
+	})
+}
+
+func TestRepository_FindOne(t *testing.T) {
+	ctx := context.Background()
+	userRepository := newUserRepository(t)
+
+	for _, entry := range []user.User{
+		{Name: "John Doe", Email: "johndoe@example.com", Type: user.TypeParticipant},
+		{Name: "Jane Doe", Email: "janedoe@example.com", Type: user.TypeSpeaker},
+	} {
+		if err := userRepository.InsertUser(ctx, entry); err != nil {
+			t.Fatalf("inserting user: %s", err.Error())
+		}
+	}
+
+	foundUser, err := userRepository.FindOne(ctx, user.UserLookup{Type: user.TypeSpeaker})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if foundUser.Email != "janedoe@example.com" {
+		t.Errorf("expecting janedoe@example.com, got %+v", foundUser)
+	}
+
+	_, err = userRepository.FindOne(ctx, user.UserLookup{Email: "johndoe@example.com", Type: user.TypeSpeaker})
+	if !errors.Is(err, user.ErrUserNotFound) {
+		t.Errorf("expecting ErrUserNotFound, got %v", err)
+	}
+
+	// The email is compared as is, it can't widen the where clause to match other users.
+	_, err = userRepository.FindOne(ctx, user.UserLookup{Email: "x)~or(Type,eq,speaker"})
+	if !errors.Is(err, user.ErrUserNotFound) {
+		t.Errorf("expecting ErrUserNotFound, got %v", err)
+	}
+
+	_, err = userRepository.FindOne(ctx, user.UserLookup{})
+	if err == nil {
+		t.Error("expecting an error for an empty lookup, got nil")
+	}
+}
+
+func TestNocoDBRepository_EntriesBeforeEmailConfirmation(t *testing.T) {
+	ctx := context.Background()
+	database := newNocoDBClient(t)
+
+	userRepository, err := user.NewNocoDBRepository(database, "users")
+	if err != nil {
+		t.Fatalf("creating user repository: %s", err.Error())
+	}
+
+	// Entries made before the EmailConfirmed column exists.
+	type legacyUser struct {
+		Name  string
+		Email string
+		Type  user.Type
+	}
+	legacyUsers, err := nocodb.NewTable[legacyUser](database, "users")
+	if err != nil {
+		t.Fatalf("creating table: %s", err.Error())
+	}
+
+	_, err = legacyUsers.Create(ctx, legacyUser{Name: "John Doe", Email: "johndoe@example.com", Type: user.TypeParticipant})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	err = userRepository.InsertUser(ctx, user.User{Name: "Jane Doe", Email: "janedoe@example.com", Type: user.TypeParticipant})
+	if err != nil {
+		t.Fatalf("inserting user: %s", err.Error())
+	}
+
+	legacy, err := userRepository.FindOne(ctx, user.UserLookup{Email: "johndoe@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if !legacy.EmailConfirmed {
+		t.Error("expecting an entry without the EmailConfirmed column to count as confirmed")
+	}
+
+	unconfirmed, err := userRepository.FindOne(ctx, user.UserLookup{Email: "janedoe@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if unconfirmed.EmailConfirmed {
+		t.Error("expecting a new entry to stay unconfirmed")
+	}
+}
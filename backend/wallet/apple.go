@@ -0,0 +1,329 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"time"
+)
+
+// ApplePassConfig configures the Apple Wallet pass. The certificate and private key are the Pass Type ID
+// certificate issued by Apple, and the intermediate certificate is the Apple Worldwide Developer Relations
+// certificate that issues it. Every one of them is PEM encoded.
+type ApplePassConfig struct {
+	PassTypeIdentifier      string
+	TeamIdentifier          string
+	OrganizationName        string
+	EventName               string
+	Certificate             []byte
+	PrivateKey              []byte
+	IntermediateCertificate []byte
+	// Icon is the PNG icon shown on the lock screen notification. A plain icon is generated if it's empty.
+	Icon []byte
+}
+
+// ApplePassSigner builds signed .pkpass files.
+type ApplePassSigner struct {
+	passTypeIdentifier string
+	teamIdentifier     string
+	organizationName   string
+	eventName          string
+	certificate        *x509.Certificate
+	intermediates      []*x509.Certificate
+	privateKey         crypto.Signer
+	icons              map[string][]byte
+}
+
+// ApplePassContentType is the media type of the .pkpass file.
+const ApplePassContentType = "application/vnd.apple.pkpass"
+
+func NewApplePassSigner(config ApplePassConfig) (*ApplePassSigner, error) {
+	if config.PassTypeIdentifier == "" {
+		return nil, fmt.Errorf("pass type identifier is empty")
+	}
+
+	if config.TeamIdentifier == "" {
+		return nil, fmt.Errorf("team identifier is empty")
+	}
+
+	if config.OrganizationName == "" {
+		return nil, fmt.Errorf("organization name is empty")
+	}
+
+	if config.EventName == "" {
+		return nil, fmt.Errorf("event name is empty")
+	}
+
+	certificates, err := parseCertificates(config.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+
+	intermediates, err := parseCertificates(config.IntermediateCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing intermediate certificate: %w", err)
+	}
+
+	privateKey, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+
+	certificate := certificates[0]
+	intermediates = append(certificates[1:], intermediates...)
+	if !publicKeyEqual(certificate.PublicKey, privateKey.Public()) {
+		return nil, fmt.Errorf("private key does not match the certificate")
+	}
+
+	icons := map[string][]byte{}
+	if len(config.Icon) > 0 {
+		if _, err := png.DecodeConfig(bytes.NewReader(config.Icon)); err != nil {
+			return nil, fmt.Errorf("decoding icon: %w", err)
+		}
+
+		icons["icon.png"] = config.Icon
+		icons["icon@2x.png"] = config.Icon
+	} else {
+		for name, size := range map[string]int{"icon.png": 29, "icon@2x.png": 58} {
+			icon, err := generateIcon(size)
+			if err != nil {
+				return nil, fmt.Errorf("generating icon: %w", err)
+			}
+
+			icons[name] = icon
+		}
+	}
+
+	return &ApplePassSigner{
+		passTypeIdentifier: config.PassTypeIdentifier,
+		teamIdentifier:     config.TeamIdentifier,
+		organizationName:   config.OrganizationName,
+		eventName:          config.EventName,
+		certificate:        certificate,
+		intermediates:      intermediates,
+		privateKey:         privateKey,
+		icons:              icons,
+	}, nil
+}
+
+type passField struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+type passBarcode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+type passStructure struct {
+	PrimaryFields   []passField `json:"primaryFields"`
+	SecondaryFields []passField `json:"secondaryFields,omitempty"`
+	AuxiliaryFields []passField `json:"auxiliaryFields,omitempty"`
+	BackFields      []passField `json:"backFields,omitempty"`
+}
+
+type passJson struct {
+	FormatVersion      int           `json:"formatVersion"`
+	PassTypeIdentifier string        `json:"passTypeIdentifier"`
+	SerialNumber       string        `json:"serialNumber"`
+	TeamIdentifier     string        `json:"teamIdentifier"`
+	OrganizationName   string        `json:"organizationName"`
+	Description        string        `json:"description"`
+	LogoText           string        `json:"logoText"`
+	ForegroundColor    string        `json:"foregroundColor"`
+	BackgroundColor    string        `json:"backgroundColor"`
+	LabelColor         string        `json:"labelColor"`
+	Barcode            passBarcode   `json:"barcode"`
+	Barcodes           []passBarcode `json:"barcodes"`
+	EventTicket        passStructure `json:"eventTicket"`
+}
+
+// Build creates the .pkpass file of the ticket, which is a zip of the pass.json, its images, the manifest of
+// every file's SHA-1 checksum, and the detached PKCS#7 signature of the manifest.
+func (a *ApplePassSigner) Build(ticket PassTicket) ([]byte, error) {
+	if ticket.SerialNumber == "" {
+		return nil, fmt.Errorf("serial number is empty")
+	}
+
+	if ticket.Barcode == "" {
+		return nil, fmt.Errorf("barcode is empty")
+	}
+
+	// The QR code payload is base45, which is plain ASCII, so iso-8859-1 keeps it byte for byte.
+	barcode := passBarcode{
+		Format:          "PKBarcodeFormatQR",
+		Message:         ticket.Barcode,
+		MessageEncoding: "iso-8859-1",
+	}
+
+	pass := passJson{
+		FormatVersion:      1,
+		PassTypeIdentifier: a.passTypeIdentifier,
+		SerialNumber:       ticket.SerialNumber,
+		TeamIdentifier:     a.teamIdentifier,
+		OrganizationName:   a.organizationName,
+		Description:        a.eventName + " Ticket",
+		LogoText:           a.eventName,
+		ForegroundColor:    "rgb(255, 255, 255)",
+		BackgroundColor:    "rgb(17, 24, 39)",
+		LabelColor:         "rgb(156, 163, 175)",
+		Barcode:            barcode,
+		Barcodes:           []passBarcode{barcode},
+		EventTicket: passStructure{
+			PrimaryFields: []passField{{Key: "event", Label: "EVENT", Value: a.eventName}},
+			BackFields:    []passField{{Key: "serial", Label: "Ticket Number", Value: ticket.SerialNumber}},
+		},
+	}
+
+	if ticket.AttendeeName != "" {
+		pass.EventTicket.SecondaryFields = append(pass.EventTicket.SecondaryFields, passField{Key: "attendee", Label: "ATTENDEE", Value: ticket.AttendeeName})
+	}
+
+	if ticket.AttendeeEmail != "" {
+		pass.EventTicket.BackFields = append(pass.EventTicket.BackFields, passField{Key: "email", Label: "Email", Value: ticket.AttendeeEmail})
+	}
+
+	access := "General Admission"
+	if len(ticket.Entitlements) > 0 {
+		access = strings.Join(ticket.Entitlements, ", ")
+	}
+	pass.EventTicket.AuxiliaryFields = append(pass.EventTicket.AuxiliaryFields, passField{Key: "access", Label: "ACCESS", Value: access})
+
+	passContent, err := json.Marshal(pass)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling pass.json: %w", err)
+	}
+
+	files := map[string][]byte{"pass.json": passContent}
+	for name, icon := range a.icons {
+		files[name] = icon
+	}
+
+	manifest := make(map[string]string, len(files))
+	for name, content := range files {
+		checksum := sha1.Sum(content)
+		manifest[name] = hex.EncodeToString(checksum[:])
+	}
+
+	manifestContent, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling manifest.json: %w", err)
+	}
+
+	signature, err := signDetached(manifestContent, a.certificate, a.intermediates, a.privateKey, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("signing manifest: %w", err)
+	}
+
+	files["manifest.json"] = manifestContent
+	files["signature"] = signature
+
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	for _, name := range []string{"pass.json", "icon.png", "icon@2x.png", "manifest.json", "signature"} {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s: %w", name, err)
+		}
+
+		if _, err := writer.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("closing zip: %w", err)
+	}
+
+	return archive.Bytes(), nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	return certificates, nil
+}
+
+// parsePrivateKey accepts PKCS#8, PKCS#1 RSA, and SEC 1 EC private keys.
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return signer, nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	equaler, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && equaler.Equal(b)
+}
+
+func generateIcon(size int) ([]byte, error) {
+	icon := image.NewRGBA(image.Rect(0, 0, size, size))
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			icon.Set(x, y, color.RGBA{R: 17, G: 24, B: 39, A: 255})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, icon); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,321 @@
+package wallet_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"testing"
+
+	"conf/wallet"
+)
+
+type parsedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type parsedSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      parsedContentInfo
+	Certificates     []asn1.RawValue    `asn1:"optional,tag:0"`
+	SignerInfos      []parsedSignerInfo `asn1:"set"`
+}
+
+type parsedSignerInfo struct {
+	Version               int
+	IssuerAndSerialNumber struct {
+		Issuer       asn1.RawValue
+		SerialNumber *big.Int
+	}
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type parsedAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+var oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+
+// verifyDetachedSignature checks the PKCS#7 signature the way Wallet does: the message digest attribute
+// must match the content, the attributes must be signed by the embedded certificate, and the certificate
+// must chain up to the root through the embedded intermediates.
+func verifyDetachedSignature(t *testing.T, signature []byte, content []byte, root *x509.Certificate) *x509.Certificate {
+	t.Helper()
+
+	var outer parsedContentInfo
+	if rest, err := asn1.Unmarshal(signature, &outer); err != nil || len(rest) > 0 {
+		t.Fatalf("parsing content info: %v", err)
+	}
+
+	if !outer.ContentType.Equal(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}) {
+		t.Fatalf("expecting signedData content type, got %s", outer.ContentType)
+	}
+
+	var signed parsedSignedData
+	if _, err := asn1.Unmarshal(outer.Content.Bytes, &signed); err != nil {
+		t.Fatalf("parsing signed data: %s", err.Error())
+	}
+
+	if len(signed.ContentInfo.Content.Bytes) != 0 {
+		t.Error("expecting a detached signature without the content")
+	}
+
+	var certificates []*x509.Certificate
+	for _, raw := range signed.Certificates {
+		certificate, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			t.Fatalf("parsing embedded certificate: %s", err.Error())
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) < 2 {
+		t.Fatalf("expecting the signer and intermediate certificates to be embedded, got %d", len(certificates))
+	}
+
+	if len(signed.SignerInfos) != 1 {
+		t.Fatalf("expecting 1 signer info, got %d", len(signed.SignerInfos))
+	}
+
+	signerInfo := signed.SignerInfos[0]
+	var signer *x509.Certificate
+	for _, certificate := range certificates {
+		if certificate.SerialNumber.Cmp(signerInfo.IssuerAndSerialNumber.SerialNumber) == 0 &&
+			bytes.Equal(certificate.RawIssuer, signerInfo.IssuerAndSerialNumber.Issuer.FullBytes) {
+			signer = certificate
+		}
+	}
+
+	if signer == nil {
+		t.Fatal("signer certificate is not embedded")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range certificates {
+		intermediates.AddCert(certificate)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	if _, err := signer.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		t.Fatalf("verifying certificate chain: %s", err.Error())
+	}
+
+	var attributes []parsedAttribute
+	if _, err := asn1.UnmarshalWithParams(signerInfo.AuthenticatedAttributes.FullBytes, &attributes, "set,tag:0"); err != nil {
+		t.Fatalf("parsing authenticated attributes: %s", err.Error())
+	}
+
+	contentDigest := sha256.Sum256(content)
+	var foundDigest bool
+	for _, attribute := range attributes {
+		if !attribute.Type.Equal(oidMessageDigest) {
+			continue
+		}
+
+		var digest []byte
+		if _, err := asn1.Unmarshal(attribute.Values[0].FullBytes, &digest); err != nil {
+			t.Fatalf("parsing message digest: %s", err.Error())
+		}
+
+		if !bytes.Equal(digest, contentDigest[:]) {
+			t.Error("message digest does not match the content")
+		}
+
+		foundDigest = true
+	}
+
+	if !foundDigest {
+		t.Error("expecting a message digest attribute")
+	}
+
+	signedAttributes := append([]byte{0x31}, signerInfo.AuthenticatedAttributes.FullBytes[1:]...)
+	algorithm := x509.SHA256WithRSA
+	if _, ok := signer.PublicKey.(*rsa.PublicKey); !ok {
+		algorithm = x509.ECDSAWithSHA256
+	}
+
+	if err := signer.CheckSignature(algorithm, signedAttributes, signerInfo.EncryptedDigest); err != nil {
+		t.Fatalf("verifying signature: %s", err.Error())
+	}
+
+	return signer
+}
+
+func readPass(t *testing.T, pkpass []byte) map[string][]byte {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(pkpass), int64(len(pkpass)))
+	if err != nil {
+		t.Fatalf("opening pkpass: %s", err.Error())
+	}
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		opened, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %s", file.Name, err.Error())
+		}
+
+		content, err := io.ReadAll(opened)
+		_ = opened.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %s", file.Name, err.Error())
+		}
+
+		files[file.Name] = content
+	}
+
+	return files
+}
+
+func TestApplePassSigner_Build(t *testing.T) {
+	root := issueCertificate(t, "Test Root CA", generateEcdsaKey(t), nil)
+	intermediate := issueCertificate(t, "Test Worldwide Developer Relations", generateEcdsaKey(t), &root)
+
+	tests := []struct {
+		name string
+		pass testCertificate
+	}{
+		{name: "RSA key", pass: issueCertificate(t, "Pass Type ID: pass.test.teknumconf", generateRsaKey(t), &intermediate)},
+		{name: "ECDSA key", pass: issueCertificate(t, "Pass Type ID: pass.test.teknumconf", generateEcdsaKey(t), &intermediate)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signer, err := wallet.NewApplePassSigner(wallet.ApplePassConfig{
+				PassTypeIdentifier:      "pass.test.teknumconf",
+				TeamIdentifier:          "TEAM123456",
+				OrganizationName:        "Teknologi Umum",
+				EventName:               "TeknumConf 2023",
+				Certificate:             test.pass.certificatePem(),
+				PrivateKey:              test.pass.privateKeyPem(t),
+				IntermediateCertificate: intermediate.certificatePem(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			pkpass, err := signer.Build(wallet.PassTicket{
+				SerialNumber:  "42-1",
+				Barcode:       "NCF0:SIGNED PAYLOAD",
+				AttendeeName:  "John Doe",
+				AttendeeEmail: "john@example.com",
+				Entitlements:  []string{"main-hall", "workshop-a"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			files := readPass(t, pkpass)
+			for _, name := range []string{"pass.json", "icon.png", "icon@2x.png", "manifest.json", "signature"} {
+				if _, ok := files[name]; !ok {
+					t.Errorf("expecting %s on the pass", name)
+				}
+			}
+
+			var manifest map[string]string
+			if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+				t.Fatalf("parsing manifest: %s", err.Error())
+			}
+
+			for name, content := range files {
+				if name == "manifest.json" || name == "signature" {
+					continue
+				}
+
+				checksum := sha1.Sum(content)
+				if manifest[name] != hex.EncodeToString(checksum[:]) {
+					t.Errorf("expecting manifest checksum of %s to be %x, got %q", name, checksum, manifest[name])
+				}
+			}
+
+			signerCertificate := verifyDetachedSignature(t, files["signature"], files["manifest.json"], root.certificate)
+			if !signerCertificate.Equal(test.pass.certificate) {
+				t.Error("expecting the pass certificate to sign the manifest")
+			}
+
+			var pass struct {
+				PassTypeIdentifier string `json:"passTypeIdentifier"`
+				TeamIdentifier     string `json:"teamIdentifier"`
+				SerialNumber       string `json:"serialNumber"`
+				Barcodes           []struct {
+					Format  string `json:"format"`
+					Message string `json:"message"`
+				} `json:"barcodes"`
+			}
+			if err := json.Unmarshal(files["pass.json"], &pass); err != nil {
+				t.Fatalf("parsing pass.json: %s", err.Error())
+			}
+
+			if pass.PassTypeIdentifier != "pass.test.teknumconf" || pass.TeamIdentifier != "TEAM123456" || pass.SerialNumber != "42-1" {
+				t.Errorf("unexpected pass identifiers: %+v", pass)
+			}
+
+			if len(pass.Barcodes) != 1 || pass.Barcodes[0].Format != "PKBarcodeFormatQR" || pass.Barcodes[0].Message != "NCF0:SIGNED PAYLOAD" {
+				t.Errorf("expecting the signed payload as the QR barcode, got %+v", pass.Barcodes)
+			}
+		})
+	}
+}
+
+func TestNewApplePassSigner(t *testing.T) {
+	root := issueCertificate(t, "Test Root CA", generateEcdsaKey(t), nil)
+	pass := issueCertificate(t, "Pass Type ID: pass.test.teknumconf", generateEcdsaKey(t), &root)
+	other := issueCertificate(t, "Pass Type ID: pass.test.teknumconf", generateEcdsaKey(t), &root)
+
+	config := wallet.ApplePassConfig{
+		PassTypeIdentifier:      "pass.test.teknumconf",
+		TeamIdentifier:          "TEAM123456",
+		OrganizationName:        "Teknologi Umum",
+		EventName:               "TeknumConf 2023",
+		Certificate:             pass.certificatePem(),
+		PrivateKey:              pass.privateKeyPem(t),
+		IntermediateCertificate: root.certificatePem(),
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		if _, err := wallet.NewApplePassSigner(config); err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+	})
+
+	t.Run("Mismatched private key", func(t *testing.T) {
+		mismatched := config
+		mismatched.PrivateKey = other.privateKeyPem(t)
+		if _, err := wallet.NewApplePassSigner(mismatched); err == nil {
+			t.Error("expecting an error, got nil")
+		}
+	})
+
+	t.Run("Missing certificate", func(t *testing.T) {
+		missing := config
+		missing.Certificate = nil
+		if _, err := wallet.NewApplePassSigner(missing); err == nil {
+			t.Error("expecting an error, got nil")
+		}
+	})
+
+	t.Run("Empty pass type identifier", func(t *testing.T) {
+		empty := config
+		empty.PassTypeIdentifier = ""
+		if _, err := wallet.NewApplePassSigner(empty); err == nil {
+			t.Error("expecting an error, got nil")
+		}
+	})
+}
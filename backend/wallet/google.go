@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// GoogleWalletConfig configures the Google Wallet "Add to Google Wallet" link. The event ticket class is
+// expected to be created ahead of time on the Google Pay & Wallet Console, and the private key is the PEM
+// encoded RSA key of the service account that has access to the issuer.
+type GoogleWalletConfig struct {
+	IssuerId            string
+	ClassSuffix         string
+	ServiceAccountEmail string
+	PrivateKey          []byte
+}
+
+// GoogleWalletIssuer creates the signed save links of Google Wallet passes.
+type GoogleWalletIssuer struct {
+	issuerId            string
+	classSuffix         string
+	serviceAccountEmail string
+	privateKey          crypto.Signer
+}
+
+// googleWalletSaveUrl is the prefix of the save link, followed by the signed JWT.
+const googleWalletSaveUrl = "https://pay.google.com/gp/v/save/"
+
+// objectSuffixPattern is the allowed characters of Google Wallet object ID suffixes.
+var objectSuffixPattern = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func NewGoogleWalletIssuer(config GoogleWalletConfig) (*GoogleWalletIssuer, error) {
+	if config.IssuerId == "" {
+		return nil, fmt.Errorf("issuer id is empty")
+	}
+
+	if config.ClassSuffix == "" {
+		return nil, fmt.Errorf("class suffix is empty")
+	}
+
+	if config.ServiceAccountEmail == "" {
+		return nil, fmt.Errorf("service account email is empty")
+	}
+
+	privateKey, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+
+	if _, ok := privateKey.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+
+	return &GoogleWalletIssuer{
+		issuerId:            config.IssuerId,
+		classSuffix:         config.ClassSuffix,
+		serviceAccountEmail: config.ServiceAccountEmail,
+		privateKey:          privateKey,
+	}, nil
+}
+
+// SaveLink creates the "Add to Google Wallet" link of the ticket. The pass object is embedded on the link
+// itself, so Google creates it when the attendee opens the link.
+func (g *GoogleWalletIssuer) SaveLink(ticket PassTicket) (string, error) {
+	if ticket.SerialNumber == "" {
+		return "", fmt.Errorf("serial number is empty")
+	}
+
+	if ticket.Barcode == "" {
+		return "", fmt.Errorf("barcode is empty")
+	}
+
+	object := map[string]any{
+		"id":      g.issuerId + "." + objectSuffixPattern.ReplaceAllString(ticket.SerialNumber, "_"),
+		"classId": g.issuerId + "." + g.classSuffix,
+		"state":   "ACTIVE",
+		"barcode": map[string]any{
+			"type":  "QR_CODE",
+			"value": ticket.Barcode,
+		},
+	}
+
+	if ticket.AttendeeName != "" {
+		object["ticketHolderName"] = ticket.AttendeeName
+	}
+
+	if len(ticket.Entitlements) > 0 {
+		object["ticketType"] = map[string]any{
+			"defaultValue": map[string]any{
+				"language": "en-US",
+				"value":    strings.Join(ticket.Entitlements, ", "),
+			},
+		}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":     g.serviceAccountEmail,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"iat":     time.Now().Unix(),
+		"origins": []string{},
+		"payload": map[string]any{
+			"eventTicketObjects": []any{object},
+		},
+	})
+
+	signed, err := token.SignedString(g.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	return googleWalletSaveUrl + signed, nil
+}
@@ -0,0 +1,99 @@
+package wallet_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"conf/wallet"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestGoogleWalletIssuer_SaveLink(t *testing.T) {
+	privateKey := generateRsaKey(t)
+	privateKeyDer, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("marshaling private key: %s", err.Error())
+	}
+
+	issuer, err := wallet.NewGoogleWalletIssuer(wallet.GoogleWalletConfig{
+		IssuerId:            "3388000000012345678",
+		ClassSuffix:         "teknumconf-2023",
+		ServiceAccountEmail: "wallet@teknumconf.iam.gserviceaccount.com",
+		PrivateKey:          pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDer}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	link, err := issuer.SaveLink(wallet.PassTicket{
+		SerialNumber: "42-1",
+		Barcode:      "NCF0:SIGNED PAYLOAD",
+		AttendeeName: "John Doe",
+		Entitlements: []string{"main-hall"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	rawToken, found := strings.CutPrefix(link, "https://pay.google.com/gp/v/save/")
+	if !found {
+		t.Fatalf("unexpected save link: %s", link)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			t.Errorf("unexpected signing method: %s", token.Method.Alg())
+		}
+
+		return &privateKey.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatalf("verifying token: %s", err.Error())
+	}
+
+	if claims["iss"] != "wallet@teknumconf.iam.gserviceaccount.com" || claims["aud"] != "google" || claims["typ"] != "savetowallet" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+
+	payload, _ := claims["payload"].(map[string]any)
+	objects, _ := payload["eventTicketObjects"].([]any)
+	if len(objects) != 1 {
+		t.Fatalf("expecting 1 event ticket object, got %v", payload)
+	}
+
+	object := objects[0].(map[string]any)
+	if object["id"] != "3388000000012345678.42-1" || object["classId"] != "3388000000012345678.teknumconf-2023" {
+		t.Errorf("unexpected object identifiers: %v", object)
+	}
+
+	barcode, _ := object["barcode"].(map[string]any)
+	if barcode["type"] != "QR_CODE" || barcode["value"] != "NCF0:SIGNED PAYLOAD" {
+		t.Errorf("expecting the signed payload as the QR barcode, got %v", barcode)
+	}
+}
+
+func TestNewGoogleWalletIssuer(t *testing.T) {
+	ecdsaKeyDer, err := x509.MarshalPKCS8PrivateKey(generateEcdsaKey(t))
+	if err != nil {
+		t.Fatalf("marshaling private key: %s", err.Error())
+	}
+
+	_, err = wallet.NewGoogleWalletIssuer(wallet.GoogleWalletConfig{
+		IssuerId:            "3388000000012345678",
+		ClassSuffix:         "teknumconf-2023",
+		ServiceAccountEmail: "wallet@teknumconf.iam.gserviceaccount.com",
+		PrivateKey:          pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecdsaKeyDer}),
+	})
+	if err == nil {
+		t.Error("expecting an error for a non-RSA key, got nil")
+	}
+
+	_, err = wallet.NewGoogleWalletIssuer(wallet.GoogleWalletConfig{ClassSuffix: "teknumconf-2023"})
+	if err == nil {
+		t.Error("expecting an error for an empty issuer id, got nil")
+	}
+}
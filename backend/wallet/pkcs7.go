@@ -0,0 +1,168 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+var (
+	oidData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// The structures below follows RFC 2315, only the parts needed for a detached signature.
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+// signDetached creates a DER encoded PKCS#7 signature of the content, without embedding the content. The
+// certificate of the signer and its intermediates are embedded, so the verifier only needs the root.
+func signDetached(content []byte, certificate *x509.Certificate, intermediates []*x509.Certificate, signer crypto.Signer, signingTime time.Time) ([]byte, error) {
+	var encryptionAlgorithm pkix.AlgorithmIdentifier
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		encryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		encryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", signer.Public())
+	}
+
+	contentDigest := sha256.Sum256(content)
+	attributes, err := encodeAttributes([]attributeValue{
+		{Type: oidContentType, Value: oidData},
+		{Type: oidSigningTime, Value: signingTime.UTC()},
+		{Type: oidMessageDigest, Value: contentDigest[:]},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature covers the attributes encoded as a SET, not as the implicitly tagged field it's stored in.
+	signedAttributes := append([]byte{0x31}, attributes.FullBytes[1:]...)
+	attributesDigest := sha256.Sum256(signedAttributes)
+	signature, err := signer.Sign(rand.Reader, attributesDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("signing attributes: %w", err)
+	}
+
+	var certificates []byte
+	certificates = append(certificates, certificate.Raw...)
+	for _, intermediate := range intermediates {
+		certificates = append(certificates, intermediate.Raw...)
+	}
+
+	digestAlgorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	signed, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certificates},
+		SignerInfos: []signerInfo{
+			{
+				Version: 1,
+				IssuerAndSerialNumber: issuerAndSerialNumber{
+					Issuer:       asn1.RawValue{FullBytes: certificate.RawIssuer},
+					SerialNumber: certificate.SerialNumber,
+				},
+				DigestAlgorithm:           digestAlgorithm,
+				AuthenticatedAttributes:   attributes,
+				DigestEncryptionAlgorithm: encryptionAlgorithm,
+				EncryptedDigest:           signature,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling signed data: %w", err)
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+}
+
+type attributeValue struct {
+	Type  asn1.ObjectIdentifier
+	Value any
+}
+
+// encodeAttributes encodes the attributes as `[0] IMPLICIT SET OF Attribute`, sorted as DER requires.
+func encodeAttributes(values []attributeValue) (asn1.RawValue, error) {
+	var encoded [][]byte
+	for _, value := range values {
+		encodedValue, err := asn1.Marshal(value.Value)
+		if err != nil {
+			return asn1.RawValue{}, fmt.Errorf("marshaling attribute %s: %w", value.Type, err)
+		}
+
+		encodedAttribute, err := asn1.Marshal(attribute{
+			Type:  value.Type,
+			Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: encodedValue},
+		})
+		if err != nil {
+			return asn1.RawValue{}, fmt.Errorf("marshaling attribute %s: %w", value.Type, err)
+		}
+
+		encoded = append(encoded, encodedAttribute)
+	}
+
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+
+	raw := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: bytes.Join(encoded, nil)}
+	fullBytes, err := asn1.Marshal(raw)
+	if err != nil {
+		return asn1.RawValue{}, fmt.Errorf("marshaling attributes: %w", err)
+	}
+
+	raw.FullBytes = fullBytes
+	return raw, nil
+}
@@ -0,0 +1,23 @@
+// Package wallet builds the Apple Wallet and Google Wallet passes of a ticket, so attendees can save the
+// ticket to their phone instead of digging for the email.
+package wallet
+
+// Wallet holds the pass builders that are sent along with the ticket. Either of them can be nil to skip
+// that kind of pass.
+type Wallet struct {
+	Apple  *ApplePassSigner
+	Google *GoogleWalletIssuer
+}
+
+// PassTicket is the content of the pass. Both Apple and Google pass are built from the same one, so they
+// carry the same signed QR code payload.
+type PassTicket struct {
+	// SerialNumber must be unique for every issued pass. A reissued ticket should have a new one.
+	SerialNumber string
+	// Barcode is the signed QR code payload, the same one that is sent as the QR code image.
+	Barcode       string
+	AttendeeName  string
+	AttendeeEmail string
+	// Entitlements lists the checkpoints the ticket can be redeemed on. It's empty for general admission.
+	Entitlements []string
+}
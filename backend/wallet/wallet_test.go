@@ -0,0 +1,95 @@
+package wallet_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	privateKey  crypto.Signer
+}
+
+func (c testCertificate) certificatePem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.certificate.Raw})
+}
+
+func (c testCertificate) privateKeyPem(t *testing.T) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(c.privateKey)
+	if err != nil {
+		t.Fatalf("marshaling private key: %s", err.Error())
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// issueCertificate creates a certificate signed by the parent, or a self-signed one if parent is nil.
+func issueCertificate(t *testing.T, commonName string, privateKey crypto.Signer, parent *testCertificate) testCertificate {
+	t.Helper()
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("generating serial number: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{"Teknologi Umum Test"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil || commonName != "Pass Type ID: pass.test.teknumconf",
+	}
+
+	issuer, issuerKey := template, privateKey
+	if parent != nil {
+		issuer, issuerKey = parent.certificate, parent.privateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, privateKey.Public(), issuerKey)
+	if err != nil {
+		t.Fatalf("creating certificate: %s", err.Error())
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %s", err.Error())
+	}
+
+	return testCertificate{certificate: certificate, privateKey: privateKey}
+}
+
+func generateRsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating rsa key: %s", err.Error())
+	}
+
+	return privateKey
+}
+
+func generateEcdsaKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ecdsa key: %s", err.Error())
+	}
+
+	return privateKey
+}